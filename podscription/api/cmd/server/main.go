@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"podscription-api/controllers"
+	"podscription-api/internal/bundles"
+	"podscription-api/internal/cluster"
 	"podscription-api/internal/handlers"
+	"podscription-api/internal/knowledge"
 	"podscription-api/internal/managers"
 	"podscription-api/internal/store"
 	"podscription-api/pkg/config"
@@ -24,56 +32,197 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
 
-	// Validate OpenAI API key
-	if cfg.OpenAI.APIKey == "" {
-		logger.Fatal("OPENAI_API_KEY environment variable is required")
-		os.Exit(1)
-	}
-
 	logger.WithFields(logrus.Fields{
 		"host":         cfg.Server.Host,
 		"port":         cfg.Server.Port,
-		"openai_model": cfg.OpenAI.Model,
+		"llm_provider": cfg.LLM.Provider,
+		"llm_model":    cfg.LLM.Model,
 		"store_type":   cfg.Store.Type,
 	}).Info("starting podscription API server")
 
 	// Initialize store
 	var dataStore store.Store
 	switch cfg.Store.Type {
-	case "memory":
-		dataStore = store.NewMemoryStore(cfg.Store.Path)
+	case config.StoreMemory:
+		memoryStore, err := store.NewMemoryStore(cfg.Store.Path, time.Duration(cfg.Store.FlushIntervalMs)*time.Millisecond, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to load memory store")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := memoryStore.Close(); err != nil {
+				logger.WithError(err).Error("failed to persist sessions on shutdown")
+			}
+		}()
+		dataStore = memoryStore
+	case config.StoreSQLite:
+		sqliteStore, err := store.NewSQLiteStore(cfg.Store.Path)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to open SQLite store")
+			os.Exit(1)
+		}
+		defer sqliteStore.Close()
+		if cfg.Store.ImportPath != "" {
+			imported, err := sqliteStore.ImportJSON(cfg.Store.ImportPath)
+			if err != nil {
+				logger.WithError(err).Fatal("failed to import sessions into SQLite store")
+				os.Exit(1)
+			}
+			if imported > 0 {
+				logger.WithFields(logrus.Fields{
+					"path":     cfg.Store.ImportPath,
+					"sessions": imported,
+				}).Info("imported sessions into SQLite store")
+			}
+		}
+		dataStore = sqliteStore
+	case config.StorePostgres:
+		postgresStore, err := store.NewPostgresStore(cfg.Store.DSN, cfg.Store.MaxConns)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to open Postgres store")
+			os.Exit(1)
+		}
+		defer postgresStore.Close()
+		dataStore = postgresStore
 	default:
 		logger.WithField("store_type", cfg.Store.Type).Fatal("unsupported store type")
 		os.Exit(1)
 	}
 
+	// Load prompt templates
+	templates, err := managers.LoadPromptTemplates(cfg.Prompts.TemplatesDir)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to load prompt templates")
+		os.Exit(1)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"templates_dir":  cfg.Prompts.TemplatesDir,
+		"prompt_version": templates.Version(),
+	}).Info("loaded prompt templates")
+
+	// Initialize LLM provider
+	provider, err := managers.NewProvider(cfg.LLM, templates)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to initialize LLM provider")
+		os.Exit(1)
+	}
+
 	// Initialize managers
-	openAIManager := managers.NewOpenAIManager(cfg.OpenAI)
-	sessionManager := managers.NewSessionManager(dataStore, openAIManager, logger)
+	var sessionOptions []managers.SessionManagerOption
+	if cfg.Classifier.RulesEnabled {
+		sessionOptions = append(sessionOptions, managers.WithRuleClassifier(managers.NewRuleClassifier(cfg.Classifier.MinConfidence)))
+	}
+	if cfg.Analyzers.OfflineEnabled {
+		sessionOptions = append(sessionOptions, managers.WithOfflineAnalyzers(managers.NewOfflineAnalyzers()))
+	}
+	if cfg.Cluster.Enabled {
+		clientset, err := cluster.NewClientset(cfg.Cluster.Kubeconfig)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to connect to cluster")
+		}
+		timeout := time.Duration(cfg.Cluster.TimeoutSeconds) * time.Second
+		sessionOptions = append(sessionOptions, managers.WithClusterCollector(cluster.NewCollector(clientset), timeout))
+		logger.Info("live cluster context enabled")
+	}
+	if cfg.Knowledge.Dir != "" {
+		base, err := knowledge.Load(cfg.Knowledge.Dir)
+		if err != nil {
+			logger.WithError(err).Fatal("failed to load knowledge base")
+		}
+		sessionOptions = append(sessionOptions, managers.WithKnowledge(base, cfg.Knowledge.Limit))
+		logger.WithFields(logrus.Fields{
+			"dir":       cfg.Knowledge.Dir,
+			"documents": base.Documents(),
+			"chunks":    base.Chunks(),
+		}).Info("knowledge base loaded")
+	}
+	if cfg.Cases.Enabled {
+		sessionOptions = append(sessionOptions, managers.WithRelatedCases(cfg.Cases.Limit, cfg.Cases.MinSimilarity))
+	}
+	sessionOptions = append(sessionOptions, managers.WithBundles(bundles.NewRegistry(cfg.Bundles.Dir)))
+	contextBuilder := managers.NewContextBuilder(cfg.LLM.Model, cfg.LLM.ContextWindow, cfg.LLM.MaxTokens, cfg.History.MaxTokens)
+	sessionOptions = append(sessionOptions, managers.WithContextBuilder(contextBuilder))
+	logger.WithField("history_tokens", contextBuilder.HistoryTokens()).Info("conversation history budget set")
+	sessionManager := managers.NewSessionManager(dataStore, provider, logger, sessionOptions...)
+	if err := sessionManager.IndexCases(); err != nil {
+		logger.WithError(err).Fatal("failed to index past cases")
+	}
 
 	// Initialize controllers
 	chatController := controllers.NewChatController(sessionManager, logger)
+	bundleController := controllers.NewBundleController(sessionManager, logger)
+	searchController := controllers.NewSearchController(sessionManager, logger)
+	planController := controllers.NewPlanController(sessionManager, logger)
+	resolutionController := controllers.NewResolutionController(sessionManager, logger)
+	feedbackController := controllers.NewFeedbackController(sessionManager, logger)
 
 	// Initialize handlers
 	chatHandler := handlers.NewChatHandler(chatController, logger)
+	bundleHandler := handlers.NewBundleHandler(bundleController, logger, int64(cfg.Bundles.MaxSizeMB)*1024*1024)
+	searchHandler := handlers.NewSearchHandler(searchController, logger)
+	planHandler := handlers.NewPlanHandler(planController, logger)
+	resolutionHandler := handlers.NewResolutionHandler(resolutionController, logger)
+	feedbackHandler := handlers.NewFeedbackHandler(feedbackController, logger)
 
 	// Setup Gin router
-	router := setupRouter(chatHandler, logger, cfg)
+	router := setupRouter(chatHandler, bundleHandler, searchHandler, planHandler, resolutionHandler, feedbackHandler, logger, cfg)
+
+	// Stop on SIGINT/SIGTERM so that stores flush and close before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start the retention reaper
+	if cfg.Retention.Days > 0 {
+		if cfg.Retention.Action != config.RetentionArchive && cfg.Retention.Action != config.RetentionDelete {
+			logger.WithField("action", cfg.Retention.Action).Fatal("unsupported session retention action")
+		}
+		if cfg.Retention.IntervalMinutes <= 0 {
+			logger.WithField("interval_minutes", cfg.Retention.IntervalMinutes).Fatal("session retention interval must be positive")
+		}
+		policy := managers.RetentionPolicy{
+			MaxAge:   time.Duration(cfg.Retention.Days) * 24 * time.Hour,
+			Purge:    cfg.Retention.Action == config.RetentionDelete,
+			Interval: time.Duration(cfg.Retention.IntervalMinutes) * time.Minute,
+		}
+		go sessionManager.RunRetention(ctx, policy)
+		logger.WithFields(logrus.Fields{
+			"days":   cfg.Retention.Days,
+			"action": cfg.Retention.Action,
+		}).Info("session retention enabled")
+	}
 
 	// Start server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	logger.WithField("address", addr).Info("server starting")
-	
-	if err := router.Run(addr); err != nil {
-		logger.WithError(err).Fatal("failed to start server")
-		os.Exit(1)
+
+	server := &http.Server{Addr: addr, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.WithError(err).Fatal("failed to start server")
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Error("failed to shut down server gracefully")
 	}
 }
 
-func setupRouter(chatHandler *handlers.ChatHandler, logger *logrus.Logger, cfg *config.Config) *gin.Engine {
+func setupRouter(chatHandler *handlers.ChatHandler, bundleHandler *handlers.BundleHandler, searchHandler *handlers.SearchHandler, planHandler *handlers.PlanHandler, resolutionHandler *handlers.ResolutionHandler, feedbackHandler *handlers.FeedbackHandler, logger *logrus.Logger, cfg *config.Config) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
-	
+
 	router := gin.New()
 
 	// Middleware
@@ -90,11 +239,33 @@ func setupRouter(chatHandler *handlers.ChatHandler, logger *logrus.Logger, cfg *
 	{
 		// Chat endpoints
 		api.POST("/chat", chatHandler.SendMessage)
-		
+		api.POST("/chat/stream", chatHandler.StreamMessage)
+
 		// Session endpoints
 		api.POST("/sessions", chatHandler.CreateSession)
 		api.GET("/sessions", chatHandler.ListSessions)
 		api.GET("/sessions/:id", chatHandler.GetSession)
+		api.PATCH("/sessions/:id", chatHandler.UpdateSession)
+		api.DELETE("/sessions/:id", chatHandler.DeleteSession)
+
+		// Treatment plan endpoints
+		api.GET("/sessions/:id/plan", planHandler.GetPlan)
+		api.PATCH("/sessions/:id/plan/steps/:stepId", planHandler.UpdatePlanStep)
+
+		// Resolution endpoints
+		api.PUT("/sessions/:id/resolution", resolutionHandler.SetResolution)
+		api.GET("/stats/resolutions", resolutionHandler.Stats)
+
+		// Feedback endpoints
+		api.PUT("/sessions/:id/messages/:messageId/feedback", feedbackHandler.SetFeedback)
+		api.GET("/feedback/report", feedbackHandler.Report)
+
+		// Support bundle endpoints
+		api.POST("/bundles", bundleHandler.UploadBundle)
+		api.GET("/bundles/:id", bundleHandler.GetBundle)
+
+		// Search endpoints
+		api.GET("/search", searchHandler.Search)
 	}
 
 	// Serve static files (for potential future use)
@@ -106,11 +277,11 @@ func setupRouter(chatHandler *handlers.ChatHandler, logger *logrus.Logger, cfg *
 func corsMiddleware() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With"}
 	config.ExposeHeaders = []string{"Content-Length"}
 	config.AllowCredentials = true
-	
+
 	return cors.New(config)
 }
 
@@ -142,4 +313,4 @@ func loggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 			"path":        path,
 		}).Info("request processed")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/bundles"
+	"podscription-api/internal/managers"
+	"podscription-api/types"
+)
+
+// BundleController handles support bundle business logic
+type BundleController struct {
+	sessionManager *managers.SessionManager
+	logger         *logrus.Logger
+}
+
+// NewBundleController creates a new bundle controller
+func NewBundleController(sessionManager *managers.SessionManager, logger *logrus.Logger) *BundleController {
+	return &BundleController{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// UploadBundle ingests a tar.gz support bundle and opens a consultation grounded in it
+func (c *BundleController) UploadBundle(name string, r io.Reader) (*types.BundleUploadResponse, error) {
+	if name == "" {
+		name = "support-bundle.tar.gz"
+	}
+
+	summary, session, err := c.sessionManager.IngestBundle(name, r)
+	if err != nil {
+		c.logger.WithFields(logrus.Fields{
+			"bundle": name,
+			"error":  err,
+		}).Error("failed to upload support bundle")
+
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "BUNDLE_TOO_LARGE",
+				Message:   fmt.Sprintf("Support bundle cannot exceed %d MiB", tooLarge.Limit/1024/1024),
+			}
+		case errors.Is(err, bundles.ErrInvalidBundle):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "INVALID_BUNDLE",
+				Message:   "Support bundle must be a tar.gz archive of Kubernetes resources, events or logs",
+			}
+		case errors.Is(err, managers.ErrBundlesBusy):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "BUNDLES_BUSY",
+				Message:   "Too many support bundles are being processed, please retry shortly",
+			}
+		case errors.Is(err, managers.ErrBundlesDisabled):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "BUNDLES_DISABLED",
+				Message:   "Support bundles are not enabled",
+			}
+		}
+
+		return nil, &types.ErrorResponse{
+			ErrorCode: "BUNDLE_UPLOAD_FAILED",
+			Message:   "Failed to process support bundle",
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"bundle_id":  summary.ID,
+		"session_id": session.ID,
+		"findings":   len(summary.Findings),
+	}).Info("successfully uploaded support bundle")
+
+	return &types.BundleUploadResponse{
+		Bundle:  *summary,
+		Session: *session,
+	}, nil
+}
+
+// GetBundle retrieves the summary of an ingested support bundle
+func (c *BundleController) GetBundle(bundleID uuid.UUID) (*types.BundleSummary, error) {
+	summary, err := c.sessionManager.GetBundle(bundleID)
+	if err != nil {
+		c.logger.WithFields(logrus.Fields{
+			"bundle_id": bundleID,
+			"error":     err,
+		}).Error("failed to get support bundle")
+
+		return nil, &types.ErrorResponse{
+			ErrorCode: "BUNDLE_NOT_FOUND",
+			Message:   "Support bundle not found",
+		}
+	}
+
+	return summary, nil
+}
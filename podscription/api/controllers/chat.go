@@ -2,11 +2,16 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"podscription-api/internal/attachments"
 	"podscription-api/internal/managers"
+	"podscription-api/internal/store"
 	"podscription-api/types"
 )
 
@@ -24,73 +29,178 @@ func NewChatController(sessionManager *managers.SessionManager, logger *logrus.L
 	}
 }
 
+// StreamEventFunc receives the server-sent events produced while streaming a chat response
+type StreamEventFunc func(event string, data interface{})
+
+// Stream event names emitted by StreamMessage. Delta events carry the diagnosis text as
+// it is generated, without the JSON structure around it or the commands; the done event
+// carries the rendered message.
+const (
+	StreamEventSession = "session"
+	StreamEventIntent  = "intent"
+	StreamEventDelta   = "delta"
+	StreamEventDone    = "done"
+)
+
+// maxAttachments caps the number of attachments sent with a single message
+const maxAttachments = 10
+
+// Session listing page sizes
+const (
+	defaultSessionPageSize = 50
+	maxSessionPageSize     = 200
+)
+
 // SendMessage processes a chat message and returns the response
 func (c *ChatController) SendMessage(ctx context.Context, req types.ChatRequest) (*types.ChatResponse, error) {
+	sessionID, err := c.resolveSession(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Process the message
+	response, err := c.sessionManager.ProcessMessage(ctx, sessionID, req.Content, req.Attachments)
+	if err != nil {
+		return nil, c.processingError(sessionID, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"session_id":      sessionID,
+		"message_count":   len(response.Session.Messages),
+		"intent_category": response.Message.Intent.Category,
+		"related_cases":   len(response.RelatedCases),
+	}).Info("successfully processed chat message")
+
+	return response, nil
+}
+
+// StreamMessage processes a chat message, emitting the session ID, the classified intent,
+// diagnosis deltas and finally the complete response as stream events
+func (c *ChatController) StreamMessage(ctx context.Context, req types.ChatRequest, emit StreamEventFunc) error {
+	sessionID, err := c.resolveSession(req)
+	if err != nil {
+		return err
+	}
+
+	// Streaming keeps the client informed, so allow longer generations than SendMessage
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	emit(StreamEventSession, map[string]interface{}{
+		"sessionId": sessionID,
+	})
+
+	response, err := c.sessionManager.ProcessMessageStream(ctx, sessionID, req.Content, req.Attachments, managers.StreamCallbacks{
+		OnIntent: func(intent *types.PodIntent) {
+			emit(StreamEventIntent, intent)
+		},
+		OnDelta: func(delta string) {
+			emit(StreamEventDelta, types.StreamDelta{Content: delta})
+		},
+	})
+	if err != nil {
+		return c.processingError(sessionID, err)
+	}
+
+	emit(StreamEventDone, response)
+
+	c.logger.WithFields(logrus.Fields{
+		"session_id":      sessionID,
+		"message_count":   len(response.Session.Messages),
+		"intent_category": response.Message.Intent.Category,
+		"related_cases":   len(response.RelatedCases),
+	}).Info("successfully streamed chat message")
+
+	return nil
+}
+
+// resolveSession validates a chat request and returns the session it targets,
+// creating a new session when none is given
+func (c *ChatController) resolveSession(req types.ChatRequest) (uuid.UUID, error) {
 	// Validate request
 	if req.Content == "" {
-		return nil, &types.ErrorResponse{
+		return uuid.Nil, &types.ErrorResponse{
 			ErrorCode: "INVALID_REQUEST",
 			Message:   "Message content cannot be empty",
 		}
 	}
-
-	var sessionID uuid.UUID
-	var err error
+	if err := validateAttachments(req.Attachments); err != nil {
+		return uuid.Nil, err
+	}
 
 	// If no session ID provided, create a new session
 	if req.SessionID == nil {
 		session, err := c.sessionManager.CreateSession("")
 		if err != nil {
 			c.logger.WithError(err).Error("failed to create new session for chat")
-			return nil, &types.ErrorResponse{
+			return uuid.Nil, &types.ErrorResponse{
 				ErrorCode: "SESSION_CREATION_FAILED",
 				Message:   "Failed to create chat session",
 			}
 		}
-		sessionID = session.ID
-		c.logger.WithField("session_id", sessionID).Info("created new session for chat")
-	} else {
-		sessionID = *req.SessionID
+		c.logger.WithField("session_id", session.ID).Info("created new session for chat")
+		return session.ID, nil
 	}
 
-	// Add timeout to context
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	return *req.SessionID, nil
+}
 
-	// Process the message
-	session, message, err := c.sessionManager.ProcessMessage(ctx, sessionID, req.Content)
-	if err != nil {
-		c.logger.WithFields(logrus.Fields{
-			"session_id": sessionID,
-			"error":      err,
-		}).Error("failed to process chat message")
+// validateAttachments checks the number, kinds and sizes of the attachments on a chat request
+func validateAttachments(items []types.Attachment) error {
+	if len(items) > maxAttachments {
+		return &types.ErrorResponse{
+			ErrorCode: "INVALID_REQUEST",
+			Message:   fmt.Sprintf("At most %d attachments can be sent with a message", maxAttachments),
+		}
+	}
 
-		// Check if it's a session not found error
-		if err.Error() == "session not found" {
-			return nil, &types.ErrorResponse{
-				ErrorCode: "SESSION_NOT_FOUND",
-				Message:   "Chat session not found",
+	for _, attachment := range items {
+		if !attachment.Kind.Valid() {
+			return &types.ErrorResponse{
+				ErrorCode: "INVALID_REQUEST",
+				Message:   fmt.Sprintf("Unknown attachment kind %q, expected describe, events, logs or manifest", attachment.Kind),
 			}
 		}
-
-		return nil, &types.ErrorResponse{
-			ErrorCode: "PROCESSING_FAILED",
-			Message:   "Failed to process message",
+		if attachment.Content == "" {
+			return &types.ErrorResponse{
+				ErrorCode: "INVALID_REQUEST",
+				Message:   "Attachment content cannot be empty",
+			}
+		}
+		if len(attachment.Content) > attachments.MaxContentSize {
+			return &types.ErrorResponse{
+				ErrorCode: "INVALID_REQUEST",
+				Message:   fmt.Sprintf("Attachment content cannot exceed %d KiB", attachments.MaxContentSize/1024),
+			}
 		}
 	}
 
-	response := &types.ChatResponse{
-		Session: *session,
-		Message: *message,
-	}
+	return nil
+}
 
+// processingError logs a message processing failure and maps it to an error response
+func (c *ChatController) processingError(sessionID uuid.UUID, err error) error {
 	c.logger.WithFields(logrus.Fields{
-		"session_id":     sessionID,
-		"message_count":  len(session.Messages),
-		"intent_category": message.Intent.Category,
-	}).Info("successfully processed chat message")
+		"session_id": sessionID,
+		"error":      err,
+	}).Error("failed to process chat message")
 
-	return response, nil
+	// Check if it's a session not found error
+	if errors.Is(err, store.ErrSessionNotFound) {
+		return &types.ErrorResponse{
+			ErrorCode: "SESSION_NOT_FOUND",
+			Message:   "Chat session not found",
+		}
+	}
+
+	return &types.ErrorResponse{
+		ErrorCode: "PROCESSING_FAILED",
+		Message:   "Failed to process message",
+	}
 }
 
 // CreateSession creates a new chat session
@@ -115,7 +225,7 @@ func (c *ChatController) GetSession(sessionID uuid.UUID) (*types.Session, error)
 			"session_id": sessionID,
 			"error":      err,
 		}).Error("failed to get session")
-		
+
 		return nil, &types.ErrorResponse{
 			ErrorCode: "SESSION_NOT_FOUND",
 			Message:   "Session not found",
@@ -125,9 +235,14 @@ func (c *ChatController) GetSession(sessionID uuid.UUID) (*types.Session, error)
 	return session, nil
 }
 
-// ListSessions returns all sessions
-func (c *ChatController) ListSessions() ([]*types.Session, error) {
-	sessions, err := c.sessionManager.ListSessions()
+// ListSessions returns a page of session summaries matching the request's filters
+func (c *ChatController) ListSessions(req types.ListSessionsRequest) (*types.SessionPage, error) {
+	query, err := sessionQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := c.sessionManager.ListSessions(query)
 	if err != nil {
 		c.logger.WithError(err).Error("failed to list sessions")
 		return nil, &types.ErrorResponse{
@@ -136,5 +251,127 @@ func (c *ChatController) ListSessions() ([]*types.Session, error) {
 		}
 	}
 
-	return sessions, nil
-}
\ No newline at end of file
+	return page, nil
+}
+
+// sessionQuery validates a session listing request and converts it to a store query
+func sessionQuery(req types.ListSessionsRequest) (store.SessionQuery, error) {
+	query := store.SessionQuery{
+		Category: types.IntentCategory(req.Category),
+		Name:     strings.TrimSpace(req.Name),
+		Archived: req.Archived,
+		Limit:    req.Limit,
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultSessionPageSize
+	}
+	if query.Limit < 1 || query.Limit > maxSessionPageSize {
+		return query, invalidRequest(fmt.Sprintf("Limit must be between 1 and %d", maxSessionPageSize))
+	}
+
+	switch req.Order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, invalidRequest(`Order must be "asc" or "desc"`)
+	}
+
+	if query.Category != "" && !query.Category.Valid() {
+		return query, invalidRequest(fmt.Sprintf("Unknown intent category %q", req.Category))
+	}
+
+	if req.Cursor != "" {
+		cursor, err := store.ParseCursor(req.Cursor)
+		if err != nil {
+			return query, invalidRequest("Invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	var err error
+	if query.UpdatedAfter, err = parseDate(req.UpdatedAfter); err != nil {
+		return query, invalidRequest("updatedAfter must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if query.UpdatedBefore, err = parseDate(req.UpdatedBefore); err != nil {
+		return query, invalidRequest("updatedBefore must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+
+	return query, nil
+}
+
+// parseDate reads an RFC 3339 timestamp or a date at midnight UTC; empty values are the zero time
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// invalidRequest is the error response for a request that fails validation
+func invalidRequest(message string) error {
+	return &types.ErrorResponse{
+		ErrorCode: "INVALID_REQUEST",
+		Message:   message,
+	}
+}
+
+// UpdateSession renames and/or archives a session
+func (c *ChatController) UpdateSession(sessionID uuid.UUID, req types.UpdateSessionRequest) (*types.Session, error) {
+	if req.Name == nil && req.Archived == nil {
+		return nil, &types.ErrorResponse{
+			ErrorCode: "INVALID_REQUEST",
+			Message:   "Nothing to update, expected name or archived",
+		}
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, &types.ErrorResponse{
+				ErrorCode: "INVALID_REQUEST",
+				Message:   "Session name cannot be empty",
+			}
+		}
+		req.Name = &name
+	}
+
+	session, err := c.sessionManager.UpdateSession(sessionID, req.Name, req.Archived)
+	if err != nil {
+		return nil, c.sessionError(sessionID, err, "SESSION_UPDATE_FAILED", "Failed to update session")
+	}
+
+	return session, nil
+}
+
+// DeleteSession permanently deletes a session
+func (c *ChatController) DeleteSession(sessionID uuid.UUID) error {
+	if err := c.sessionManager.DeleteSession(sessionID); err != nil {
+		return c.sessionError(sessionID, err, "SESSION_DELETE_FAILED", "Failed to delete session")
+	}
+
+	return nil
+}
+
+// sessionError maps a failed session operation to an error response, distinguishing missing sessions
+func (c *ChatController) sessionError(sessionID uuid.UUID, err error, code, message string) error {
+	if errors.Is(err, store.ErrSessionNotFound) {
+		return &types.ErrorResponse{
+			ErrorCode: "SESSION_NOT_FOUND",
+			Message:   "Session not found",
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"session_id": sessionID,
+		"error":      err,
+	}).Error("session operation failed")
+
+	return &types.ErrorResponse{
+		ErrorCode: code,
+		Message:   message,
+	}
+}
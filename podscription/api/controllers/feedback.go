@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/managers"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// maxFeedbackCommentLength caps the free text of message feedback
+const maxFeedbackCommentLength = 4000
+
+// FeedbackController handles feedback on the answers of the Pod Doctor
+type FeedbackController struct {
+	sessionManager *managers.SessionManager
+	logger         *logrus.Logger
+}
+
+// NewFeedbackController creates a new feedback controller
+func NewFeedbackController(sessionManager *managers.SessionManager, logger *logrus.Logger) *FeedbackController {
+	return &FeedbackController{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// SetFeedback rates an assistant message and flags the commands that were wrong
+func (c *FeedbackController) SetFeedback(sessionID, messageID uuid.UUID, req types.SetFeedbackRequest) (*types.Feedback, error) {
+	if !req.Rating.Valid() {
+		return nil, invalidRequest(fmt.Sprintf("Invalid rating %q, expected up or down", req.Rating))
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len(comment) > maxFeedbackCommentLength {
+		return nil, invalidRequest(fmt.Sprintf("Comment cannot exceed %d characters", maxFeedbackCommentLength))
+	}
+
+	feedback, err := c.sessionManager.SetFeedback(sessionID, messageID, req.Rating, comment, req.WrongCommands)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionNotFound):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "SESSION_NOT_FOUND",
+				Message:   "Session not found",
+			}
+		case errors.Is(err, managers.ErrMessageNotFound):
+			return nil, &types.ErrorResponse{
+				ErrorCode: "MESSAGE_NOT_FOUND",
+				Message:   "Assistant message not found",
+			}
+		case errors.Is(err, managers.ErrCommandNotPrescribed):
+			return nil, invalidRequest("wrongCommands must be commands prescribed by the message")
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"message_id": messageID,
+			"error":      err,
+		}).Error("failed to record message feedback")
+		return nil, &types.ErrorResponse{
+			ErrorCode: "FEEDBACK_UPDATE_FAILED",
+			Message:   "Failed to record feedback",
+		}
+	}
+
+	return feedback, nil
+}
+
+// Report returns the feedback grouped by prompt version, model and intent category
+func (c *FeedbackController) Report() (*types.FeedbackReport, error) {
+	report, err := c.sessionManager.FeedbackReport()
+	if err != nil {
+		c.logger.WithError(err).Error("failed to build feedback report")
+		return nil, &types.ErrorResponse{
+			ErrorCode: "REPORT_FAILED",
+			Message:   "Failed to build feedback report",
+		}
+	}
+
+	return report, nil
+}
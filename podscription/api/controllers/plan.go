@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/attachments"
+	"podscription-api/internal/managers"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// PlanController handles the treatment plans of sessions
+type PlanController struct {
+	sessionManager *managers.SessionManager
+	logger         *logrus.Logger
+}
+
+// NewPlanController creates a new plan controller
+func NewPlanController(sessionManager *managers.SessionManager, logger *logrus.Logger) *PlanController {
+	return &PlanController{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// GetPlan returns the steps prescribed in a session and how they went
+func (c *PlanController) GetPlan(sessionID uuid.UUID) (*types.TreatmentPlan, error) {
+	plan, err := c.sessionManager.GetPlan(sessionID)
+	if err != nil {
+		return nil, c.planError(sessionID, uuid.Nil, err, "PLAN_RETRIEVAL_FAILED", "Failed to retrieve treatment plan")
+	}
+
+	return plan, nil
+}
+
+// UpdatePlanStep records the outcome of a prescribed step
+func (c *PlanController) UpdatePlanStep(sessionID, stepID uuid.UUID, req types.UpdatePlanStepRequest) (*types.TreatmentPlan, error) {
+	if !req.Status.Valid() {
+		return nil, invalidRequest(fmt.Sprintf("Invalid step status %q, expected pending, done, skipped or failed", req.Status))
+	}
+	if req.Output != nil && len(*req.Output) > attachments.MaxContentSize {
+		return nil, invalidRequest(fmt.Sprintf("Step output cannot exceed %d KiB", attachments.MaxContentSize/1024))
+	}
+
+	plan, err := c.sessionManager.UpdatePlanStep(sessionID, stepID, req.Status, req.Output)
+	if err != nil {
+		return nil, c.planError(sessionID, stepID, err, "PLAN_UPDATE_FAILED", "Failed to update treatment step")
+	}
+
+	return plan, nil
+}
+
+// planError maps session manager errors to error responses, logging unexpected ones
+func (c *PlanController) planError(sessionID, stepID uuid.UUID, err error, code, message string) error {
+	if errors.Is(err, store.ErrSessionNotFound) {
+		return &types.ErrorResponse{
+			ErrorCode: "SESSION_NOT_FOUND",
+			Message:   "Session not found",
+		}
+	}
+	if errors.Is(err, managers.ErrStepNotFound) {
+		return &types.ErrorResponse{
+			ErrorCode: "STEP_NOT_FOUND",
+			Message:   "Treatment step not found",
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"session_id": sessionID,
+		"step_id":    stepID,
+		"error":      err,
+	}).Error("treatment plan operation failed")
+
+	return &types.ErrorResponse{
+		ErrorCode: code,
+		Message:   message,
+	}
+}
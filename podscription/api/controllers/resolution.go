@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/managers"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// maxRootCauseLength caps the root cause recorded for a session
+const maxRootCauseLength = 2000
+
+// ResolutionController handles the outcomes of consultations
+type ResolutionController struct {
+	sessionManager *managers.SessionManager
+	logger         *logrus.Logger
+}
+
+// NewResolutionController creates a new resolution controller
+func NewResolutionController(sessionManager *managers.SessionManager, logger *logrus.Logger) *ResolutionController {
+	return &ResolutionController{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// SetResolution resolves, abandons or reopens a session
+func (c *ResolutionController) SetResolution(sessionID uuid.UUID, req types.UpdateResolutionRequest) (*types.Resolution, error) {
+	if !req.Status.Valid() {
+		return nil, invalidRequest(fmt.Sprintf("Invalid resolution status %q, expected open, resolved or abandoned", req.Status))
+	}
+	if req.FixedBy != nil && req.Status != types.ResolutionStatusResolved {
+		return nil, invalidRequest("fixedBy can only be given for resolved sessions")
+	}
+	rootCause := strings.TrimSpace(req.RootCause)
+	if len(rootCause) > maxRootCauseLength {
+		return nil, invalidRequest(fmt.Sprintf("Root cause cannot exceed %d characters", maxRootCauseLength))
+	}
+
+	resolution, err := c.sessionManager.SetResolution(sessionID, req.Status, rootCause, req.FixedBy)
+	if err != nil {
+		if errors.Is(err, store.ErrSessionNotFound) {
+			return nil, &types.ErrorResponse{
+				ErrorCode: "SESSION_NOT_FOUND",
+				Message:   "Session not found",
+			}
+		}
+		if errors.Is(err, managers.ErrPrescriptionNotFound) {
+			return nil, invalidRequest("fixedBy must be an assistant message of the session with a prescription")
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"error":      err,
+		}).Error("failed to set session resolution")
+		return nil, &types.ErrorResponse{
+			ErrorCode: "RESOLUTION_UPDATE_FAILED",
+			Message:   "Failed to update session resolution",
+		}
+	}
+
+	return resolution, nil
+}
+
+// Stats returns the outcomes of consultations by intent category
+func (c *ResolutionController) Stats() (*types.ResolutionStatsResponse, error) {
+	stats, err := c.sessionManager.ResolutionStats()
+	if err != nil {
+		c.logger.WithError(err).Error("failed to compute resolution statistics")
+		return nil, &types.ErrorResponse{
+			ErrorCode: "STATS_FAILED",
+			Message:   "Failed to compute resolution statistics",
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/managers"
+	"podscription-api/types"
+)
+
+// Search result counts
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 100
+)
+
+// SearchController handles searches across past consultations
+type SearchController struct {
+	sessionManager *managers.SessionManager
+	logger         *logrus.Logger
+}
+
+// NewSearchController creates a new search controller
+func NewSearchController(sessionManager *managers.SessionManager, logger *logrus.Logger) *SearchController {
+	return &SearchController{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// Search returns the messages matching query, best first
+func (c *SearchController) Search(query string, limit int) (*types.SearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, invalidRequest("Search query cannot be empty")
+	}
+	if limit == 0 {
+		limit = defaultSearchResults
+	}
+	if limit < 1 || limit > maxSearchResults {
+		return nil, invalidRequest(fmt.Sprintf("Limit must be between 1 and %d", maxSearchResults))
+	}
+
+	hits, err := c.sessionManager.Search(query, limit)
+	if err != nil {
+		return nil, &types.ErrorResponse{
+			ErrorCode: "SEARCH_FAILED",
+			Message:   "Failed to search consultations",
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"query": query,
+		"hits":  len(hits),
+	}).Info("searched consultations")
+
+	return &types.SearchResponse{
+		Query: query,
+		Hits:  hits,
+	}, nil
+}
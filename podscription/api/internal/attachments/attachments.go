@@ -0,0 +1,145 @@
+package attachments
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"podscription-api/types"
+)
+
+const (
+	// MaxContentSize is the largest attachment content accepted, in bytes
+	MaxContentSize = 512 * 1024
+	// maxFacts caps the facts extracted from a single attachment
+	maxFacts = 25
+	// maxFactLength truncates long facts such as log lines and event messages
+	maxFactLength = 200
+	// maxLogFacts caps the error lines kept from container logs
+	maxLogFacts = 10
+)
+
+// Extract returns the facts relevant to a diagnosis found in an attachment's content
+func Extract(attachment types.Attachment) []string {
+	var facts []string
+
+	switch attachment.Kind {
+	case types.AttachmentKindDescribe:
+		facts = parseDescribe(attachment.Content)
+	case types.AttachmentKindEvents:
+		facts = parseEvents(attachment.Content)
+	case types.AttachmentKindLogs:
+		facts = parseLogs(attachment.Content)
+	case types.AttachmentKindManifest:
+		facts = parseManifest(attachment.Content)
+	}
+
+	if len(facts) > maxFacts {
+		facts = append(facts[:maxFacts], fmt.Sprintf("... %d more facts omitted", len(facts)-maxFacts))
+	}
+	return facts
+}
+
+// KindForCommand guesses the kind of output a command prints, defaulting to logs,
+// whose parser keeps the lines that look like errors
+func KindForCommand(command string) types.AttachmentKind {
+	fields := strings.Fields(strings.ToLower(command))
+	switch {
+	case slices.Contains(fields, "describe"):
+		return types.AttachmentKindDescribe
+	case slices.Contains(fields, "events"):
+		return types.AttachmentKindEvents
+	case outputFormat(fields) == "yaml" || outputFormat(fields) == "json":
+		return types.AttachmentKindManifest
+	default:
+		return types.AttachmentKindLogs
+	}
+}
+
+// outputFormat returns the value of a kubectl -o/--output flag among fields
+func outputFormat(fields []string) string {
+	for i, field := range fields {
+		for _, flag := range []string{"--output", "-o"} {
+			value, ok := strings.CutPrefix(field, flag)
+			if !ok {
+				continue
+			}
+			if value == "" && i+1 < len(fields) {
+				return fields[i+1]
+			}
+			return strings.TrimPrefix(value, "=")
+		}
+	}
+	return ""
+}
+
+// Summarize renders attachments and their facts as plain text, e.g. for intent classification
+func Summarize(attachments []types.Attachment) string {
+	var b strings.Builder
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "%s %s:\n", attachment.Kind, attachment.Name)
+		for _, fact := range attachment.Facts {
+			fmt.Fprintf(&b, "- %s\n", fact)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// logErrorPattern matches log lines that usually explain a failure
+var logErrorPattern = regexp.MustCompile(`(?i)\b(error|err|fatal|panic|exception|traceback|fail(ed|ure)?|refused|denied|forbidden|unauthorized|timeout|timed out|killed|out of memory|no such|not found|cannot|unable)\b`)
+
+// parseLogs keeps the last distinct error-looking lines and the final line of container logs
+func parseLogs(content string) []string {
+	lines := nonEmptyLines(content)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var matches []string
+	seen := make(map[string]bool)
+	for i := len(lines) - 1; i >= 0 && len(matches) < maxLogFacts; i-- {
+		line := truncate(lines[i])
+		if !logErrorPattern.MatchString(line) || seen[line] {
+			continue
+		}
+		seen[line] = true
+		matches = append(matches, line)
+	}
+
+	facts := []string{fmt.Sprintf("%d log lines, %d distinct error lines near the end", len(lines), len(matches))}
+	// Restore chronological order
+	for i := len(matches) - 1; i >= 0; i-- {
+		facts = append(facts, "Log: "+matches[i])
+	}
+	if last := truncate(lines[len(lines)-1]); !seen[last] {
+		facts = append(facts, "Last line: "+last)
+	}
+	return facts
+}
+
+// nonEmptyLines splits content into lines, dropping blank ones
+func nonEmptyLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return lines
+}
+
+// truncate collapses whitespace and shortens a fact to maxFactLength bytes, backing off to
+// the start of a character so that multi-byte characters are not split
+func truncate(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= maxFactLength {
+		return text
+	}
+	cut := maxFactLength
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
@@ -0,0 +1,45 @@
+package attachments
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short", "  back-off   restarting\tfailed container ", "back-off restarting failed container"},
+		{"exact length", strings.Repeat("a", maxFactLength), strings.Repeat("a", maxFactLength)},
+		{"ascii", strings.Repeat("a", maxFactLength+10), strings.Repeat("a", maxFactLength) + "..."},
+		{"two-byte rune at the cut", strings.Repeat("a", maxFactLength-1) + "é" + "tail", strings.Repeat("a", maxFactLength-1) + "..."},
+		{"four-byte rune at the cut", strings.Repeat("a", maxFactLength-2) + "🩺" + "tail", strings.Repeat("a", maxFactLength-2) + "..."},
+		{"rune ending at the cut", strings.Repeat("a", maxFactLength-2) + "é" + "tail", strings.Repeat("a", maxFactLength-2) + "é..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.text); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateNeverSplitsRunes(t *testing.T) {
+	for _, r := range []string{"é", "日", "🩺"} {
+		for offset := 0; offset < utf8.UTFMax; offset++ {
+			text := strings.Repeat("x", offset) + strings.Repeat(r, maxFactLength)
+			got := truncate(text)
+			if !utf8.ValidString(got) {
+				t.Fatalf("truncate split a %q rune at offset %d: %q", r, offset, got)
+			}
+			if len(got) > maxFactLength+len("...") {
+				t.Fatalf("got %d bytes, want at most %d", len(got), maxFactLength+len("..."))
+			}
+		}
+	}
+}
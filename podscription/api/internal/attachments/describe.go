@@ -0,0 +1,294 @@
+package attachments
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxEventFacts caps the events kept from describe output and event listings
+const maxEventFacts = 10
+
+// describeFields are the top-level describe fields worth reporting, in output order
+var describeFields = []string{"Status", "Reason", "Message", "Node", "Controlled By", "Replicas", "StorageClass", "Capacity", "Access Modes", "Type", "Selector", "Endpoints"}
+
+// columnSeparator splits kubectl table rows, whose columns are separated by two or more spaces
+var columnSeparator = regexp.MustCompile(`\s{2,}`)
+
+// describeContainer collects the state of one container in describe output
+type describeContainer struct {
+	name      string
+	image     string
+	state     []string
+	lastState []string
+	ready     string
+	restarts  string
+}
+
+// parseDescribe extracts identity, status, container states, failed conditions and events from kubectl describe output
+func parseDescribe(content string) []string {
+	fields := make(map[string]string)
+	var containers []*describeContainer
+	var conditions, eventRows []string
+
+	section := ""
+	var container *describeContainer
+	stateKey := ""
+
+	for _, line := range nonEmptyLines(content) {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		trimmed := strings.TrimSpace(line)
+		key, value, hasValue := splitField(trimmed)
+
+		if indent == 0 {
+			section = ""
+			if hasValue && value != "" {
+				fields[key] = value
+			} else if hasValue {
+				section = key
+			}
+			continue
+		}
+
+		switch section {
+		case "Containers", "Init Containers":
+			switch {
+			case indent == 2 && hasValue && value == "":
+				container = &describeContainer{name: key}
+				containers = append(containers, container)
+				stateKey = ""
+			case container == nil:
+			case indent == 4 && hasValue:
+				stateKey = ""
+				switch key {
+				case "Image":
+					container.image = value
+				case "State":
+					stateKey, container.state = key, []string{value}
+				case "Last State":
+					stateKey, container.lastState = key, []string{value}
+				case "Ready":
+					container.ready = value
+				case "Restart Count":
+					container.restarts = value
+				}
+			case indent >= 6 && hasValue && (key == "Reason" || key == "Exit Code" || key == "Message" || key == "Signal"):
+				detail := value
+				if key == "Exit Code" || key == "Signal" {
+					detail = strings.ToLower(key) + " " + value
+				}
+				if stateKey == "State" {
+					container.state = append(container.state, detail)
+				} else if stateKey == "Last State" {
+					container.lastState = append(container.lastState, detail)
+				}
+			}
+		case "Conditions":
+			columns := columnSeparator.Split(trimmed, -1)
+			if len(columns) >= 2 && columns[0] != "Type" && !strings.HasPrefix(columns[0], "----") && columns[1] != "True" {
+				condition := fmt.Sprintf("Condition %s=%s", columns[0], columns[1])
+				if len(columns) > 2 {
+					condition += " " + strings.Join(columns[2:], " ")
+				}
+				conditions = append(conditions, truncate(condition))
+			}
+		case "Events":
+			if trimmed != "<none>" {
+				eventRows = append(eventRows, trimmed)
+			}
+		}
+	}
+
+	var facts []string
+	if identity := describeIdentity(fields, len(containers) > 0); identity != "" {
+		facts = append(facts, identity)
+	}
+	for _, field := range describeFields {
+		if value, ok := fields[field]; ok {
+			facts = append(facts, truncate(fmt.Sprintf("%s: %s", field, value)))
+		}
+	}
+	for _, c := range containers {
+		facts = append(facts, c.String())
+	}
+	facts = append(facts, conditions...)
+	return append(facts, describeEvents(eventRows)...)
+}
+
+// describeIdentity names the described object so that references can be resolved from it
+func describeIdentity(fields map[string]string, hasContainers bool) string {
+	name := fields["Name"]
+	if name == "" {
+		return ""
+	}
+	if hasContainers {
+		name = "pod/" + name
+	}
+	if namespace := fields["Namespace"]; namespace != "" {
+		return fmt.Sprintf("Object: %s (namespace: %s)", name, namespace)
+	}
+	return "Object: " + name
+}
+
+// String summarizes the container on a single line
+func (c *describeContainer) String() string {
+	parts := []string{fmt.Sprintf("Container %s:", c.name)}
+	if len(c.state) > 0 {
+		parts = append(parts, "state "+stateSummary(c.state))
+	}
+	if len(c.lastState) > 0 {
+		parts = append(parts, "last state "+stateSummary(c.lastState))
+	}
+	if c.ready != "" {
+		parts = append(parts, "ready "+c.ready)
+	}
+	if c.restarts != "" {
+		parts = append(parts, "restarts "+c.restarts)
+	}
+	if c.image != "" {
+		parts = append(parts, "image "+c.image)
+	}
+	return truncate(parts[0] + " " + strings.Join(parts[1:], ", "))
+}
+
+// stateSummary renders a state and its details, e.g. "Terminated (Error, exit code 1)"
+func stateSummary(state []string) string {
+	if len(state) == 1 {
+		return state[0]
+	}
+	return fmt.Sprintf("%s (%s)", state[0], strings.Join(state[1:], ", "))
+}
+
+// describeEvents summarizes the Events table of describe output, warnings first
+func describeEvents(rows []string) []string {
+	var warnings, normal []string
+	for _, row := range rows {
+		columns := columnSeparator.Split(row, 5)
+		if len(columns) < 5 || columns[0] == "Type" || strings.HasPrefix(columns[0], "----") {
+			continue
+		}
+		event := fmt.Sprintf("Event %s %s (%s): %s", columns[0], columns[1], columns[2], columns[4])
+		if columns[0] == "Warning" {
+			warnings = append(warnings, truncate(event))
+		} else {
+			normal = append(normal, truncate(event))
+		}
+	}
+	return limitEvents(warnings, normal)
+}
+
+// parseEvents summarizes `kubectl get events` output, grouping repeated events and listing warnings first
+func parseEvents(content string) []string {
+	lines := nonEmptyLines(content)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	header := lines[0]
+	columns := tableColumns(header, "TYPE", "REASON", "OBJECT", "MESSAGE")
+	if columns == nil {
+		// Not a table, e.g. events pasted from describe output
+		return describeEvents(lines)
+	}
+
+	type eventGroup struct {
+		event string
+		count int
+	}
+	groups := make(map[string]*eventGroup)
+	var warnings, normal []*eventGroup
+
+	for _, line := range lines[1:] {
+		eventType := tableCell(line, columns, 0)
+		reason := tableCell(line, columns, 1)
+		object := tableCell(line, columns, 2)
+		message := tableCell(line, columns, 3)
+		if eventType == "" || reason == "" {
+			continue
+		}
+
+		key := eventType + "/" + reason + "/" + object
+		if group, ok := groups[key]; ok {
+			group.count++
+			group.event = fmt.Sprintf("Event %s %s on %s: %s", eventType, reason, object, message)
+			continue
+		}
+
+		group := &eventGroup{event: fmt.Sprintf("Event %s %s on %s: %s", eventType, reason, object, message), count: 1}
+		groups[key] = group
+		if eventType == "Warning" {
+			warnings = append(warnings, group)
+		} else {
+			normal = append(normal, group)
+		}
+	}
+
+	render := func(groups []*eventGroup) []string {
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].count > groups[j].count })
+		var events []string
+		for _, group := range groups {
+			event := group.event
+			if group.count > 1 {
+				event = fmt.Sprintf("%s (seen %d times)", event, group.count)
+			}
+			events = append(events, truncate(event))
+		}
+		return events
+	}
+
+	return limitEvents(render(warnings), render(normal))
+}
+
+// limitEvents keeps warnings before normal events, up to maxEventFacts
+func limitEvents(warnings, normal []string) []string {
+	events := append(warnings, normal...)
+	if len(events) > maxEventFacts {
+		events = events[:maxEventFacts]
+	}
+	return events
+}
+
+// tableColumns returns the start offsets of the named columns in a kubectl table header,
+// or nil when any of them is missing
+func tableColumns(header string, names ...string) []int {
+	offsets := make([]int, len(names))
+	for i, name := range names {
+		offset := regexp.MustCompile(`(^|\s)` + name + `(\s|$)`).FindStringIndex(header)
+		if offset == nil {
+			return nil
+		}
+		offsets[i] = offset[0]
+		if offset[0] > 0 {
+			offsets[i]++
+		}
+	}
+	return offsets
+}
+
+// tableCell returns the content of the i-th column of a row, the last column extending to the end of the line
+func tableCell(row string, offsets []int, i int) string {
+	start := offsets[i]
+	if start >= len(row) {
+		return ""
+	}
+	end := len(row)
+	if i+1 < len(offsets) && offsets[i+1] < end {
+		end = offsets[i+1]
+	}
+	return strings.TrimSpace(row[start:end])
+}
+
+// splitField splits a describe line such as "Restart Count:  12" into its key and value
+func splitField(line string) (string, string, bool) {
+	index := strings.Index(line, ":")
+	if index <= 0 {
+		return "", "", false
+	}
+	key := line[:index]
+	// Values like URLs contain colons too, keys never contain more than a few words
+	if strings.Count(key, " ") > 2 {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(line[index+1:]), true
+}
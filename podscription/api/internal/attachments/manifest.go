@@ -0,0 +1,292 @@
+package attachments
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// documentSeparator splits multi-document YAML
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// parseManifest extracts the specs and statuses that matter for troubleshooting from YAML or JSON manifests
+func parseManifest(content string) []string {
+	var facts []string
+
+	for _, document := range documentSeparator.Split(content, -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+			facts = append(facts, truncate("Unparseable manifest document: "+err.Error()))
+			continue
+		}
+
+		// kubectl get -o yaml returns a List when several objects are requested
+		if items, ok := object["items"].([]interface{}); ok {
+			for _, item := range items {
+				if itemObject, ok := item.(map[string]interface{}); ok {
+					facts = append(facts, manifestFacts(itemObject)...)
+				}
+			}
+			continue
+		}
+
+		facts = append(facts, manifestFacts(object)...)
+	}
+
+	return facts
+}
+
+// ObjectFacts summarizes a single decoded Kubernetes object, e.g. one read from a support bundle
+func ObjectFacts(object map[string]interface{}) []string {
+	return manifestFacts(object)
+}
+
+// manifestFacts summarizes a single object
+func manifestFacts(object map[string]interface{}) []string {
+	kind := str(object["kind"])
+	name := str(nested(object, "metadata", "name"))
+	identity := fmt.Sprintf("Object: %s/%s", strings.ToLower(kind), name)
+	if namespace := str(nested(object, "metadata", "namespace")); namespace != "" {
+		identity += fmt.Sprintf(" (namespace: %s)", namespace)
+	}
+	facts := []string{identity}
+
+	if replicas := nested(object, "spec", "replicas"); replicas != nil {
+		facts = append(facts, fmt.Sprintf("Replicas: %v desired, %v ready", replicas, orZero(nested(object, "status", "readyReplicas"))))
+	}
+
+	if spec := podSpec(kind, object); spec != nil {
+		facts = append(facts, podSpecFacts(spec)...)
+	}
+
+	switch kind {
+	case "Service":
+		facts = append(facts, fmt.Sprintf("Service type %s, selector %s, ports %s",
+			orDefault(str(nested(object, "spec", "type")), "ClusterIP"), mapString(nested(object, "spec", "selector")), servicePorts(nested(object, "spec", "ports"))))
+	case "PersistentVolumeClaim":
+		facts = append(facts, fmt.Sprintf("Claim storage class %s, access modes %s, requested %s",
+			orDefault(str(nested(object, "spec", "storageClassName")), "<default>"), listString(nested(object, "spec", "accessModes")), str(nested(object, "spec", "resources", "requests", "storage"))))
+	}
+
+	return append(facts, statusFacts(object)...)
+}
+
+// podSpec returns the pod spec of pods and of the workloads that template them
+func podSpec(kind string, object map[string]interface{}) map[string]interface{} {
+	var spec interface{}
+	switch kind {
+	case "Pod":
+		spec = nested(object, "spec")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		spec = nested(object, "spec", "template", "spec")
+	case "CronJob":
+		spec = nested(object, "spec", "jobTemplate", "spec", "template", "spec")
+	}
+	result, _ := spec.(map[string]interface{})
+	return result
+}
+
+// podSpecFacts summarizes containers, probes, resources and volumes of a pod spec
+func podSpecFacts(spec map[string]interface{}) []string {
+	var facts []string
+
+	if serviceAccount := str(spec["serviceAccountName"]); serviceAccount != "" {
+		facts = append(facts, "Service account: "+serviceAccount)
+	}
+
+	for _, key := range []string{"initContainers", "containers"} {
+		containers, _ := spec[key].([]interface{})
+		for _, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			parts := []string{fmt.Sprintf("Container %s: image %s", str(container["name"]), str(container["image"]))}
+			if requests := mapString(nested(container, "resources", "requests")); requests != "" {
+				parts = append(parts, "requests "+requests)
+			}
+			if limits := mapString(nested(container, "resources", "limits")); limits != "" {
+				parts = append(parts, "limits "+limits)
+			} else {
+				parts = append(parts, "no limits")
+			}
+			var probes []string
+			for _, probe := range []string{"livenessProbe", "readinessProbe", "startupProbe"} {
+				if _, ok := container[probe]; ok {
+					probes = append(probes, strings.TrimSuffix(probe, "Probe"))
+				}
+			}
+			if len(probes) > 0 {
+				parts = append(parts, "probes "+strings.Join(probes, ","))
+			}
+			if command := listString(container["command"]); command != "" {
+				parts = append(parts, "command "+command)
+			}
+			facts = append(facts, truncate(strings.Join(parts, ", ")))
+		}
+	}
+
+	volumes, _ := spec["volumes"].([]interface{})
+	for _, item := range volumes {
+		volume, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch {
+		case nested(volume, "persistentVolumeClaim") != nil:
+			facts = append(facts, fmt.Sprintf("Volume %s: PVC %s", str(volume["name"]), str(nested(volume, "persistentVolumeClaim", "claimName"))))
+		case nested(volume, "secret") != nil:
+			facts = append(facts, fmt.Sprintf("Volume %s: secret %s", str(volume["name"]), str(nested(volume, "secret", "secretName"))))
+		case nested(volume, "configMap") != nil:
+			facts = append(facts, fmt.Sprintf("Volume %s: config map %s", str(volume["name"]), str(nested(volume, "configMap", "name"))))
+		}
+	}
+
+	return facts
+}
+
+// statusFacts summarizes the status of objects exported with kubectl get -o yaml
+func statusFacts(object map[string]interface{}) []string {
+	var facts []string
+
+	if phase := str(nested(object, "status", "phase")); phase != "" {
+		facts = append(facts, "Status: "+phase)
+	}
+
+	conditions, _ := nested(object, "status", "conditions").([]interface{})
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok || str(condition["status"]) == "True" {
+			continue
+		}
+		facts = append(facts, truncate(fmt.Sprintf("Condition %s=%s %s %s", str(condition["type"]), str(condition["status"]), str(condition["reason"]), str(condition["message"]))))
+	}
+
+	for _, key := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _ := nested(object, "status", key).([]interface{})
+		for _, item := range statuses {
+			status, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			parts := []string{fmt.Sprintf("Container %s status:", str(status["name"]))}
+			if state := containerStatusState(status["state"]); state != "" {
+				parts = append(parts, "state "+state)
+			}
+			if state := containerStatusState(status["lastState"]); state != "" {
+				parts = append(parts, "last state "+state)
+			}
+			parts = append(parts, fmt.Sprintf("ready %v, restarts %v", orZero(status["ready"]), orZero(status["restartCount"])))
+			facts = append(facts, truncate(parts[0]+" "+strings.Join(parts[1:], ", ")))
+		}
+	}
+
+	return facts
+}
+
+// containerStatusState renders a container state such as {"terminated": {"reason": "Error", "exitCode": 1}}
+func containerStatusState(value interface{}) string {
+	state, _ := value.(map[string]interface{})
+	for _, name := range []string{"waiting", "terminated", "running"} {
+		details, ok := state[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var parts []string
+		if reason := str(details["reason"]); reason != "" {
+			parts = append(parts, reason)
+		}
+		if exitCode, ok := details["exitCode"]; ok {
+			parts = append(parts, fmt.Sprintf("exit code %v", exitCode))
+		}
+		if len(parts) == 0 {
+			return name
+		}
+		return fmt.Sprintf("%s (%s)", name, strings.Join(parts, ", "))
+	}
+	return ""
+}
+
+// servicePorts renders service ports as port->targetPort/protocol
+func servicePorts(value interface{}) string {
+	ports, _ := value.([]interface{})
+	var rendered []string
+	for _, item := range ports {
+		port, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rendered = append(rendered, fmt.Sprintf("%v->%v/%s", port["port"], orDefault(str(port["targetPort"]), str(port["port"])), orDefault(str(port["protocol"]), "TCP")))
+	}
+	return strings.Join(rendered, ",")
+}
+
+// nested walks a decoded object along the given keys
+func nested(object map[string]interface{}, keys ...string) interface{} {
+	var current interface{} = object
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// str renders scalars as strings and anything else as empty
+func str(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, int, int64, bool:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+// mapString renders a map as sorted key=value pairs
+func mapString(value interface{}) string {
+	m, _ := value.(map[string]interface{})
+	pairs := make([]string, 0, len(m))
+	for key, v := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, str(v)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// listString renders a list of scalars separated by spaces
+func listString(value interface{}) string {
+	list, _ := value.([]interface{})
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		items = append(items, str(item))
+	}
+	return strings.Join(items, " ")
+}
+
+// orZero renders missing numbers and booleans as 0
+func orZero(value interface{}) interface{} {
+	if value == nil {
+		return 0
+	}
+	return value
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,251 @@
+package bundles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/text"
+	"podscription-api/types"
+)
+
+const (
+	// maxContextLength caps the bundle excerpt given to a single diagnosis prompt
+	maxContextLength = 6000
+	// contextFindings, contextResources, contextEvents and contextLogs cap each part of the excerpt
+	contextFindings  = 5
+	contextResources = 5
+	contextEvents    = 8
+	contextLogs      = 3
+	// contextFactsPerResource caps the facts listed for each resource in the excerpt
+	contextFactsPerResource = 8
+)
+
+// Bundle is the searchable index of an ingested support bundle
+type Bundle struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Resources []Resource      `json:"resources"`
+	Events    []Event         `json:"events"`
+	Logs      []Log           `json:"logs"`
+	Findings  []types.Finding `json:"findings"`
+}
+
+// Resource is a Kubernetes object found in a bundle
+type Resource struct {
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Facts     []string `json:"facts"`
+}
+
+// Event is a Kubernetes event found in a bundle
+type Event struct {
+	Namespace string `json:"namespace,omitempty"`
+	Object    string `json:"object"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Count     int    `json:"count"`
+}
+
+// Log is a container log found in a bundle
+type Log struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Pod       string   `json:"pod"`
+	Container string   `json:"container,omitempty"`
+	Path      string   `json:"path"`
+	Facts     []string `json:"facts"`
+}
+
+// Ref returns the resource as kind/name, qualified with its namespace when it has one
+func (r Resource) Ref() string {
+	return qualifiedRef(strings.ToLower(r.Kind), r.Namespace, r.Name)
+}
+
+// Ref returns the event's involved object, qualified with its namespace when it has one
+func (e Event) Ref() string {
+	kind, name, _ := strings.Cut(e.Object, "/")
+	return qualifiedRef(kind, e.Namespace, name)
+}
+
+// Ref returns the log's pod and container
+func (l Log) Ref() string {
+	ref := qualifiedRef("pod", l.Namespace, l.Pod)
+	if l.Container != "" {
+		ref += " container " + l.Container
+	}
+	return ref
+}
+
+// Summary describes the bundle for API responses
+func (b *Bundle) Summary() types.BundleSummary {
+	findings := b.Findings
+	if findings == nil {
+		findings = []types.Finding{}
+	}
+	return types.BundleSummary{
+		ID:        b.ID,
+		Name:      b.Name,
+		CreatedAt: b.CreatedAt,
+		Resources: len(b.Resources),
+		Events:    len(b.Events),
+		Logs:      len(b.Logs),
+		Findings:  findings,
+	}
+}
+
+// Context returns a compact excerpt of the bundle relevant to a question: the top findings
+// followed by the resources, events and logs that best match the question's terms
+func (b *Bundle) Context(question string) string {
+	terms := questionTerms(question)
+	var sections []string
+
+	if len(b.Findings) > 0 {
+		var lines []string
+		for i, finding := range b.Findings {
+			if i == contextFindings {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("- [%s] %s: %s", finding.Severity, finding.Object, finding.Summary))
+		}
+		sections = append(sections, "Top findings:\n"+strings.Join(lines, "\n"))
+	}
+
+	resources := rank(len(b.Resources), contextResources, func(i int) int {
+		r := b.Resources[i]
+		return matchScore(terms, r.Name, r.Namespace, strings.ToLower(r.Kind), strings.Join(r.Facts, " "))
+	})
+	if len(resources) > 0 {
+		var lines []string
+		for _, i := range resources {
+			r := b.Resources[i]
+			facts := r.Facts
+			if len(facts) > contextFactsPerResource {
+				facts = facts[:contextFactsPerResource]
+			}
+			lines = append(lines, "- "+r.Ref())
+			for _, fact := range facts {
+				lines = append(lines, "  - "+fact)
+			}
+		}
+		sections = append(sections, "Relevant resources:\n"+strings.Join(lines, "\n"))
+	}
+
+	events := rank(len(b.Events), contextEvents, func(i int) int {
+		e := b.Events[i]
+		score := matchScore(terms, e.Object, e.Namespace, e.Reason, e.Message)
+		if score > 0 && e.Type == "Warning" {
+			score++
+		}
+		return score
+	})
+	if len(events) > 0 {
+		var lines []string
+		for _, i := range events {
+			e := b.Events[i]
+			lines = append(lines, fmt.Sprintf("- %s %s on %s (x%d): %s", e.Type, e.Reason, e.Ref(), e.Count, e.Message))
+		}
+		sections = append(sections, "Relevant events:\n"+strings.Join(lines, "\n"))
+	}
+
+	logs := rank(len(b.Logs), contextLogs, func(i int) int {
+		l := b.Logs[i]
+		return matchScore(terms, l.Pod, l.Namespace, l.Container, strings.Join(l.Facts, " "))
+	})
+	if len(logs) > 0 {
+		var lines []string
+		for _, i := range logs {
+			l := b.Logs[i]
+			lines = append(lines, "- logs of "+l.Ref())
+			for _, fact := range l.Facts {
+				lines = append(lines, "  - "+fact)
+			}
+		}
+		sections = append(sections, "Relevant logs:\n"+strings.Join(lines, "\n"))
+	}
+
+	excerpt := strings.Join(sections, "\n\n")
+	if len(excerpt) > maxContextLength {
+		excerpt = excerpt[:maxContextLength] + "\n..."
+	}
+	return excerpt
+}
+
+// rank returns the indexes of the best scoring items, dropping items that do not match at all
+func rank(count, limit int, score func(i int) int) []int {
+	type scored struct{ index, score int }
+	var matches []scored
+	for i := 0; i < count; i++ {
+		if s := score(i); s > 0 {
+			matches = append(matches, scored{i, s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	var indexes []int
+	for i, match := range matches {
+		if i == limit {
+			break
+		}
+		indexes = append(indexes, match.index)
+	}
+	return indexes
+}
+
+// matchScore scores an item against the question terms, weighting its name above its other fields
+func matchScore(terms []string, name string, fields ...string) int {
+	name = strings.ToLower(name)
+	text := strings.ToLower(strings.Join(fields, " "))
+
+	score := 0
+	for _, term := range terms {
+		switch {
+		case name == term:
+			score += 6
+		case strings.Contains(name, term):
+			score += 3
+		case strings.Contains(text, term):
+			score++
+		}
+	}
+	return score
+}
+
+// bundleStopWords name every resource of a bundle, so they cannot select any of them
+var bundleStopWords = map[string]bool{"bundle": true, "cluster": true}
+
+// questionTerms splits a question into lowercase search terms, keeping Kubernetes names intact
+func questionTerms(question string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '/')
+	}) {
+		field = strings.Trim(field, "-./")
+		if index := strings.LastIndex(field, "/"); index >= 0 {
+			field = field[index+1:]
+		}
+		if len(field) < 3 || text.IsStopWord(field) || bundleStopWords[field] || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// qualifiedRef renders kind/namespace/name references, omitting empty parts
+func qualifiedRef(kind, namespace, name string) string {
+	ref := name
+	if namespace != "" {
+		ref = namespace + "/" + name
+	}
+	if kind != "" {
+		ref = kind + " " + ref
+	}
+	return ref
+}
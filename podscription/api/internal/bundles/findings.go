@@ -0,0 +1,224 @@
+package bundles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"podscription-api/types"
+)
+
+const (
+	// maxFindings caps the findings kept for a bundle
+	maxFindings = 50
+	// restartWarningThreshold is the restart count from which a container is reported
+	restartWarningThreshold = 5
+	// eventWarningThreshold is the number of repeats from which a warning event is reported
+	eventWarningThreshold = 3
+)
+
+// criticalWaitingReasons are container waiting reasons that keep a workload down
+var criticalWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// severityRank orders findings from most to least urgent
+var severityRank = map[types.FindingSeverity]int{
+	types.FindingSeverityCritical: 3,
+	types.FindingSeverityWarning:  2,
+	types.FindingSeverityInfo:     1,
+}
+
+// resourceFindings detects problems in the status of a single object
+func resourceFindings(kind, namespace, name string, object map[string]interface{}) []types.Finding {
+	ref := qualifiedRef(strings.ToLower(kind), namespace, name)
+	status, _ := object["status"].(map[string]interface{})
+	var findings []types.Finding
+
+	switch kind {
+	case "Pod":
+		phase, _ := status["phase"].(string)
+		switch phase {
+		case "Pending":
+			summary := "Pod is Pending"
+			if message := conditionMessage(status, "PodScheduled", "False"); message != "" {
+				summary += ": " + message
+			}
+			findings = append(findings, newFinding(types.FindingSeverityWarning, ref, summary))
+		case "Failed":
+			reason, _ := status["reason"].(string)
+			message, _ := status["message"].(string)
+			findings = append(findings, newFinding(types.FindingSeverityWarning, ref, strings.TrimSpace(fmt.Sprintf("Pod failed %s %s", reason, message))))
+		}
+
+		for _, key := range []string{"initContainerStatuses", "containerStatuses"} {
+			containers, _ := status[key].([]interface{})
+			for _, item := range containers {
+				findings = append(findings, containerFindings(ref, item)...)
+			}
+		}
+
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		spec, _ := object["spec"].(map[string]interface{})
+		desired := number(spec["replicas"], 1)
+		ready := number(status["readyReplicas"], 0)
+		if ready < desired && (kind != "ReplicaSet" || desired > 0) {
+			findings = append(findings, newFinding(types.FindingSeverityWarning, ref, fmt.Sprintf("Only %d of %d replicas are ready", ready, desired)))
+		}
+
+	case "DaemonSet":
+		desired := number(status["desiredNumberScheduled"], 0)
+		ready := number(status["numberReady"], 0)
+		if ready < desired {
+			findings = append(findings, newFinding(types.FindingSeverityWarning, ref, fmt.Sprintf("Only %d of %d daemon pods are ready", ready, desired)))
+		}
+
+	case "PersistentVolumeClaim":
+		if phase, _ := status["phase"].(string); phase != "" && phase != "Bound" {
+			findings = append(findings, newFinding(types.FindingSeverityWarning, ref, "Claim is "+phase))
+		}
+
+	case "Node":
+		conditions, _ := status["conditions"].([]interface{})
+		for _, item := range conditions {
+			condition, _ := item.(map[string]interface{})
+			conditionType, _ := condition["type"].(string)
+			conditionStatus, _ := condition["status"].(string)
+			message, _ := condition["message"].(string)
+			switch {
+			case conditionType == "Ready" && conditionStatus != "True":
+				findings = append(findings, newFinding(types.FindingSeverityCritical, ref, "Node is NotReady: "+message))
+			case conditionType != "Ready" && conditionStatus == "True" && strings.HasSuffix(conditionType, "Pressure"):
+				findings = append(findings, newFinding(types.FindingSeverityWarning, ref, fmt.Sprintf("Node reports %s: %s", conditionType, message)))
+			}
+		}
+	}
+
+	return findings
+}
+
+// containerFindings detects crash loops, pull failures, OOM kills and frequent restarts of a container
+func containerFindings(podRef string, item interface{}) []types.Finding {
+	container, _ := item.(map[string]interface{})
+	name, _ := container["name"].(string)
+	restarts := number(container["restartCount"], 0)
+	var findings []types.Finding
+
+	waiting, _ := nestedMap(container, "state", "waiting")
+	if reason, _ := waiting["reason"].(string); criticalWaitingReasons[reason] {
+		summary := fmt.Sprintf("Container %s is in %s", name, reason)
+		if restarts > 0 {
+			summary += fmt.Sprintf(" after %d restarts", restarts)
+		}
+		if message, _ := waiting["message"].(string); message != "" {
+			summary += ": " + message
+		}
+		findings = append(findings, newFinding(types.FindingSeverityCritical, podRef, summary))
+	}
+
+	terminated, _ := nestedMap(container, "lastState", "terminated")
+	if reason, _ := terminated["reason"].(string); reason == "OOMKilled" {
+		findings = append(findings, newFinding(types.FindingSeverityCritical, podRef, fmt.Sprintf("Container %s was OOMKilled (exit code 137)", name)))
+	} else if len(findings) == 0 && restarts >= restartWarningThreshold {
+		summary := fmt.Sprintf("Container %s restarted %d times", name, restarts)
+		if reason != "" {
+			summary += fmt.Sprintf(", last exit %s (exit code %d)", reason, number(terminated["exitCode"], 0))
+		}
+		findings = append(findings, newFinding(types.FindingSeverityWarning, podRef, summary))
+	}
+
+	return findings
+}
+
+// logFindings reports logs with error lines
+func logFindings(log Log) []types.Finding {
+	var errorLines []string
+	for _, fact := range log.Facts {
+		if strings.HasPrefix(fact, "Log: ") {
+			errorLines = append(errorLines, strings.TrimPrefix(fact, "Log: "))
+		}
+	}
+	if len(errorLines) == 0 {
+		return nil
+	}
+	return []types.Finding{newFinding(types.FindingSeverityInfo, "logs of "+log.Ref(),
+		fmt.Sprintf("%d error lines, latest: %s", len(errorLines), errorLines[len(errorLines)-1]))}
+}
+
+// detectFindings adds findings for repeated warning events and ranks all findings by severity
+func detectFindings(b *Bundle) []types.Finding {
+	findings := append([]types.Finding(nil), b.Findings...)
+	for _, event := range b.Events {
+		if event.Type == "Warning" && event.Count >= eventWarningThreshold {
+			findings = append(findings, newFinding(types.FindingSeverityWarning, event.Ref(),
+				fmt.Sprintf("%s event seen %d times: %s", event.Reason, event.Count, event.Message)))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+	if len(findings) > maxFindings {
+		findings = findings[:maxFindings]
+	}
+	return findings
+}
+
+// sortEvents orders events with warnings first, most repeated first
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if (events[i].Type == "Warning") != (events[j].Type == "Warning") {
+			return events[i].Type == "Warning"
+		}
+		return events[i].Count > events[j].Count
+	})
+}
+
+// newFinding creates a finding with a single-line summary
+func newFinding(severity types.FindingSeverity, object, summary string) types.Finding {
+	summary = strings.Join(strings.Fields(summary), " ")
+	if len(summary) > 240 {
+		summary = summary[:240] + "..."
+	}
+	return types.Finding{Severity: severity, Object: object, Summary: summary}
+}
+
+// conditionMessage returns the message of a condition with the given type and status
+func conditionMessage(status map[string]interface{}, conditionType, conditionStatus string) string {
+	conditions, _ := status["conditions"].([]interface{})
+	for _, item := range conditions {
+		condition, _ := item.(map[string]interface{})
+		if condition["type"] == conditionType && condition["status"] == conditionStatus {
+			message, _ := condition["message"].(string)
+			return message
+		}
+	}
+	return ""
+}
+
+// nestedMap walks a decoded object along the given keys to a nested object
+func nestedMap(object map[string]interface{}, keys ...string) (map[string]interface{}, bool) {
+	current := object
+	for _, key := range keys {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// number reads a decoded JSON number, returning fallback when it is missing
+func number(value interface{}, fallback int) int {
+	if n, ok := value.(float64); ok {
+		return int(n)
+	}
+	return fallback
+}
@@ -0,0 +1,307 @@
+package bundles
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/attachments"
+	"podscription-api/types"
+	"sigs.k8s.io/yaml"
+)
+
+// Archive limits, variables so that tests can lower them
+var (
+	// maxEntrySize is the most read from a single archive entry; larger logs are truncated
+	maxEntrySize int64 = 16 * 1024 * 1024
+	// maxExtractedSize bounds the total uncompressed size of an archive, including the parts
+	// of oversized entries that are skipped, since the gzip stream is decompressed either way
+	maxExtractedSize int64 = 256 * 1024 * 1024
+)
+
+// ErrInvalidBundle is returned when an upload is not a readable tar.gz support bundle
+var ErrInvalidBundle = errors.New("invalid support bundle")
+
+// pathKinds maps the directory and file names used by troubleshoot.sh and
+// kubectl cluster-info dump onto the kind of the objects they contain
+var pathKinds = map[string]string{
+	"pods":                     "Pod",
+	"events":                   "Event",
+	"deployments":              "Deployment",
+	"statefulsets":             "StatefulSet",
+	"daemonsets":               "DaemonSet",
+	"replicasets":              "ReplicaSet",
+	"replication-controllers":  "ReplicationController",
+	"jobs":                     "Job",
+	"cronjobs":                 "CronJob",
+	"services":                 "Service",
+	"ingress":                  "Ingress",
+	"ingresses":                "Ingress",
+	"pvcs":                     "PersistentVolumeClaim",
+	"persistentvolumeclaims":   "PersistentVolumeClaim",
+	"pvs":                      "PersistentVolume",
+	"persistentvolumes":        "PersistentVolume",
+	"storage-classes":          "StorageClass",
+	"storageclasses":           "StorageClass",
+	"nodes":                    "Node",
+	"configmaps":               "ConfigMap",
+	"serviceaccounts":          "ServiceAccount",
+	"roles":                    "Role",
+	"rolebindings":             "RoleBinding",
+	"clusterroles":             "ClusterRole",
+	"clusterrolebindings":      "ClusterRoleBinding",
+	"networkpolicies":          "NetworkPolicy",
+	"network-policy":           "NetworkPolicy",
+	"horizontalpodautoscalers": "HorizontalPodAutoscaler",
+	"resource-quotas":          "ResourceQuota",
+	"limitranges":              "LimitRange",
+}
+
+// documentSeparator splits multi-document YAML
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// dumpLogSection matches the log sections of a single-stream kubectl cluster-info dump
+var dumpLogSection = regexp.MustCompile(`(?m)^==== START logs for container (\S+) of pod (\S+)/(\S+) ====\n([\s\S]*?)^==== END logs for container \S+ of pod \S+ ====`)
+
+// Ingest indexes the resources, events and logs of a tar.gz support bundle
+func Ingest(name string, r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a gzip archive: %w", ErrInvalidBundle, err)
+	}
+	defer gz.Close()
+
+	bundle := &Bundle{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	events := make(map[string]*Event)
+
+	archive := tar.NewReader(gz)
+	var extracted int64
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+		// Links, directories and devices carry no content worth indexing
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Nothing is written to disk, but entry names end up in paths shown to users and the model
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%w: entry %q escapes the archive", ErrInvalidBundle, header.Name)
+		}
+
+		extracted += header.Size
+		if extracted > maxExtractedSize {
+			return nil, fmt.Errorf("%w: more than %d MiB once extracted", ErrInvalidBundle, maxExtractedSize/1024/1024)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(archive, maxEntrySize))
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read %s: %w", ErrInvalidBundle, header.Name, err)
+		}
+
+		bundle.ingestFile(name, data, events)
+	}
+
+	for _, event := range events {
+		bundle.Events = append(bundle.Events, *event)
+	}
+	sortEvents(bundle.Events)
+	bundle.Findings = detectFindings(bundle)
+
+	if len(bundle.Resources) == 0 && len(bundle.Events) == 0 && len(bundle.Logs) == 0 {
+		return nil, fmt.Errorf("%w: no Kubernetes resources, events or logs found", ErrInvalidBundle)
+	}
+
+	return bundle, nil
+}
+
+// ingestFile indexes a single archive entry by its extension
+func (b *Bundle) ingestFile(name string, data []byte, events map[string]*Event) {
+	ext := strings.ToLower(path.Ext(name))
+
+	// A single-stream cluster-info dump interleaves JSON lists with log sections
+	if dumpLogSection.Match(data) {
+		b.ingestDumpLogs(data)
+		data = dumpLogSection.ReplaceAll(data, nil)
+		ext = ".json"
+	}
+
+	switch ext {
+	case ".json":
+		for _, object := range decodeJSON(data) {
+			b.ingestObject(name, object, events)
+		}
+	case ".yaml", ".yml":
+		for _, document := range documentSeparator.Split(string(data), -1) {
+			var object map[string]interface{}
+			if yaml.Unmarshal([]byte(document), &object) == nil && object != nil {
+				b.ingestObject(name, object, events)
+			}
+		}
+	case ".log", ".txt":
+		b.ingestLog(name, string(data))
+	}
+}
+
+// decodeJSON decodes every JSON object in data, which may hold several concatenated values
+func decodeJSON(data []byte) []map[string]interface{} {
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return objects
+		}
+		objects = append(objects, object)
+	}
+}
+
+// ingestObject indexes an object or every item of a list, inferring kinds from the list or the file path
+func (b *Bundle) ingestObject(name string, object map[string]interface{}, events map[string]*Event) {
+	kind, _ := object["kind"].(string)
+
+	if items, ok := object["items"].([]interface{}); ok {
+		itemKind := strings.TrimSuffix(kind, "List")
+		if itemKind == "" {
+			itemKind = pathKind(name)
+		}
+		for _, item := range items {
+			if itemObject, ok := item.(map[string]interface{}); ok {
+				if _, ok := itemObject["kind"].(string); !ok && itemKind != "" {
+					itemObject["kind"] = itemKind
+				}
+				b.ingestObject(name, itemObject, events)
+			}
+		}
+		return
+	}
+
+	if kind == "" {
+		kind = pathKind(name)
+		object["kind"] = kind
+	}
+	metadata, _ := object["metadata"].(map[string]interface{})
+	objectName, _ := metadata["name"].(string)
+	if kind == "" || objectName == "" {
+		return
+	}
+	namespace, _ := metadata["namespace"].(string)
+
+	if kind == "Event" {
+		addEvent(events, namespace, object)
+		return
+	}
+
+	b.Resources = append(b.Resources, Resource{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      objectName,
+		Facts:     attachments.ObjectFacts(object),
+	})
+	b.Findings = append(b.Findings, resourceFindings(kind, namespace, objectName, object)...)
+}
+
+// addEvent merges an event into the index, aggregating repeats of the same reason on the same object
+func addEvent(events map[string]*Event, namespace string, object map[string]interface{}) {
+	involved, _ := object["involvedObject"].(map[string]interface{})
+	involvedKind, _ := involved["kind"].(string)
+	involvedName, _ := involved["name"].(string)
+	eventType, _ := object["type"].(string)
+	reason, _ := object["reason"].(string)
+	message, _ := object["message"].(string)
+	count := 1
+	if value, ok := object["count"].(float64); ok && value > 1 {
+		count = int(value)
+	}
+
+	ref := strings.ToLower(involvedKind) + "/" + involvedName
+	key := namespace + "|" + ref + "|" + eventType + "|" + reason
+	if event, ok := events[key]; ok {
+		event.Count += count
+		event.Message = message
+		return
+	}
+	events[key] = &Event{
+		Namespace: namespace,
+		Object:    ref,
+		Type:      eventType,
+		Reason:    reason,
+		Message:   message,
+		Count:     count,
+	}
+}
+
+// ingestLog indexes a container log, deriving namespace, pod and container from its path:
+// .../<namespace>/<pod>/<container>.log or .../<namespace>/<pod>/logs.txt
+func (b *Bundle) ingestLog(name, content string) {
+	parts := strings.Split(name, "/")
+	base := strings.TrimSuffix(parts[len(parts)-1], path.Ext(name))
+
+	log := Log{Path: name}
+	if base != "logs" {
+		log.Container = base
+	}
+	if len(parts) >= 2 {
+		log.Pod = parts[len(parts)-2]
+	}
+	if len(parts) >= 3 {
+		log.Namespace = parts[len(parts)-3]
+	}
+	if log.Pod == "" {
+		log.Pod = base
+	}
+
+	b.addLog(log, content)
+}
+
+// ingestDumpLogs indexes the log sections of a single-stream kubectl cluster-info dump
+func (b *Bundle) ingestDumpLogs(data []byte) {
+	for _, match := range dumpLogSection.FindAllSubmatch(data, -1) {
+		b.addLog(Log{
+			Namespace: string(match[2]),
+			Pod:       string(match[3]),
+			Container: string(match[1]),
+			Path:      "cluster-info dump",
+		}, string(match[4]))
+	}
+}
+
+// addLog extracts the facts of a log and records findings for logs full of errors
+func (b *Bundle) addLog(log Log, content string) {
+	log.Facts = attachments.Extract(types.Attachment{Kind: types.AttachmentKindLogs, Content: content})
+	if len(log.Facts) == 0 {
+		return
+	}
+	b.Logs = append(b.Logs, log)
+	b.Findings = append(b.Findings, logFindings(log)...)
+}
+
+// pathKind infers the kind of the objects in a file from the names of its directories and itself
+func pathKind(name string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSuffix(name, path.Ext(name))), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if kind, ok := pathKinds[parts[i]]; ok {
+			return kind
+		}
+	}
+	return ""
+}
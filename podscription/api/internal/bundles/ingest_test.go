@@ -0,0 +1,264 @@
+package bundles
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+
+	"podscription-api/types"
+)
+
+// archiveEntry is a file written into a test bundle
+type archiveEntry struct {
+	name     string
+	body     string
+	typeflag byte
+	linkname string
+}
+
+// buildBundle writes entries into a tar.gz archive
+func buildBundle(t *testing.T, entries ...archiveEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		header := &tar.Header{Name: entry.name, Typeflag: typeflag, Linkname: entry.linkname, Mode: 0644}
+		if typeflag == tar.TypeReg {
+			header.Size = int64(len(entry.body))
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write %s: %v", entry.name, err)
+		}
+		if typeflag == tar.TypeReg {
+			if _, err := archive.Write([]byte(entry.body)); err != nil {
+				t.Fatalf("failed to write %s: %v", entry.name, err)
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip stream: %v", err)
+	}
+	return &buf
+}
+
+const samplePods = `{
+  "kind": "PodList",
+  "items": [
+    {
+      "metadata": {"name": "web-7d9f", "namespace": "shop"},
+      "status": {
+        "phase": "Running",
+        "containerStatuses": [
+          {"name": "web", "restartCount": 12, "state": {"waiting": {"reason": "CrashLoopBackOff", "message": "back-off 5m0s restarting failed container"}}}
+        ]
+      }
+    },
+    {
+      "metadata": {"name": "worker-1", "namespace": "shop"},
+      "status": {
+        "phase": "Running",
+        "containerStatuses": [
+          {"name": "worker", "restartCount": 1, "lastState": {"terminated": {"reason": "OOMKilled", "exitCode": 137}}}
+        ]
+      }
+    },
+    {
+      "metadata": {"name": "api-0", "namespace": "shop"},
+      "status": {
+        "phase": "Pending",
+        "conditions": [{"type": "PodScheduled", "status": "False", "message": "0/3 nodes are available: 3 Insufficient cpu."}]
+      }
+    }
+  ]
+}`
+
+const sampleEvents = `items:
+- metadata: {name: web-7d9f.1, namespace: shop}
+  involvedObject: {kind: Pod, name: web-7d9f}
+  type: Warning
+  reason: BackOff
+  message: Back-off restarting failed container
+  count: 7
+- metadata: {name: web-7d9f.2, namespace: shop}
+  involvedObject: {kind: Pod, name: web-7d9f}
+  type: Normal
+  reason: Pulled
+  message: Container image already present on machine
+`
+
+const sampleNodes = `{"kind": "NodeList", "items": [{"metadata": {"name": "node-2"}, "status": {"conditions": [{"type": "Ready", "status": "Unknown", "message": "Kubelet stopped posting node status."}]}}]}`
+
+func TestIngestSampleBundle(t *testing.T) {
+	archive := buildBundle(t,
+		archiveEntry{name: "support-bundle/cluster-resources/", typeflag: tar.TypeDir},
+		archiveEntry{name: "support-bundle/cluster-resources/pods/shop.json", body: samplePods},
+		archiveEntry{name: "support-bundle/cluster-resources/events/shop.yaml", body: sampleEvents},
+		archiveEntry{name: "support-bundle/cluster-resources/nodes.json", body: sampleNodes},
+		archiveEntry{name: "support-bundle/cluster-resources/pvcs/shop.json", body: `{"items": [{"metadata": {"name": "data-db-0", "namespace": "shop"}, "status": {"phase": "Pending"}}]}`},
+		archiveEntry{name: "support-bundle/logs/shop/web-7d9f/web.log", body: "starting\npanic: missing DATABASE_URL\n"},
+	)
+
+	bundle, err := Ingest("sample.tar.gz", archive)
+	if err != nil {
+		t.Fatalf("failed to ingest bundle: %v", err)
+	}
+
+	if len(bundle.Resources) != 5 || len(bundle.Events) != 2 || len(bundle.Logs) != 1 {
+		t.Fatalf("got %d resources, %d events and %d logs, want 5, 2 and 1", len(bundle.Resources), len(bundle.Events), len(bundle.Logs))
+	}
+	if bundle.Resources[4].Kind != "PersistentVolumeClaim" {
+		t.Fatalf("got kind %q, want it inferred from the pvcs directory", bundle.Resources[4].Kind)
+	}
+	if log := bundle.Logs[0]; log.Namespace != "shop" || log.Pod != "web-7d9f" || log.Container != "web" {
+		t.Fatalf("got log %+v, want it attributed from its path", log)
+	}
+
+	want := []struct {
+		severity types.FindingSeverity
+		object   string
+		summary  string
+	}{
+		{types.FindingSeverityCritical, "pod shop/web-7d9f", "Container web is in CrashLoopBackOff after 12 restarts"},
+		{types.FindingSeverityCritical, "pod shop/worker-1", "Container worker was OOMKilled"},
+		{types.FindingSeverityCritical, "node node-2", "Node is NotReady: Kubelet stopped posting node status."},
+		{types.FindingSeverityWarning, "pod shop/api-0", "Pod is Pending: 0/3 nodes are available: 3 Insufficient cpu."},
+		{types.FindingSeverityWarning, "persistentvolumeclaim shop/data-db-0", "Claim is Pending"},
+		{types.FindingSeverityWarning, "pod shop/web-7d9f", "BackOff event seen 7 times"},
+		{types.FindingSeverityInfo, "logs of pod shop/web-7d9f container web", "panic: missing DATABASE_URL"},
+	}
+	if len(bundle.Findings) != len(want) {
+		t.Fatalf("got findings %+v, want %d", bundle.Findings, len(want))
+	}
+	for _, finding := range want {
+		if !hasFinding(bundle.Findings, finding.severity, finding.object, finding.summary) {
+			t.Errorf("missing %s finding on %s: %s\ngot %+v", finding.severity, finding.object, finding.summary, bundle.Findings)
+		}
+	}
+	for i := 1; i < len(bundle.Findings); i++ {
+		if severityRank[bundle.Findings[i].Severity] > severityRank[bundle.Findings[i-1].Severity] {
+			t.Fatalf("got findings %+v, want them ranked by severity", bundle.Findings)
+		}
+	}
+}
+
+func TestIngestRejectsTraversal(t *testing.T) {
+	for _, name := range []string{"../etc/passwd.json", "bundle/../../pods.json", "/etc/pods.json", ".."} {
+		t.Run(name, func(t *testing.T) {
+			archive := buildBundle(t,
+				archiveEntry{name: "bundle/nodes.json", body: sampleNodes},
+				archiveEntry{name: name, body: samplePods},
+			)
+
+			_, err := Ingest("traversal.tar.gz", archive)
+			if !errors.Is(err, ErrInvalidBundle) || !strings.Contains(err.Error(), "escapes the archive") {
+				t.Fatalf("got %v, want the entry rejected", err)
+			}
+		})
+	}
+
+	// Names that only look suspicious stay inside the archive
+	archive := buildBundle(t, archiveEntry{name: "bundle/pods/../nodes..json", body: sampleNodes})
+	if _, err := Ingest("dots.tar.gz", archive); err != nil {
+		t.Fatalf("failed to ingest bundle: %v", err)
+	}
+}
+
+func TestIngestSkipsNonRegularEntries(t *testing.T) {
+	archive := buildBundle(t,
+		archiveEntry{name: "bundle/", typeflag: tar.TypeDir},
+		archiveEntry{name: "bundle/pods.json", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+		archiveEntry{name: "bundle/events.json", typeflag: tar.TypeLink, linkname: "bundle/nodes.json"},
+		archiveEntry{name: "bundle/fifo.log", typeflag: tar.TypeFifo},
+		archiveEntry{name: "../outside.json", typeflag: tar.TypeSymlink, linkname: "/"},
+		archiveEntry{name: "bundle/nodes.json", body: sampleNodes},
+	)
+
+	bundle, err := Ingest("links.tar.gz", archive)
+	if err != nil {
+		t.Fatalf("failed to ingest bundle: %v", err)
+	}
+	if len(bundle.Resources) != 1 || bundle.Resources[0].Kind != "Node" || len(bundle.Events) != 0 || len(bundle.Logs) != 0 {
+		t.Fatalf("got %+v, want only the regular nodes file indexed", bundle)
+	}
+}
+
+func TestIngestOversizeEntries(t *testing.T) {
+	restore := lowerArchiveLimits(64, 256)
+	defer restore()
+
+	// An entry above maxEntrySize is truncated, not rejected
+	log := "panic: first line\n" + strings.Repeat("x", 100)
+	archive := buildBundle(t, archiveEntry{name: "bundle/logs/shop/web/web.log", body: log})
+	bundle, err := Ingest("truncated.tar.gz", archive)
+	if err != nil {
+		t.Fatalf("failed to ingest bundle: %v", err)
+	}
+	if len(bundle.Logs) != 1 || !strings.Contains(strings.Join(bundle.Logs[0].Facts, "\n"), "panic: first line") {
+		t.Fatalf("got logs %+v, want the start of the log kept", bundle.Logs)
+	}
+
+	// The skipped remainder of oversized entries still counts towards maxExtractedSize
+	archive = buildBundle(t,
+		archiveEntry{name: "bundle/nodes.json", body: sampleNodes[:64]},
+		archiveEntry{name: "bundle/huge.log", body: strings.Repeat("x", 300)},
+	)
+	if _, err := Ingest("bomb.tar.gz", archive); !errors.Is(err, ErrInvalidBundle) || !strings.Contains(err.Error(), "once extracted") {
+		t.Fatalf("got %v, want the archive rejected for its extracted size", err)
+	}
+}
+
+func TestIngestRejectsInvalidArchives(t *testing.T) {
+	var notTar bytes.Buffer
+	gz := gzip.NewWriter(&notTar)
+	gz.Write([]byte(strings.Repeat("this is a plain text file, not a tar archive\n", 20)))
+	gz.Close()
+
+	tests := []struct {
+		name string
+		body *bytes.Buffer
+	}{
+		{"not gzip", bytes.NewBufferString("PK\x03\x04 a zip file")},
+		{"gzip but not tar", &notTar},
+		{"empty archive", buildBundle(t)},
+		{"no Kubernetes content", buildBundle(t, archiveEntry{name: "README.md", body: "# hello"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Ingest(tt.name, tt.body); !errors.Is(err, ErrInvalidBundle) {
+				t.Fatalf("got %v, want ErrInvalidBundle", err)
+			}
+		})
+	}
+}
+
+// lowerArchiveLimits sets the archive limits for a test, returning a function restoring them
+func lowerArchiveLimits(entry, extracted int64) func() {
+	previousEntry, previousExtracted := maxEntrySize, maxExtractedSize
+	maxEntrySize, maxExtractedSize = entry, extracted
+	return func() {
+		maxEntrySize, maxExtractedSize = previousEntry, previousExtracted
+	}
+}
+
+// hasFinding reports whether a finding with the given severity and object contains summary
+func hasFinding(findings []types.Finding, severity types.FindingSeverity, object, summary string) bool {
+	for _, finding := range findings {
+		if finding.Severity == severity && finding.Object == object && strings.Contains(finding.Summary, summary) {
+			return true
+		}
+	}
+	return false
+}
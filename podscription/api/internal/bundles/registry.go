@@ -0,0 +1,98 @@
+package bundles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/fileutil"
+)
+
+// Registry keeps ingested bundles in memory, optionally persisting their index to a directory.
+// Replicas sharing a store must share the directory too, a bundle saved by one is loaded by the
+// others from there.
+type Registry struct {
+	bundles map[uuid.UUID]*Bundle
+	mu      sync.RWMutex
+	dir     string
+}
+
+// NewRegistry creates a bundle registry persisting to dir, or only in memory when dir is empty
+func NewRegistry(dir string) *Registry {
+	return &Registry{
+		bundles: make(map[uuid.UUID]*Bundle),
+		dir:     dir,
+	}
+}
+
+// Save registers a bundle and writes its index to the registry directory
+func (r *Registry) Save(bundle *Bundle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bundles[bundle.ID] = bundle
+	if r.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	// A reader after a restart must never see a partially written index
+	if err := fileutil.WriteFileAtomic(r.path(bundle.ID), data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Delete forgets a bundle and removes its index from the registry directory; deleting a
+// bundle that does not exist is not an error
+func (r *Registry) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.bundles, id)
+	if r.dir == "" {
+		return nil
+	}
+	if err := os.Remove(r.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove bundle: %w", err)
+	}
+	return nil
+}
+
+// Get returns a bundle by ID, loading it from the registry directory after a restart
+func (r *Registry) Get(id uuid.UUID) (*Bundle, error) {
+	r.mu.RLock()
+	bundle, exists := r.bundles[id]
+	r.mu.RUnlock()
+	if exists {
+		return bundle, nil
+	}
+
+	if r.dir == "" {
+		return nil, fmt.Errorf("bundle not found: %s", id)
+	}
+	data, err := os.ReadFile(r.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("bundle not found: %s", id)
+	}
+	bundle = &Bundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle %s: %w", id, err)
+	}
+
+	r.mu.Lock()
+	r.bundles[id] = bundle
+	r.mu.Unlock()
+	return bundle, nil
+}
+
+// path returns the file a bundle index is persisted to
+func (r *Registry) path(id uuid.UUID) string {
+	return filepath.Join(r.dir, id.String()+".json")
+}
@@ -0,0 +1,61 @@
+package bundles
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRegistryPersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	bundle := &Bundle{ID: uuid.New(), Name: "sample.tar.gz", Resources: []Resource{{Kind: "Pod", Namespace: "shop", Name: "web"}}}
+
+	if err := NewRegistry(dir).Save(bundle); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read registry directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != bundle.ID.String()+".json" {
+		t.Fatalf("got %v, want only the bundle index and no temporary files", entries)
+	}
+
+	loaded, err := NewRegistry(dir).Get(bundle.ID)
+	if err != nil {
+		t.Fatalf("failed to get bundle after a restart: %v", err)
+	}
+	if loaded.Name != bundle.Name || len(loaded.Resources) != 1 || loaded.Resources[0].Ref() != "pod shop/web" {
+		t.Fatalf("got %+v, want the saved bundle", loaded)
+	}
+
+	if _, err := NewRegistry(dir).Get(uuid.New()); err == nil {
+		t.Fatal("got a bundle for an unknown ID, want an error")
+	}
+}
+
+func TestRegistryDelete(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewRegistry(dir)
+	bundle := &Bundle{ID: uuid.New(), Name: "sample.tar.gz"}
+	if err := registry.Save(bundle); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+
+	if err := registry.Delete(bundle.ID); err != nil {
+		t.Fatalf("failed to delete bundle: %v", err)
+	}
+	if _, err := registry.Get(bundle.ID); err == nil {
+		t.Fatal("got a deleted bundle, want an error")
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Fatalf("got %d files (%v), want the bundle index removed", len(entries), err)
+	}
+
+	// Deleting twice, or a bundle never saved, is not an error
+	if err := registry.Delete(bundle.ID); err != nil {
+		t.Fatalf("failed to delete a deleted bundle: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+// Package cases finds past consultations similar to a new problem, so that earlier
+// diagnoses can inform the next one
+package cases
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/text"
+	"podscription-api/types"
+)
+
+// categoryBoost is added to the similarity of cases in the same intent category
+const categoryBoost = 0.1
+
+// Query describes the problem to find similar cases for
+type Query struct {
+	// Exclude is the session asking, which is never its own related case
+	Exclude  uuid.UUID
+	Text     string
+	Category types.IntentCategory
+	Symptoms []string
+}
+
+// Index ranks past sessions that reached a diagnosis by the TF-IDF cosine similarity of
+// their problem descriptions, symptoms and diagnoses to a new problem. The index lives in
+// process, so each replica only learns the sessions it serves until it is rebuilt.
+type Index struct {
+	mu    sync.RWMutex
+	cases map[uuid.UUID]indexedCase
+	// documentFrequency counts the cases containing each term
+	documentFrequency map[string]int
+}
+
+// indexedCase is a session's entry in the index
+type indexedCase struct {
+	summary types.RelatedCase
+	terms   map[string]int
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		cases:             make(map[uuid.UUID]indexedCase),
+		documentFrequency: make(map[string]int),
+	}
+}
+
+// Add indexes a session, replacing its previous entry. Sessions without a diagnosis
+// are not cases yet and are left out.
+func (idx *Index) Add(session *types.Session) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(session.ID)
+
+	summary, document, ok := describe(session)
+	if !ok {
+		return
+	}
+
+	terms := make(map[string]int)
+	for _, term := range text.Tokenize(document) {
+		terms[term]++
+	}
+	for term := range terms {
+		idx.documentFrequency[term]++
+	}
+	idx.cases[session.ID] = indexedCase{summary: summary, terms: terms}
+}
+
+// Remove drops a session from the index
+func (idx *Index) Remove(id uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+// Len returns the number of indexed cases
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.cases)
+}
+
+// Similar returns up to limit cases at least minSimilarity similar to the query, most similar first
+func (idx *Index) Similar(query Query, limit int, minSimilarity float64) []types.RelatedCase {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryTerms := make(map[string]int)
+	for _, term := range text.Tokenize(query.Text + "\n" + strings.Join(query.Symptoms, "\n")) {
+		queryTerms[term]++
+	}
+	queryVector, queryNorm := idx.vector(queryTerms)
+	if queryNorm == 0 {
+		return nil
+	}
+
+	related := make([]types.RelatedCase, 0)
+	for id, c := range idx.cases {
+		if id == query.Exclude {
+			continue
+		}
+
+		caseVector, caseNorm := idx.vector(c.terms)
+		if caseNorm == 0 {
+			continue
+		}
+		var dot float64
+		for term, weight := range queryVector {
+			dot += weight * caseVector[term]
+		}
+		similarity := dot / (queryNorm * caseNorm)
+		if similarity < minSimilarity {
+			continue
+		}
+		if query.Category != "" && c.summary.Category == query.Category {
+			similarity = math.Min(1, similarity+categoryBoost)
+		}
+
+		match := c.summary
+		match.Similarity = math.Round(similarity*1000) / 1000
+		related = append(related, match)
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Similarity != related[j].Similarity {
+			return related[i].Similarity > related[j].Similarity
+		}
+		return related[i].UpdatedAt.After(related[j].UpdatedAt)
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related
+}
+
+// remove drops a case; callers hold idx.mu
+func (idx *Index) remove(id uuid.UUID) {
+	c, ok := idx.cases[id]
+	if !ok {
+		return
+	}
+
+	for term := range c.terms {
+		idx.documentFrequency[term]--
+		if idx.documentFrequency[term] <= 0 {
+			delete(idx.documentFrequency, term)
+		}
+	}
+	delete(idx.cases, id)
+}
+
+// vector weighs term counts by log-scaled frequency and smoothed inverse document frequency
+func (idx *Index) vector(terms map[string]int) (map[string]float64, float64) {
+	vector := make(map[string]float64, len(terms))
+	var norm float64
+	for term, count := range terms {
+		idf := math.Log(float64(len(idx.cases)+1)/float64(idx.documentFrequency[term]+1)) + 1
+		weight := (1 + math.Log(float64(count))) * idf
+		vector[term] = weight
+		norm += weight * weight
+	}
+	return vector, math.Sqrt(norm)
+}
+
+// describe summarizes a session as a case and returns the text it is matched on: what the
+// user reported, the symptoms classified from it and the diagnoses given. A resolved case is
+// summarized by the prescription that fixed it and its root cause.
+func describe(session *types.Session) (types.RelatedCase, string, bool) {
+	summary := types.RelatedCase{
+		SessionID:   session.ID,
+		SessionName: session.Name,
+		UpdatedAt:   session.UpdatedAt,
+	}
+
+	var document []string
+	for _, message := range session.Messages {
+		if message.Role == types.MessageRoleUser {
+			document = append(document, message.Content)
+		}
+		if message.Intent != nil {
+			summary.Category = message.Intent.Category
+			summary.Symptoms = message.Intent.Symptoms
+			// Symptoms are the distilled problem, weigh them above the free text
+			document = append(document, strings.Join(message.Intent.Symptoms, "\n"), strings.Join(message.Intent.Symptoms, "\n"))
+		}
+		if message.Prescription != nil {
+			summary.Diagnosis = message.Prescription.Diagnosis
+			summary.Treatment = message.Prescription.Treatment
+			document = append(document, message.Prescription.Diagnosis)
+		}
+	}
+
+	if resolution := session.Resolution; resolution != nil {
+		summary.Status = resolution.Status
+		summary.RootCause = resolution.RootCause
+		if resolution.RootCause != "" {
+			document = append(document, resolution.RootCause)
+		}
+		for _, message := range session.Messages {
+			if resolution.FixedBy != nil && message.ID == *resolution.FixedBy && message.Prescription != nil {
+				summary.Diagnosis = message.Prescription.Diagnosis
+				summary.Treatment = message.Prescription.Treatment
+			}
+		}
+	}
+
+	return summary, strings.Join(document, "\n"), summary.Diagnosis != ""
+}
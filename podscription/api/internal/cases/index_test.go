@@ -0,0 +1,54 @@
+package cases
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// diagnosedSession is a session whose user reported problem was diagnosed
+func diagnosedSession(problem string, category types.IntentCategory, diagnosis string) *types.Session {
+	return &types.Session{
+		ID: uuid.New(),
+		Messages: []types.Message{
+			{Role: types.MessageRoleUser, Content: problem},
+			{
+				Role:         types.MessageRoleAssistant,
+				Intent:       &types.PodIntent{Category: category},
+				Prescription: &types.Prescription{Diagnosis: diagnosis},
+			},
+		},
+	}
+}
+
+func TestIndexSimilar(t *testing.T) {
+	idx := NewIndex()
+	oom := diagnosedSession("web pods are OOMKilled after the deploy", types.IntentCategoryPodIssues, "Memory exhaustion")
+	dns := diagnosedSession("service lookups fail with NXDOMAIN", types.IntentCategoryNetworking, "Name resolution failure")
+	undiagnosed := &types.Session{ID: uuid.New(), Messages: []types.Message{{Role: types.MessageRoleUser, Content: "pods OOMKilled"}}}
+	for _, session := range []*types.Session{oom, dns, undiagnosed} {
+		idx.Add(session)
+	}
+	if idx.Len() != 2 {
+		t.Fatalf("got %d cases, want only the diagnosed sessions", idx.Len())
+	}
+
+	related := idx.Similar(Query{Text: "my api pod got OOMKilled", Category: types.IntentCategoryPodIssues}, 3, 0.1)
+	if len(related) != 1 || related[0].SessionID != oom.ID || related[0].Diagnosis != "Memory exhaustion" {
+		t.Fatalf("got %+v, want the OOM case", related)
+	}
+
+	// A session is never its own related case
+	if related := idx.Similar(Query{Exclude: oom.ID, Text: "pods OOMKilled"}, 3, 0.1); len(related) != 0 {
+		t.Fatalf("got %+v, want the asking session excluded", related)
+	}
+
+	idx.Remove(oom.ID)
+	if related := idx.Similar(Query{Text: "pods OOMKilled"}, 3, 0.1); len(related) != 0 {
+		t.Fatalf("got %+v after removing the case, want none", related)
+	}
+	if related := idx.Similar(Query{Text: "the and of"}, 3, 0); related != nil {
+		t.Fatalf("got %+v for stop words only, want none", related)
+	}
+}
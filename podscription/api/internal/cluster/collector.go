@@ -0,0 +1,389 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// maxEvents caps the events listed per object, most recent first
+	maxEvents = 5
+	// maxDeploymentPods caps the pods summarized for a deployment
+	maxDeploymentPods = 3
+	// maxMessageLength truncates long status and event messages
+	maxMessageLength = 160
+)
+
+// Collector gathers a read-only snapshot of the objects referenced in a conversation.
+// It only issues get and list requests.
+type Collector struct {
+	client kubernetes.Interface
+}
+
+// NewCollector creates a collector using the given clientset
+func NewCollector(client kubernetes.Interface) *Collector {
+	return &Collector{client: client}
+}
+
+// NewClientset connects to the cluster with the given kubeconfig. When kubeconfig is empty
+// the in-cluster service account is used, falling back to the default kubeconfig locations.
+func NewClientset(kubeconfig string) (kubernetes.Interface, error) {
+	var restConfig *rest.Config
+	var err error
+
+	if kubeconfig == "" {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if kubeconfig != "" || err != nil {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = kubeconfig
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster configuration: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return client, nil
+}
+
+// Summarize returns a compact, prompt-ready description of every referenced object.
+// Objects that cannot be read are noted in the summary and reported in the returned error.
+func (c *Collector) Summarize(ctx context.Context, refs []Reference) (string, error) {
+	var sections []string
+	var errs []error
+
+	for _, ref := range refs {
+		var lines []string
+		var err error
+
+		switch ref.Kind {
+		case KindPod:
+			lines, err = c.describePod(ctx, ref.Namespace, ref.Name, "")
+		case KindDeployment:
+			lines, err = c.describeDeployment(ctx, ref)
+		case KindPVC:
+			lines, err = c.describePVC(ctx, ref)
+		case KindService:
+			lines, err = c.describeService(ctx, ref)
+		default:
+			continue
+		}
+
+		if apierrors.IsNotFound(err) {
+			lines, err = []string{fmt.Sprintf("%s: not found", ref)}, nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", ref, err))
+			lines = []string{fmt.Sprintf("%s: could not be read (%s)", ref, reason(err))}
+		}
+
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(sections, "\n"), errors.Join(errs...)
+}
+
+// describePod summarizes a pod's phase, conditions, containers, owners, volumes, services and events
+func (c *Collector) describePod(ctx context.Context, namespace, name, indent string) ([]string, error) {
+	pod, err := c.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("Pod %s/%s: phase %s", pod.Namespace, pod.Name, pod.Status.Phase)
+	if pod.Spec.NodeName != "" {
+		header += ", node " + pod.Spec.NodeName
+	}
+	if owners := c.owners(ctx, pod); owners != "" {
+		header += ", owned by " + owners
+	}
+	if pod.Status.Reason != "" {
+		header += fmt.Sprintf(", reason %s", pod.Status.Reason)
+	}
+	lines := []string{indent + header}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			lines = append(lines, fmt.Sprintf("%s  Condition %s=%s: %s %s", indent, condition.Type, condition.Status, condition.Reason, truncate(condition.Message)))
+		}
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		lines = append(lines, fmt.Sprintf("%s  Container %s: %s, ready %t, restarts %d%s", indent, status.Name, containerState(status.State), status.Ready, status.RestartCount, lastState(status.LastTerminationState)))
+	}
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := c.client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, volume.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s  PVC %s: %s", indent, volume.PersistentVolumeClaim.ClaimName, reason(err)))
+			continue
+		}
+		lines = append(lines, indent+"  "+pvcLine(pvc))
+	}
+
+	services, err := c.client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, service := range services.Items {
+			if len(service.Spec.Selector) > 0 && labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+				lines = append(lines, fmt.Sprintf("%s  Selected by service %s", indent, service.Name))
+			}
+		}
+	}
+
+	return append(lines, c.events(ctx, namespace, "Pod", name, indent+"  ")...), nil
+}
+
+// describeDeployment summarizes a deployment's rollout state and a few of its pods
+func (c *Collector) describeDeployment(ctx context.Context, ref Reference) ([]string, error) {
+	deployment, err := c.client.AppsV1().Deployments(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	lines := []string{fmt.Sprintf("Deployment %s/%s: %d/%d ready, %d updated, %d unavailable",
+		deployment.Namespace, deployment.Name, deployment.Status.ReadyReplicas, desired, deployment.Status.UpdatedReplicas, deployment.Status.UnavailableReplicas)}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			lines = append(lines, fmt.Sprintf("  Condition %s=%s: %s %s", condition.Type, condition.Status, condition.Reason, truncate(condition.Message)))
+		}
+	}
+	lines = append(lines, c.events(ctx, ref.Namespace, "Deployment", ref.Name, "  ")...)
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return lines, nil
+	}
+	pods, err := c.client.CoreV1().Pods(ref.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return append(lines, fmt.Sprintf("  Pods: %s", reason(err))), nil
+	}
+
+	// Unhealthy pods are the interesting ones
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return !podReady(&pods.Items[i]) && podReady(&pods.Items[j])
+	})
+	for i, pod := range pods.Items {
+		if i == maxDeploymentPods {
+			lines = append(lines, fmt.Sprintf("  ... and %d more pods", len(pods.Items)-maxDeploymentPods))
+			break
+		}
+		podLines, err := c.describePod(ctx, ref.Namespace, pod.Name, "  ")
+		if err != nil {
+			continue
+		}
+		lines = append(lines, podLines...)
+	}
+
+	return lines, nil
+}
+
+// describePVC summarizes a claim and its events
+func (c *Collector) describePVC(ctx context.Context, ref Reference) ([]string, error) {
+	pvc, err := c.client.CoreV1().PersistentVolumeClaims(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{pvcLine(pvc)}
+	return append(lines, c.events(ctx, ref.Namespace, "PersistentVolumeClaim", ref.Name, "  ")...), nil
+}
+
+// describeService summarizes a service, how many endpoints back it and its events
+func (c *Collector) describeService(ctx context.Context, ref Reference) ([]string, error) {
+	service, err := c.client.CoreV1().Services(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []string
+	for _, port := range service.Spec.Ports {
+		ports = append(ports, fmt.Sprintf("%d->%s/%s", port.Port, port.TargetPort.String(), port.Protocol))
+	}
+
+	line := fmt.Sprintf("Service %s/%s: type %s, selector %s, ports %s",
+		service.Namespace, service.Name, service.Spec.Type, labels.Set(service.Spec.Selector).String(), strings.Join(ports, ", "))
+
+	slices, err := c.client.DiscoveryV1().EndpointSlices(ref.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{discoveryv1.LabelServiceName: service.Name}.String(),
+	})
+	if err == nil {
+		ready, notReady := 0, 0
+		for _, slice := range slices.Items {
+			for _, endpoint := range slice.Endpoints {
+				if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+					ready++
+				} else {
+					notReady++
+				}
+			}
+		}
+		line += fmt.Sprintf(", endpoints %d ready, %d not ready", ready, notReady)
+	}
+
+	lines := []string{line}
+	return append(lines, c.events(ctx, ref.Namespace, "Service", ref.Name, "  ")...), nil
+}
+
+// owners returns the pod's controllers, resolving ReplicaSets to their Deployment
+func (c *Collector) owners(ctx context.Context, pod *corev1.Pod) string {
+	var owners []string
+	for _, owner := range pod.OwnerReferences {
+		entry := owner.Kind + "/" + owner.Name
+		if owner.Kind == "ReplicaSet" {
+			replicaSet, err := c.client.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err == nil {
+				for _, parent := range replicaSet.OwnerReferences {
+					entry += " (" + parent.Kind + "/" + parent.Name + ")"
+				}
+			}
+		}
+		owners = append(owners, entry)
+	}
+	return strings.Join(owners, ", ")
+}
+
+// events lists the most recent events of an object, one per line
+func (c *Collector) events(ctx context.Context, namespace, kind, name, indent string) []string {
+	list, err := c.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []string{fmt.Sprintf("%sEvents: %s", indent, reason(err))}
+	}
+
+	var events []corev1.Event
+	for _, event := range list.Items {
+		if event.InvolvedObject.Kind == kind && event.InvolvedObject.Name == name {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(events[i]).After(eventTime(events[j]))
+	})
+
+	var lines []string
+	for i, event := range events {
+		if i == maxEvents {
+			break
+		}
+		count := ""
+		if event.Count > 1 {
+			count = fmt.Sprintf(" (x%d)", event.Count)
+		}
+		lines = append(lines, fmt.Sprintf("%sEvent %s %s%s: %s", indent, event.Type, event.Reason, count, truncate(event.Message)))
+	}
+	return lines
+}
+
+// containerState describes the current state of a container
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return strings.TrimSpace(fmt.Sprintf("waiting %s %s", state.Waiting.Reason, truncate(state.Waiting.Message)))
+	case state.Terminated != nil:
+		return fmt.Sprintf("terminated %s (exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+	case state.Running != nil:
+		return "running"
+	default:
+		return "unknown state"
+	}
+}
+
+// lastState describes how the previous instance of a container ended, if it did
+func lastState(state corev1.ContainerState) string {
+	if state.Terminated == nil {
+		return ""
+	}
+	return fmt.Sprintf(", last terminated %s (exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+}
+
+// pvcLine summarizes a claim on a single line
+func pvcLine(pvc *corev1.PersistentVolumeClaim) string {
+	storageClass := "<default>"
+	if pvc.Spec.StorageClassName != nil {
+		storageClass = *pvc.Spec.StorageClassName
+	}
+
+	var modes []string
+	for _, mode := range pvc.Spec.AccessModes {
+		modes = append(modes, string(mode))
+	}
+
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	line := fmt.Sprintf("PVC %s/%s: %s, storage class %s, requested %s, access modes %s",
+		pvc.Namespace, pvc.Name, pvc.Status.Phase, storageClass, requested.String(), strings.Join(modes, ","))
+	if pvc.Spec.VolumeName != "" {
+		line += ", volume " + pvc.Spec.VolumeName
+	}
+	return line
+}
+
+// podReady reports whether a pod's Ready condition is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// eventTime returns the most recent time an event was observed
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// reason returns a short description of an API error, e.g. "forbidden"
+func reason(err error) string {
+	switch {
+	case apierrors.IsNotFound(err):
+		return "not found"
+	case apierrors.IsForbidden(err):
+		return "forbidden"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	default:
+		return truncate(err.Error())
+	}
+}
+
+// truncate shortens a message to maxMessageLength characters
+func truncate(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if len(message) <= maxMessageLength {
+		return message
+	}
+	return message[:maxMessageLength] + "..."
+}
@@ -0,0 +1,205 @@
+package cluster
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// crashingShop is a namespace whose web deployment has a pod in a crash loop with a bound claim
+func crashingShop() *fake.Clientset {
+	storageClass := "standard"
+	replicas := int32(2)
+	ready, notReady := true, false
+	now := time.Now()
+
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			Status: appsv1.DeploymentStatus{
+				ReadyReplicas:       1,
+				UpdatedReplicas:     2,
+				UnavailableReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type:    appsv1.DeploymentAvailable,
+					Status:  corev1.ConditionFalse,
+					Reason:  "MinimumReplicasUnavailable",
+					Message: "Deployment does not have minimum availability.",
+				}},
+			},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-7d9f",
+				Namespace:       "shop",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-7d9f-abcde",
+				Namespace:       "shop",
+				Labels:          map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f"}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "web-data"},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{
+					Type:   corev1.PodReady,
+					Status: corev1.ConditionFalse,
+					Reason: "ContainersNotReady",
+				}},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         "app",
+					RestartCount: 7,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 5m0s restarting failed container"},
+					},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+					},
+				}},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-data", Namespace: "shop"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+				VolumeName: "pv-123",
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: map[string]string{"app": "web"},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt32(8080), Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web-x1",
+				Namespace: "shop",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+			},
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}},
+				{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+			},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web-7d9f-abcde.1", Namespace: "shop"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web-7d9f-abcde"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			Count:          12,
+			LastTimestamp:  metav1.NewTime(now),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web-7d9f-abcde.0", Namespace: "shop"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web-7d9f-abcde"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Pulled",
+			Message:        "Container image already present on machine",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+	)
+}
+
+func TestSummarizePod(t *testing.T) {
+	collector := NewCollector(crashingShop())
+
+	summary, err := collector.Summarize(context.Background(), []Reference{{Kind: KindPod, Namespace: "shop", Name: "web-7d9f-abcde"}})
+	if err != nil {
+		t.Fatalf("failed to summarize pod: %v", err)
+	}
+
+	want := []string{
+		"Pod shop/web-7d9f-abcde: phase Running, node node-1, owned by ReplicaSet/web-7d9f (Deployment/web)",
+		"  Condition Ready=False: ContainersNotReady",
+		"  Container app: waiting CrashLoopBackOff back-off 5m0s restarting failed container, ready false, restarts 7, last terminated OOMKilled (exit code 137)",
+		"  PVC shop/web-data: Bound, storage class standard, requested 10Gi, access modes ReadWriteOnce, volume pv-123",
+		"  Selected by service web",
+		"  Event Warning BackOff (x12): Back-off restarting failed container",
+		"  Event Normal Pulled: Container image already present on machine",
+	}
+	if got := summaryLines(summary); !slices.Equal(got, want) {
+		t.Fatalf("got summary\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSummarizeDeploymentPVCAndService(t *testing.T) {
+	collector := NewCollector(crashingShop())
+
+	summary, err := collector.Summarize(context.Background(), []Reference{
+		{Kind: KindDeployment, Namespace: "shop", Name: "web"},
+		{Kind: KindPVC, Namespace: "shop", Name: "web-data"},
+		{Kind: KindService, Namespace: "shop", Name: "web"},
+	})
+	if err != nil {
+		t.Fatalf("failed to summarize objects: %v", err)
+	}
+
+	lines := summaryLines(summary)
+	for _, want := range []string{
+		"Deployment shop/web: 1/2 ready, 2 updated, 1 unavailable",
+		"  Condition Available=False: MinimumReplicasUnavailable Deployment does not have minimum availability.",
+		"  Pod shop/web-7d9f-abcde: phase Running, node node-1, owned by ReplicaSet/web-7d9f (Deployment/web)",
+		"PVC shop/web-data: Bound, storage class standard, requested 10Gi, access modes ReadWriteOnce, volume pv-123",
+		"Service shop/web: type ClusterIP, selector app=web, ports 80->8080/TCP, endpoints 1 ready, 1 not ready",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("summary is missing line %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestSummarizeMissingObject(t *testing.T) {
+	collector := NewCollector(crashingShop())
+
+	summary, err := collector.Summarize(context.Background(), []Reference{{Kind: KindPod, Namespace: "shop", Name: "ghost"}})
+	if err != nil {
+		t.Fatalf("a missing object is not an error: %v", err)
+	}
+	if summary != "pod shop/ghost: not found" {
+		t.Fatalf("got summary %q", summary)
+	}
+}
+
+// summaryLines splits a summary into lines without trailing blanks
+func summaryLines(summary string) []string {
+	lines := strings.Split(summary, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return lines
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of objects the collector knows how to summarize
+const (
+	KindPod        = "pod"
+	KindDeployment = "deployment"
+	KindPVC        = "pvc"
+	KindService    = "service"
+)
+
+// maxReferences caps how many objects are looked up for a single message
+const maxReferences = 3
+
+// Reference identifies a Kubernetes object mentioned in a conversation
+type Reference struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String returns the reference as kind namespace/name
+func (r Reference) String() string {
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+// kindAliases maps the spellings used in kubectl commands and prose onto known kinds
+var kindAliases = map[string]string{
+	"po":                     KindPod,
+	"pod":                    KindPod,
+	"pods":                   KindPod,
+	"deploy":                 KindDeployment,
+	"deployment":             KindDeployment,
+	"deployments":            KindDeployment,
+	"pvc":                    KindPVC,
+	"pvcs":                   KindPVC,
+	"persistentvolumeclaim":  KindPVC,
+	"persistentvolumeclaims": KindPVC,
+	"svc":                    KindService,
+	"service":                KindService,
+	"services":               KindService,
+}
+
+var (
+	namespacePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?:^|\s)(?:-n|--namespace)[\s=]+([a-z0-9][a-z0-9-]*)`),
+		regexp.MustCompile(`\b(?i:namespace)(?:\s*[:=]\s*["'` + "`" + `]?|\s+["'` + "`" + `])([a-z0-9][a-z0-9-]*)`),
+		regexp.MustCompile(`\b(?i:in)\s+(?:the\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9-]*)["'` + "`" + `]?\s+(?i:namespace)\b`),
+	}
+
+	// namespaceStopWords are words that precede "namespace" in prose without naming one
+	namespaceStopWords = map[string]bool{
+		"a": true, "any": true, "another": true, "each": true, "every": true, "my": true, "our": true,
+		"same": true, "other": true, "that": true, "this": true, "wrong": true, "right": true, "correct": true,
+	}
+
+	// kind/name as used by kubectl, e.g. pod/api-7d9f or deploy/api
+	slashReferencePattern = regexp.MustCompile(`\b((?i:po|pods?|deploy|deployments?|pvcs?|persistentvolumeclaims?|svc|services?))/([a-z0-9][a-z0-9.-]*[a-z0-9])`)
+	// kind "name" as printed by kubectl, or kind followed by a generated-looking name such as api-7d9f
+	proseReferencePattern = regexp.MustCompile(`\b((?i:pods?|deployments?|pvcs?|persistentvolumeclaims?|services?))\s+(?:named\s+|called\s+)?(?:["'` + "`" + `]([a-z0-9][a-z0-9.-]*[a-z0-9])["'` + "`" + `]|([a-z0-9]+(?:[.-][a-z0-9]+)+))`)
+)
+
+// ParseReferences extracts the objects a text refers to, resolving them against the namespace
+// mentioned in the text or "default". Only the first few distinct references are returned.
+func ParseReferences(text string) []Reference {
+	namespace := "default"
+	for _, pattern := range namespacePatterns {
+		if match := pattern.FindStringSubmatch(text); match != nil && !namespaceStopWords[match[1]] {
+			namespace = match[1]
+			break
+		}
+	}
+
+	var refs []Reference
+	seen := make(map[Reference]bool)
+	add := func(kind, name string) {
+		ref := Reference{Kind: kindAliases[strings.ToLower(kind)], Namespace: namespace, Name: name}
+		if ref.Kind == "" || ref.Name == "" || seen[ref] || len(refs) >= maxReferences {
+			return
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+
+	for _, match := range slashReferencePattern.FindAllStringSubmatch(text, -1) {
+		add(match[1], match[2])
+	}
+	for _, match := range proseReferencePattern.FindAllStringSubmatch(text, -1) {
+		name := match[2]
+		if name == "" {
+			name = match[3]
+		}
+		add(match[1], name)
+	}
+
+	return refs
+}
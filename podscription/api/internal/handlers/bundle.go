@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"podscription-api/controllers"
+	"podscription-api/types"
+)
+
+// BundleHandler handles HTTP requests for support bundles
+type BundleHandler struct {
+	controller *controllers.BundleController
+	logger     *logrus.Logger
+	// maxSize is the largest upload accepted, in bytes
+	maxSize int64
+}
+
+// NewBundleHandler creates a new bundle handler accepting uploads up to maxSize bytes
+func NewBundleHandler(controller *controllers.BundleController, logger *logrus.Logger, maxSize int64) *BundleHandler {
+	return &BundleHandler{
+		controller: controller,
+		logger:     logger,
+		maxSize:    maxSize,
+	}
+}
+
+// UploadBundle handles POST /api/bundles. The bundle is sent either as the "bundle"
+// field of a multipart form or as the raw request body, named by the "name" query parameter.
+func (h *BundleHandler) UploadBundle(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize)
+
+	name := c.Query("name")
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			h.invalidPayload(c, err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				h.invalidPayload(c, err)
+				return
+			}
+			if part.FormName() == "bundle" {
+				if name == "" {
+					name = part.FileName()
+				}
+				body = part
+				break
+			}
+		}
+	}
+
+	response, err := h.controller.UploadBundle(name, body)
+	if err != nil {
+		if errorResp, ok := err.(*types.ErrorResponse); ok {
+			h.logErrorResponse(errorResp, c)
+
+			switch errorResp.ErrorCode {
+			case "INVALID_BUNDLE":
+				c.JSON(http.StatusBadRequest, errorResp)
+			case "BUNDLE_TOO_LARGE":
+				c.JSON(http.StatusRequestEntityTooLarge, errorResp)
+			case "BUNDLES_DISABLED":
+				c.JSON(http.StatusNotFound, errorResp)
+			case "BUNDLES_BUSY":
+				c.Header("Retry-After", "5")
+				c.JSON(http.StatusServiceUnavailable, errorResp)
+			default:
+				c.JSON(http.StatusInternalServerError, errorResp)
+			}
+			return
+		}
+
+		h.logger.WithError(err).Error("internal error uploading support bundle")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			ErrorCode: "INTERNAL_ERROR",
+			Message:   "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetBundle handles GET /api/bundles/:id
+func (h *BundleHandler) GetBundle(c *gin.Context) {
+	bundleID, ok := parseID(c, h.logger, "id", "INVALID_BUNDLE_ID", "Invalid bundle ID format")
+	if !ok {
+		return
+	}
+
+	summary, err := h.controller.GetBundle(bundleID)
+	if err != nil {
+		if errorResp, ok := err.(*types.ErrorResponse); ok {
+			h.logErrorResponse(errorResp, c)
+			c.JSON(http.StatusNotFound, errorResp)
+			return
+		}
+
+		h.logger.WithError(err).Error("internal error getting support bundle")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			ErrorCode: "INTERNAL_ERROR",
+			Message:   "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// invalidPayload rejects uploads whose multipart form has no readable "bundle" field
+func (h *BundleHandler) invalidPayload(c *gin.Context, err error) {
+	h.logger.WithError(err).Error("invalid bundle upload payload")
+	c.JSON(http.StatusBadRequest, types.ErrorResponse{
+		ErrorCode: "INVALID_PAYLOAD",
+		Message:   `Expected the support bundle in the "bundle" form field`,
+	})
+}
+
+// logErrorResponse logs error responses with context
+func (h *BundleHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
+	h.logger.WithFields(logrus.Fields{
+		"error_code":    errorResp.ErrorCode,
+		"error_message": errorResp.Message,
+		"method":        c.Request.Method,
+		"path":          c.Request.URL.Path,
+		"remote_addr":   c.ClientIP(),
+	}).Error("returning error response")
+}
@@ -4,7 +4,6 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"podscription-api/controllers"
 	"podscription-api/types"
@@ -41,7 +40,7 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		// Check if it's our custom error type
 		if errorResp, ok := err.(*types.ErrorResponse); ok {
 			h.logErrorResponse(errorResp, c)
-			
+
 			switch errorResp.ErrorCode {
 			case "SESSION_NOT_FOUND":
 				c.JSON(http.StatusNotFound, errorResp)
@@ -65,6 +64,65 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// StreamMessage handles POST /api/chat/stream using Server-Sent Events
+func (h *ChatHandler) StreamMessage(c *gin.Context) {
+	var req types.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("invalid chat stream request payload")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid request payload",
+		})
+		return
+	}
+
+	// Headers are only committed once the first event is ready, so failures
+	// before that point can still be reported with a regular status code
+	streaming := false
+	emit := func(event string, data interface{}) {
+		if !streaming {
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+			streaming = true
+		}
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	err := h.controller.StreamMessage(c.Request.Context(), req, emit)
+	if err == nil {
+		return
+	}
+
+	errorResp, ok := err.(*types.ErrorResponse)
+	if !ok {
+		h.logger.WithError(err).Error("internal error streaming chat message")
+		errorResp = &types.ErrorResponse{
+			ErrorCode: "INTERNAL_ERROR",
+			Message:   "Internal server error",
+		}
+	} else {
+		h.logErrorResponse(errorResp, c)
+	}
+
+	if streaming {
+		emit("error", errorResp)
+		return
+	}
+
+	switch errorResp.ErrorCode {
+	case "SESSION_NOT_FOUND":
+		c.JSON(http.StatusNotFound, errorResp)
+	case "INVALID_REQUEST":
+		c.JSON(http.StatusBadRequest, errorResp)
+	default:
+		c.JSON(http.StatusInternalServerError, errorResp)
+	}
+}
+
 // CreateSession handles POST /api/sessions
 func (h *ChatHandler) CreateSession(c *gin.Context) {
 	var req types.CreateSessionRequest
@@ -98,14 +156,8 @@ func (h *ChatHandler) CreateSession(c *gin.Context) {
 
 // GetSession handles GET /api/sessions/:id
 func (h *ChatHandler) GetSession(c *gin.Context) {
-	sessionIDStr := c.Param("id")
-	sessionID, err := uuid.Parse(sessionIDStr)
-	if err != nil {
-		h.logger.WithField("session_id", sessionIDStr).Error("invalid session ID format")
-		c.JSON(http.StatusBadRequest, types.ErrorResponse{
-			ErrorCode: "INVALID_SESSION_ID",
-			Message:   "Invalid session ID format",
-		})
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
 		return
 	}
 
@@ -113,7 +165,7 @@ func (h *ChatHandler) GetSession(c *gin.Context) {
 	if err != nil {
 		if errorResp, ok := err.(*types.ErrorResponse); ok {
 			h.logErrorResponse(errorResp, c)
-			
+
 			if errorResp.ErrorCode == "SESSION_NOT_FOUND" {
 				c.JSON(http.StatusNotFound, errorResp)
 			} else {
@@ -135,11 +187,25 @@ func (h *ChatHandler) GetSession(c *gin.Context) {
 
 // ListSessions handles GET /api/sessions
 func (h *ChatHandler) ListSessions(c *gin.Context) {
-	sessions, err := h.controller.ListSessions()
+	var req types.ListSessionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("invalid list sessions query")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid query parameters",
+		})
+		return
+	}
+
+	page, err := h.controller.ListSessions(req)
 	if err != nil {
 		if errorResp, ok := err.(*types.ErrorResponse); ok {
 			h.logErrorResponse(errorResp, c)
-			c.JSON(http.StatusInternalServerError, errorResp)
+			if errorResp.ErrorCode == "INVALID_REQUEST" {
+				c.JSON(http.StatusBadRequest, errorResp)
+			} else {
+				c.JSON(http.StatusInternalServerError, errorResp)
+			}
 			return
 		}
 
@@ -151,9 +217,7 @@ func (h *ChatHandler) ListSessions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"sessions": sessions,
-	})
+	c.JSON(http.StatusOK, page)
 }
 
 // HealthCheck handles GET /health
@@ -165,6 +229,70 @@ func (h *ChatHandler) HealthCheck(c *gin.Context) {
 	})
 }
 
+// UpdateSession handles PATCH /api/sessions/:id
+func (h *ChatHandler) UpdateSession(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("invalid update session request payload")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid request payload",
+		})
+		return
+	}
+
+	session, err := h.controller.UpdateSession(sessionID, req)
+	if err != nil {
+		h.sessionError(c, err, "internal error updating session")
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteSession handles DELETE /api/sessions/:id
+func (h *ChatHandler) DeleteSession(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.controller.DeleteSession(sessionID); err != nil {
+		h.sessionError(c, err, "internal error deleting session")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// sessionError writes the response for a failed session update or delete
+func (h *ChatHandler) sessionError(c *gin.Context, err error, internalMessage string) {
+	if errorResp, ok := err.(*types.ErrorResponse); ok {
+		h.logErrorResponse(errorResp, c)
+
+		switch errorResp.ErrorCode {
+		case "INVALID_REQUEST":
+			c.JSON(http.StatusBadRequest, errorResp)
+		case "SESSION_NOT_FOUND":
+			c.JSON(http.StatusNotFound, errorResp)
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp)
+		}
+		return
+	}
+
+	h.logger.WithError(err).Error(internalMessage)
+	c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+		ErrorCode: "INTERNAL_ERROR",
+		Message:   "Internal server error",
+	})
+}
+
 // logErrorResponse logs error responses with context
 func (h *ChatHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
 	h.logger.WithFields(logrus.Fields{
@@ -174,4 +302,4 @@ func (h *ChatHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Co
 		"path":          c.Request.URL.Path,
 		"remote_addr":   c.ClientIP(),
 	}).Error("returning error response")
-}
\ No newline at end of file
+}
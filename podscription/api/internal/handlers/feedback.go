@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"podscription-api/controllers"
+	"podscription-api/types"
+)
+
+// FeedbackHandler handles HTTP requests for feedback on assistant messages
+type FeedbackHandler struct {
+	controller *controllers.FeedbackController
+	logger     *logrus.Logger
+}
+
+// NewFeedbackHandler creates a new feedback handler
+func NewFeedbackHandler(controller *controllers.FeedbackController, logger *logrus.Logger) *FeedbackHandler {
+	return &FeedbackHandler{
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+// SetFeedback handles PUT /api/sessions/:id/messages/:messageId/feedback
+func (h *FeedbackHandler) SetFeedback(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+	messageID, ok := parseID(c, h.logger, "messageId", "INVALID_MESSAGE_ID", "Invalid message ID format")
+	if !ok {
+		return
+	}
+
+	var req types.SetFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("invalid feedback request payload")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid request payload",
+		})
+		return
+	}
+
+	feedback, err := h.controller.SetFeedback(sessionID, messageID, req)
+	if err != nil {
+		h.feedbackError(c, err, "internal error recording message feedback")
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// Report handles GET /api/feedback/report
+func (h *FeedbackHandler) Report(c *gin.Context) {
+	report, err := h.controller.Report()
+	if err != nil {
+		h.feedbackError(c, err, "internal error building feedback report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// feedbackError writes the response for a failed feedback request
+func (h *FeedbackHandler) feedbackError(c *gin.Context, err error, internalMessage string) {
+	if errorResp, ok := err.(*types.ErrorResponse); ok {
+		h.logErrorResponse(errorResp, c)
+
+		switch errorResp.ErrorCode {
+		case "INVALID_REQUEST":
+			c.JSON(http.StatusBadRequest, errorResp)
+		case "SESSION_NOT_FOUND", "MESSAGE_NOT_FOUND":
+			c.JSON(http.StatusNotFound, errorResp)
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp)
+		}
+		return
+	}
+
+	h.logger.WithError(err).Error(internalMessage)
+	c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+		ErrorCode: "INTERNAL_ERROR",
+		Message:   "Internal server error",
+	})
+}
+
+// logErrorResponse logs error responses with context
+func (h *FeedbackHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
+	h.logger.WithFields(logrus.Fields{
+		"error_code":    errorResp.ErrorCode,
+		"error_message": errorResp.Message,
+		"method":        c.Request.Method,
+		"path":          c.Request.URL.Path,
+		"remote_addr":   c.ClientIP(),
+	}).Error("returning error response")
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/types"
+)
+
+// parseID reads a UUID path parameter, writing the error response when it is malformed
+func parseID(c *gin.Context, logger *logrus.Logger, param, code, message string) (uuid.UUID, bool) {
+	raw := c.Param(param)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"param": param,
+			"value": raw,
+		}).Error("invalid ID format")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: code,
+			Message:   message,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseSessionID reads the session ID of the :id path parameter like parseID
+func parseSessionID(c *gin.Context, logger *logrus.Logger) (uuid.UUID, bool) {
+	return parseID(c, logger, "id", "INVALID_SESSION_ID", "Invalid session ID format")
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"podscription-api/controllers"
+	"podscription-api/types"
+)
+
+// PlanHandler handles HTTP requests for the treatment plans of sessions
+type PlanHandler struct {
+	controller *controllers.PlanController
+	logger     *logrus.Logger
+}
+
+// NewPlanHandler creates a new plan handler
+func NewPlanHandler(controller *controllers.PlanController, logger *logrus.Logger) *PlanHandler {
+	return &PlanHandler{
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+// GetPlan handles GET /api/sessions/:id/plan
+func (h *PlanHandler) GetPlan(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+
+	plan, err := h.controller.GetPlan(sessionID)
+	if err != nil {
+		h.planError(c, err, "internal error retrieving treatment plan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// UpdatePlanStep handles PATCH /api/sessions/:id/plan/steps/:stepId
+func (h *PlanHandler) UpdatePlanStep(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+	stepID, ok := parseID(c, h.logger, "stepId", "INVALID_STEP_ID", "Invalid step ID format")
+	if !ok {
+		return
+	}
+
+	var req types.UpdatePlanStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("invalid update step request payload")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid request payload",
+		})
+		return
+	}
+
+	plan, err := h.controller.UpdatePlanStep(sessionID, stepID, req)
+	if err != nil {
+		h.planError(c, err, "internal error updating treatment step")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// planError writes the response for a failed plan request
+func (h *PlanHandler) planError(c *gin.Context, err error, internalMessage string) {
+	if errorResp, ok := err.(*types.ErrorResponse); ok {
+		h.logErrorResponse(errorResp, c)
+
+		switch errorResp.ErrorCode {
+		case "INVALID_REQUEST":
+			c.JSON(http.StatusBadRequest, errorResp)
+		case "SESSION_NOT_FOUND", "STEP_NOT_FOUND":
+			c.JSON(http.StatusNotFound, errorResp)
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp)
+		}
+		return
+	}
+
+	h.logger.WithError(err).Error(internalMessage)
+	c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+		ErrorCode: "INTERNAL_ERROR",
+		Message:   "Internal server error",
+	})
+}
+
+// logErrorResponse logs error responses with context
+func (h *PlanHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
+	h.logger.WithFields(logrus.Fields{
+		"error_code":    errorResp.ErrorCode,
+		"error_message": errorResp.Message,
+		"method":        c.Request.Method,
+		"path":          c.Request.URL.Path,
+		"remote_addr":   c.ClientIP(),
+	}).Error("returning error response")
+}
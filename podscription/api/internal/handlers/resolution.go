@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"podscription-api/controllers"
+	"podscription-api/types"
+)
+
+// ResolutionHandler handles HTTP requests for the outcomes of consultations
+type ResolutionHandler struct {
+	controller *controllers.ResolutionController
+	logger     *logrus.Logger
+}
+
+// NewResolutionHandler creates a new resolution handler
+func NewResolutionHandler(controller *controllers.ResolutionController, logger *logrus.Logger) *ResolutionHandler {
+	return &ResolutionHandler{
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+// SetResolution handles PUT /api/sessions/:id/resolution
+func (h *ResolutionHandler) SetResolution(c *gin.Context) {
+	sessionID, ok := parseSessionID(c, h.logger)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateResolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("invalid resolution request payload")
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			ErrorCode: "INVALID_PAYLOAD",
+			Message:   "Invalid request payload",
+		})
+		return
+	}
+
+	resolution, err := h.controller.SetResolution(sessionID, req)
+	if err != nil {
+		h.resolutionError(c, err, "internal error updating session resolution")
+		return
+	}
+
+	c.JSON(http.StatusOK, resolution)
+}
+
+// Stats handles GET /api/stats/resolutions
+func (h *ResolutionHandler) Stats(c *gin.Context) {
+	stats, err := h.controller.Stats()
+	if err != nil {
+		h.resolutionError(c, err, "internal error computing resolution statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// resolutionError writes the response for a failed resolution request
+func (h *ResolutionHandler) resolutionError(c *gin.Context, err error, internalMessage string) {
+	if errorResp, ok := err.(*types.ErrorResponse); ok {
+		h.logErrorResponse(errorResp, c)
+
+		switch errorResp.ErrorCode {
+		case "INVALID_REQUEST":
+			c.JSON(http.StatusBadRequest, errorResp)
+		case "SESSION_NOT_FOUND":
+			c.JSON(http.StatusNotFound, errorResp)
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp)
+		}
+		return
+	}
+
+	h.logger.WithError(err).Error(internalMessage)
+	c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+		ErrorCode: "INTERNAL_ERROR",
+		Message:   "Internal server error",
+	})
+}
+
+// logErrorResponse logs error responses with context
+func (h *ResolutionHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
+	h.logger.WithFields(logrus.Fields{
+		"error_code":    errorResp.ErrorCode,
+		"error_message": errorResp.Message,
+		"method":        c.Request.Method,
+		"path":          c.Request.URL.Path,
+		"remote_addr":   c.ClientIP(),
+	}).Error("returning error response")
+}
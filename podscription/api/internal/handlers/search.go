@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"podscription-api/controllers"
+	"podscription-api/types"
+)
+
+// SearchHandler handles HTTP requests for searching past consultations
+type SearchHandler struct {
+	controller *controllers.SearchController
+	logger     *logrus.Logger
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(controller *controllers.SearchController, logger *logrus.Logger) *SearchHandler {
+	return &SearchHandler{
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+// Search handles GET /api/search?q=...&limit=...
+func (h *SearchHandler) Search(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			h.logger.WithField("limit", raw).Error("invalid search limit")
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				ErrorCode: "INVALID_PAYLOAD",
+				Message:   "Invalid query parameters",
+			})
+			return
+		}
+	}
+
+	response, err := h.controller.Search(c.Query("q"), limit)
+	if err != nil {
+		if errorResp, ok := err.(*types.ErrorResponse); ok {
+			h.logErrorResponse(errorResp, c)
+			if errorResp.ErrorCode == "INVALID_REQUEST" {
+				c.JSON(http.StatusBadRequest, errorResp)
+			} else {
+				c.JSON(http.StatusInternalServerError, errorResp)
+			}
+			return
+		}
+
+		h.logger.WithError(err).Error("internal error searching consultations")
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			ErrorCode: "INTERNAL_ERROR",
+			Message:   "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// logErrorResponse logs error responses with context
+func (h *SearchHandler) logErrorResponse(errorResp *types.ErrorResponse, c *gin.Context) {
+	h.logger.WithFields(logrus.Fields{
+		"error_code":    errorResp.ErrorCode,
+		"error_message": errorResp.Message,
+		"method":        c.Request.Method,
+		"path":          c.Request.URL.Path,
+		"remote_addr":   c.ClientIP(),
+	}).Error("returning error response")
+}
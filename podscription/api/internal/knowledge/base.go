@@ -0,0 +1,216 @@
+// Package knowledge indexes the team's runbooks so that diagnoses can cite them
+package knowledge
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"podscription-api/internal/text"
+	"podscription-api/types"
+)
+
+// categoryBoost multiplies the score of chunks filed under the query's intent category
+const categoryBoost = 1.5
+
+// minRelativeScore drops chunks scoring below this fraction of the best match
+const minRelativeScore = 0.3
+
+// maxExcerptRunes caps the excerpt of a chunk shown to the model
+const maxExcerptRunes = 1200
+
+// Query describes the problem to find runbook excerpts for
+type Query struct {
+	Text     string
+	Category types.IntentCategory
+	Symptoms []string
+}
+
+// Base is an in-process index of runbook chunks, ranked with BM25
+type Base struct {
+	chunks []chunk
+	// documentFrequency counts the chunks containing each term
+	documentFrequency map[string]int
+	averageLength     float64
+	documents         int
+}
+
+// chunk is a section of a runbook, the unit excerpts are ranked and cited by
+type chunk struct {
+	path       string
+	title      string
+	text       string
+	categories []string
+	terms      map[string]int
+	length     int
+}
+
+// Load indexes the markdown and YAML documents under dir
+func Load(dir string) (*Base, error) {
+	base := &Base{documentFrequency: make(map[string]int)}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		var split func(name string, data []byte) ([]chunk, error)
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".md", ".markdown":
+			split = splitMarkdown
+		case ".yaml", ".yml":
+			split = splitYAML
+		default:
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		chunks, err := split(name, data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		base.documents++
+		for _, c := range chunks {
+			base.add(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index knowledge directory: %w", err)
+	}
+
+	return base, nil
+}
+
+// Documents returns the number of indexed documents
+func (b *Base) Documents() int {
+	return b.documents
+}
+
+// Chunks returns the number of indexed chunks
+func (b *Base) Chunks() int {
+	return len(b.chunks)
+}
+
+// add indexes a chunk
+func (b *Base) add(c chunk) {
+	c.terms = make(map[string]int)
+	for _, term := range text.Tokenize(c.title + "\n" + c.text) {
+		c.terms[term]++
+		c.length++
+	}
+	if c.length == 0 {
+		return
+	}
+
+	for term := range c.terms {
+		b.documentFrequency[term]++
+	}
+	b.averageLength = (b.averageLength*float64(len(b.chunks)) + float64(c.length)) / float64(len(b.chunks)+1)
+	b.chunks = append(b.chunks, c)
+}
+
+// Search returns up to limit excerpts relevant to the query, best first and numbered for citation
+func (b *Base) Search(query Query, limit int) []types.KnowledgeSource {
+	const k1, bm = 1.2, 0.75
+
+	terms := make(map[string]bool)
+	for _, term := range text.Tokenize(query.Text + "\n" + strings.Join(query.Symptoms, "\n")) {
+		terms[term] = true
+	}
+	if len(terms) == 0 || len(b.chunks) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		chunk *chunk
+		score float64
+	}
+	var matches []scored
+	for i := range b.chunks {
+		c := &b.chunks[i]
+
+		var score float64
+		for term := range terms {
+			frequency := float64(c.terms[term])
+			if frequency == 0 {
+				continue
+			}
+			n := float64(b.documentFrequency[term])
+			idf := math.Log(1 + (float64(len(b.chunks))-n+0.5)/(n+0.5))
+			score += idf * frequency * (k1 + 1) / (frequency + k1*(1-bm+bm*float64(c.length)/b.averageLength))
+		}
+		if score == 0 {
+			continue
+		}
+		if query.Category != "" && c.filedUnder(query.Category) {
+			score *= categoryBoost
+		}
+		matches = append(matches, scored{chunk: c, score: score})
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].chunk.path < matches[j].chunk.path
+	})
+
+	sources := make([]types.KnowledgeSource, 0, limit)
+	for _, match := range matches {
+		if len(sources) == limit || match.score < matches[0].score*minRelativeScore {
+			break
+		}
+		sources = append(sources, types.KnowledgeSource{
+			Ref:     len(sources) + 1,
+			Path:    match.chunk.path,
+			Title:   match.chunk.title,
+			Excerpt: excerpt(match.chunk.text),
+			Score:   math.Round(match.score*1000) / 1000,
+		})
+	}
+	return sources
+}
+
+// filedUnder reports whether a chunk declares the category or sits in a directory named after it
+func (c *chunk) filedUnder(category types.IntentCategory) bool {
+	for _, declared := range c.categories {
+		if strings.EqualFold(declared, string(category)) {
+			return true
+		}
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(c.path)), "/") {
+		if strings.EqualFold(dir, string(category)) {
+			return true
+		}
+	}
+	return false
+}
+
+// excerpt trims chunk text to maxExcerptRunes
+func excerpt(text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= maxExcerptRunes {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:maxExcerptRunes])) + "…"
+}
@@ -0,0 +1,62 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podscription-api/types"
+)
+
+// writeRunbooks writes files into a temporary knowledge directory
+func writeRunbooks(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadAndSearch(t *testing.T) {
+	dir := writeRunbooks(t, map[string]string{
+		"storage/volumes.md": "---\ntitle: Volume runbook\ncategories: [storage]\n---\n" +
+			"## FailedMount\nCheck that the PersistentVolumeClaim is bound before restarting the pod.\n\n" +
+			"## Resizing\nExpand the claim, then wait for the filesystem resize.\n",
+		"dns.yaml":  "title: CoreDNS\nsteps: Restart coredns when lookups return NXDOMAIN for cluster services.\n",
+		"notes.txt": "FailedMount is ignored, only markdown and YAML are indexed\n",
+	})
+
+	base, err := Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load runbooks: %v", err)
+	}
+	if base.Documents() != 2 || base.Chunks() != 3 {
+		t.Fatalf("got %d documents and %d chunks, want 2 and 3", base.Documents(), base.Chunks())
+	}
+
+	sources := base.Search(Query{Text: "pod stuck with FailedMount", Category: types.IntentCategoryStorage}, 3)
+	if len(sources) == 0 {
+		t.Fatal("got no excerpts, want the FailedMount section")
+	}
+	first := sources[0]
+	if first.Ref != 1 || first.Path != "storage/volumes.md" || first.Title != "Volume runbook › FailedMount" {
+		t.Fatalf("got %+v, want the FailedMount section cited first", first)
+	}
+
+	sources = base.Search(Query{Symptoms: []string{"NXDOMAIN"}}, 3)
+	if len(sources) != 1 || sources[0].Path != "dns.yaml" || sources[0].Title != "dns › CoreDNS" {
+		t.Fatalf("got %+v, want the CoreDNS document", sources)
+	}
+
+	if sources := base.Search(Query{Text: "the and of"}, 3); len(sources) != 0 {
+		t.Fatalf("got %+v for stop words only, want none", sources)
+	}
+}
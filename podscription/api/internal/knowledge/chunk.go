@@ -0,0 +1,155 @@
+package knowledge
+
+import (
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// maxChunkWords is the length past which a section is split at paragraph breaks
+const maxChunkWords = 250
+
+// frontMatter is the optional YAML header of a markdown runbook
+type frontMatter struct {
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+}
+
+// splitMarkdown chunks a markdown runbook by its headings, splitting long sections at paragraph breaks
+func splitMarkdown(name string, data []byte) ([]chunk, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var meta frontMatter
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		header, body, found := strings.Cut(rest, "\n---\n")
+		if found {
+			if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+				return nil, err
+			}
+			text = body
+		}
+	}
+
+	title := meta.Title
+	var sections []section
+	current := section{}
+	fenced := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if heading, level, ok := parseHeading(line); ok && !fenced {
+			if title == "" && level == 1 {
+				title = heading
+			}
+			sections = append(sections, current)
+			current = section{heading: heading}
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	sections = append(sections, current)
+
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+
+	var chunks []chunk
+	for _, s := range sections {
+		chunkTitle := title
+		if s.heading != "" && s.heading != title {
+			chunkTitle = title + " › " + s.heading
+		}
+		for _, part := range splitParagraphs(s.lines) {
+			chunks = append(chunks, chunk{path: name, title: chunkTitle, text: part, categories: meta.Categories})
+		}
+	}
+	return chunks, nil
+}
+
+// section is the text under a markdown heading
+type section struct {
+	heading string
+	lines   []string
+}
+
+// parseHeading returns the text and level of an ATX heading line
+func parseHeading(line string) (string, int, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return "", 0, false
+	}
+	return strings.TrimSpace(strings.TrimRight(line[level:], "# ")), level, true
+}
+
+// splitParagraphs joins lines into parts of up to maxChunkWords, breaking only between paragraphs
+// outside code blocks; a single longer paragraph stays whole
+func splitParagraphs(lines []string) []string {
+	var parts []string
+	var current []string
+	words := 0
+	fenced := false
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(current, "\n")); text != "" {
+			parts = append(parts, text)
+		}
+		current, words = nil, 0
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if strings.TrimSpace(line) == "" && !fenced && words >= maxChunkWords {
+			flush()
+			continue
+		}
+		current = append(current, line)
+		words += len(strings.Fields(line))
+	}
+	flush()
+	return parts
+}
+
+// yamlDocument is the part of a YAML runbook document used to title and file it
+type yamlDocument struct {
+	Title      string   `json:"title"`
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+// splitYAML chunks a YAML file by its documents, titling each after its title or name field
+func splitYAML(name string, data []byte) ([]chunk, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	fallback := strings.TrimSuffix(path.Base(name), path.Ext(name))
+
+	var chunks []chunk
+	for _, document := range strings.Split("\n"+text, "\n---") {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		// Documents that are not mappings are indexed by their text alone
+		var meta yamlDocument
+		_ = yaml.Unmarshal([]byte(document), &meta)
+		title := fallback
+		for _, candidate := range []string{meta.Title, meta.Name, meta.Metadata.Name} {
+			if candidate != "" {
+				title = fallback + " › " + candidate
+				break
+			}
+		}
+
+		for _, part := range splitParagraphs(strings.Split(document, "\n")) {
+			chunks = append(chunks, chunk{path: name, title: title, text: part, categories: meta.Categories})
+		}
+	}
+	return chunks, nil
+}
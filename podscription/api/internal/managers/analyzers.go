@@ -0,0 +1,178 @@
+package managers
+
+import (
+	"regexp"
+
+	"podscription-api/types"
+)
+
+// offlineAnalyzer recognises a well-known failure and prescribes a fixed treatment for it
+type offlineAnalyzer struct {
+	Name         string
+	Category     types.IntentCategory
+	Pattern      *regexp.Regexp
+	Prescription types.Prescription
+}
+
+// defaultOfflineAnalyzers are ordered from most to least specific, the first match wins
+var defaultOfflineAnalyzers = []offlineAnalyzer{
+	{
+		Name:     "oom-killed",
+		Category: types.IntentCategoryPodIssues,
+		Pattern:  regexp.MustCompile(`(?i)oom\s*-?killed|exit code:?\s*137`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Acute Memory Exhaustion (OOMKilled)",
+			Explanation: "The container outgrew its memory limit and the kernel put it down. Until the limit matches the real appetite of the workload, it will keep collapsing and restarting.",
+			Treatment:   "Confirm the OOM kill, measure real memory usage and raise the limit or reduce consumption.",
+			Steps: []types.TreatmentStep{
+				{Title: "Confirm the termination reason", Rationale: "The last state shows whether the container was OOMKilled and with which exit code.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "Last State: Terminated, Reason: OOMKilled, Exit Code: 137", Risk: types.RiskLevelLow},
+				{Title: "Measure current memory usage", Rationale: "Compare real usage with the configured limit before changing it.", Command: "kubectl top pod <pod> -n <namespace> --containers", ExpectedOutput: "Memory usage close to the container limit", Risk: types.RiskLevelLow},
+				{Title: "Review the configured limits", Rationale: "Shows the requests and limits the kernel enforces.", Command: "kubectl get pod <pod> -n <namespace> -o jsonpath='{.spec.containers[*].resources}'", ExpectedOutput: "A memory limit below the observed peak usage", Risk: types.RiskLevelLow},
+				{Title: "Raise the memory limit", Rationale: "Gives the workload headroom above its observed peak; prefer fixing leaks if usage keeps growing.", Command: "kubectl set resources deployment/<deployment> -n <namespace> --limits=memory=<new-limit>", ExpectedOutput: "deployment.apps/<deployment> resource requirements updated", Risk: types.RiskLevelMedium},
+			},
+			FollowUp: "Track memory usage over a few days, set requests close to typical usage and limits above peak usage, and check the application for leaks or unbounded caches. For JVM or Node.js workloads, align heap settings with the container limit.",
+			Closing:  "A balanced diet of memory keeps the OOM killer away.",
+		},
+	},
+	{
+		Name:     "crashloop",
+		Category: types.IntentCategoryPodIssues,
+		Pattern:  regexp.MustCompile(`(?i)crash\s*loop\s*back\s*-?off|back-off restarting failed container`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Recurrent Container Collapse (CrashLoopBackOff)",
+			Explanation: "The container starts, fails and is restarted with an ever longer back-off. The cause is almost always in the previous container's logs or exit code.",
+			Treatment:   "Read the logs of the crashed container, inspect its exit code and events, then fix the configuration or code that makes it exit.",
+			Steps: []types.TreatmentStep{
+				{Title: "Read the logs of the crashed container", Rationale: "The previous instance's logs usually contain the error that made it exit.", Command: "kubectl logs <pod> -n <namespace> --previous", ExpectedOutput: "A stack trace, configuration error or missing dependency right before exit", Risk: types.RiskLevelLow},
+				{Title: "Inspect the exit code and events", Rationale: "Exit code 1 points at the application, 137 at an OOM kill or failing liveness probe, 126/127 at a bad command.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "Last State: Terminated with an exit code and Back-off restarting failed container events", Risk: types.RiskLevelLow},
+				{Title: "Check the container command and configuration", Rationale: "Wrong commands, missing environment variables or config maps make containers exit immediately.", Command: "kubectl get pod <pod> -n <namespace> -o yaml", ExpectedOutput: "The command, args, env and mounted config the container starts with", Risk: types.RiskLevelLow},
+				{Title: "Roll out the fix", Rationale: "Restart the workload once the configuration or image has been corrected.", Command: "kubectl rollout restart deployment/<deployment> -n <namespace>", ExpectedOutput: "deployment.apps/<deployment> restarted", Risk: types.RiskLevelMedium},
+			},
+			FollowUp: "Make sure liveness probes have a realistic initialDelaySeconds, validate configuration at startup with clear error messages, and alert on restart counts.",
+			Closing:  "Stop, drop and read the logs.",
+		},
+	},
+	{
+		Name:     "image-pull-auth",
+		Category: types.IntentCategoryPodIssues,
+		Pattern:  regexp.MustCompile(`(?i)\b(image\s*pull\s*back\s*-?off|err\s*image\s*pull|failed to pull image)\b.*\b(unauthorized|authentication required|pull access denied|access denied|denied|401|403)\b|\b(unauthorized|authentication required|pull access denied)\b.*\b(image\s*pull\s*back\s*-?off|err\s*image\s*pull|failed to pull image)\b`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Registry Credential Rejection (ImagePullBackOff)",
+			Explanation: "The kubelet reached the registry but was refused access to the image. The pod has no pull secret, the secret is wrong or expired, or the image does not exist under that name.",
+			Treatment:   "Confirm the registry error, check the pull secret referenced by the pod and its service account, then recreate or attach a valid secret.",
+			Steps: []types.TreatmentStep{
+				{Title: "Read the pull error", Rationale: "The Failed event contains the exact registry response.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "Failed to pull image ... unauthorized: authentication required", Risk: types.RiskLevelLow},
+				{Title: "Check which pull secrets the pod uses", Rationale: "Pods inherit imagePullSecrets from their service account when none are set directly.", Command: "kubectl get pod <pod> -n <namespace> -o jsonpath='{.spec.imagePullSecrets}'", ExpectedOutput: "The name of a docker-registry secret", Risk: types.RiskLevelLow},
+				{Title: "Inspect the pull secret", Rationale: "Verifies the secret exists in the pod's namespace and targets the right registry.", Command: "kubectl get secret <pull-secret> -n <namespace> -o jsonpath='{.data.\\.dockerconfigjson}' | base64 -d", ExpectedOutput: "An auths entry for the image's registry host", Risk: types.RiskLevelLow},
+				{Title: "Recreate the pull secret", Rationale: "Replaces missing or expired registry credentials.", Command: "kubectl create secret docker-registry <pull-secret> -n <namespace> --docker-server=<registry> --docker-username=<user> --docker-password=<token>", ExpectedOutput: "secret/<pull-secret> created", Risk: types.RiskLevelMedium},
+				{Title: "Attach the secret to the service account", Rationale: "Every pod using the service account can then pull from the registry.", Command: "kubectl patch serviceaccount <serviceaccount> -n <namespace> -p '{\"imagePullSecrets\":[{\"name\":\"<pull-secret>\"}]}'", ExpectedOutput: "serviceaccount/<serviceaccount> patched", Risk: types.RiskLevelMedium},
+			},
+			FollowUp: "Use short-lived registry tokens with automated rotation, keep pull secrets in every namespace that needs them, and double-check image names and tags for typos.",
+			Closing:  "No ID, no entry: even containers need their papers in order.",
+		},
+	},
+	{
+		Name:     "image-pull",
+		Category: types.IntentCategoryPodIssues,
+		Pattern:  regexp.MustCompile(`(?i)image\s*pull\s*back\s*-?off|err\s*image\s*pull|failed to pull image`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Image Delivery Failure (ImagePullBackOff)",
+			Explanation: "The kubelet cannot pull the container image. Common causes are a misspelled image or tag, a private registry without credentials, or nodes that cannot reach the registry.",
+			Treatment:   "Read the pull error, verify the image reference and then fix the name, credentials or registry connectivity.",
+			Steps: []types.TreatmentStep{
+				{Title: "Read the pull error", Rationale: "The Failed event says whether the image was not found, access was denied or the registry was unreachable.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "Failed to pull image with a not found, unauthorized or timeout message", Risk: types.RiskLevelLow},
+				{Title: "Verify the image reference", Rationale: "Typos in the repository or tag are the most common cause.", Command: "kubectl get pod <pod> -n <namespace> -o jsonpath='{.spec.containers[*].image}'", ExpectedOutput: "An image and tag that exist in the registry", Risk: types.RiskLevelLow},
+				{Title: "Fix the image reference", Rationale: "Points the workload at an image that exists.", Command: "kubectl set image deployment/<deployment> -n <namespace> <container>=<image>:<tag>", ExpectedOutput: "deployment.apps/<deployment> image updated", Risk: types.RiskLevelMedium},
+			},
+			FollowUp: "Pin images by immutable tags or digests, mirror critical images close to the cluster, and configure pull secrets for private registries.",
+			Closing:  "An image that never arrives can't be prescribed.",
+		},
+	},
+	{
+		Name:     "insufficient-cpu",
+		Category: types.IntentCategoryPerformance,
+		Pattern:  regexp.MustCompile(`(?i)insufficient (cpu|memory)`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Scheduling Starvation (FailedScheduling: Insufficient resources)",
+			Explanation: "No node has enough unreserved CPU or memory to satisfy the pod's requests, so the scheduler keeps it Pending.",
+			Treatment:   "Compare the pod's requests with the allocatable capacity of the nodes, then lower the requests, free capacity or add nodes.",
+			Steps: []types.TreatmentStep{
+				{Title: "Read the scheduler message", Rationale: "Shows how many nodes were rejected and for which resource.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "0/N nodes are available: N Insufficient cpu", Risk: types.RiskLevelLow},
+				{Title: "Check the pod's requests", Rationale: "Requests, not real usage, decide whether a pod fits on a node.", Command: "kubectl get pod <pod> -n <namespace> -o jsonpath='{.spec.containers[*].resources.requests}'", ExpectedOutput: "CPU and memory requests of every container", Risk: types.RiskLevelLow},
+				{Title: "Check allocated capacity per node", Rationale: "Shows how much of each node's allocatable capacity is already requested.", Command: "kubectl describe nodes | grep -A 8 'Allocated resources'", ExpectedOutput: "CPU requests close to 100% on every node", Risk: types.RiskLevelLow},
+				{Title: "Right-size the requests", Rationale: "Requests above real usage waste capacity the scheduler could use.", Command: "kubectl set resources deployment/<deployment> -n <namespace> --requests=cpu=<cpu>,memory=<memory>", ExpectedOutput: "deployment.apps/<deployment> resource requirements updated", Risk: types.RiskLevelMedium},
+			},
+			FollowUp: "Set requests from observed usage, enable the cluster autoscaler or add nodes for sustained growth, and use ResourceQuotas to keep one namespace from starving the others.",
+			Closing:  "Even pods need a bed before they can recover.",
+		},
+	},
+	{
+		Name:     "failed-mount",
+		Category: types.IntentCategoryStorage,
+		Pattern:  regexp.MustCompile(`(?i)failedmount|failedattachvolume|mountvolume\.(setup|mountdevice) failed|unable to attach or mount volumes|multi-attach error`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Volume Attachment Blockage (FailedMount)",
+			Explanation: "The kubelet cannot attach or mount one of the pod's volumes, so its containers never start. Typical causes are an unbound claim, a missing secret or config map, or a volume still attached to another node.",
+			Treatment:   "Find which volume fails and why, then fix the claim, the referenced object or the stale attachment.",
+			Steps: []types.TreatmentStep{
+				{Title: "Read the mount error", Rationale: "The FailedMount event names the volume and the underlying error.", Command: "kubectl describe pod <pod> -n <namespace>", ExpectedOutput: "FailedMount or FailedAttachVolume events naming a volume", Risk: types.RiskLevelLow},
+				{Title: "Check the claim status", Rationale: "A claim that is not Bound cannot be mounted.", Command: "kubectl get pvc -n <namespace>", ExpectedOutput: "STATUS Bound for every claim the pod uses", Risk: types.RiskLevelLow},
+				{Title: "Check volume attachments", Rationale: "ReadWriteOnce volumes still attached to another node cause Multi-Attach errors.", Command: "kubectl get volumeattachments", ExpectedOutput: "The volume attached to the pod's node only", Risk: types.RiskLevelLow},
+				{Title: "Check referenced secrets and config maps", Rationale: "Missing secret or config map volumes also fail to mount.", Command: "kubectl get secrets,configmaps -n <namespace>", ExpectedOutput: "Every object referenced by the pod's volumes", Risk: types.RiskLevelLow},
+			},
+			FollowUp: "Use ReadWriteMany storage or a Recreate strategy for single-writer volumes, and make sure old pods terminate before replacements need the same volume.",
+			Closing:  "A volume that won't mount is a prescription that can't be filled.",
+		},
+	},
+	{
+		Name:     "dns-nxdomain",
+		Category: types.IntentCategoryNetworking,
+		Pattern:  regexp.MustCompile(`(?i)nxdomain|no such host|could not resolve host|temporary failure in name resolution`),
+		Prescription: types.Prescription{
+			Diagnosis:   "Name Resolution Failure (NXDOMAIN)",
+			Explanation: "DNS lookups from the pod return no answer. Either the name is wrong for the pod's namespace, the service does not exist, or cluster DNS is unhealthy.",
+			Treatment:   "Verify the service name, test resolution from inside the cluster and check the health of CoreDNS.",
+			Steps: []types.TreatmentStep{
+				{Title: "Verify the service exists", Rationale: "Short names only resolve inside the same namespace; other namespaces need <service>.<namespace>.", Command: "kubectl get svc -A | grep <service>", ExpectedOutput: "The service in the expected namespace", Risk: types.RiskLevelLow},
+				{Title: "Test resolution from the pod", Rationale: "Confirms whether the failure is specific to the pod or the name.", Command: "kubectl exec -n <namespace> <pod> -- nslookup <service>.<service-namespace>.svc.cluster.local", ExpectedOutput: "An answer with the service's cluster IP", Risk: types.RiskLevelLow},
+				{Title: "Check CoreDNS health", Rationale: "Failing DNS pods break resolution cluster-wide.", Command: "kubectl get pods -n kube-system -l k8s-app=kube-dns", ExpectedOutput: "All CoreDNS pods Running and Ready", Risk: types.RiskLevelLow},
+				{Title: "Read CoreDNS logs", Rationale: "Shows upstream errors, loops or plugin misconfiguration.", Command: "kubectl logs -n kube-system -l k8s-app=kube-dns", ExpectedOutput: "No errors for the failing name", Risk: types.RiskLevelLow},
+			},
+			FollowUp: "Use fully qualified service names across namespaces, keep at least two CoreDNS replicas, and check NetworkPolicies allow egress to kube-dns on port 53.",
+			Closing:  "It's always DNS, until the checkup proves otherwise.",
+		},
+	},
+}
+
+// OfflineAnalyzers produce prescriptions for well-known failures without an LLM,
+// keeping consultations useful while the provider is unavailable
+type OfflineAnalyzers struct {
+	analyzers []offlineAnalyzer
+}
+
+// NewOfflineAnalyzers creates the built-in offline analyzers
+func NewOfflineAnalyzers() *OfflineAnalyzers {
+	return &OfflineAnalyzers{analyzers: defaultOfflineAnalyzers}
+}
+
+// Analyze returns a prescription, its markdown rendering and the analyzer name for the first
+// analyzer that recognises the message. It reports false when none does.
+func (a *OfflineAnalyzers) Analyze(message string) (*types.Prescription, string, string, bool) {
+	for _, analyzer := range a.analyzers {
+		if !analyzer.Pattern.MatchString(message) {
+			continue
+		}
+
+		prescription := analyzer.Prescription
+		prescription.Steps = append([]types.TreatmentStep(nil), analyzer.Prescription.Steps...)
+		for _, step := range prescription.Steps {
+			if step.Command != "" {
+				prescription.Commands = append(prescription.Commands, step.Command)
+			}
+		}
+
+		return &prescription, renderPrescription(&prescription, analyzer.Category), analyzer.Name, true
+	}
+
+	return nil, "", "", false
+}
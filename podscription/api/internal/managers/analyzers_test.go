@@ -0,0 +1,129 @@
+package managers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOfflineAnalyzers(t *testing.T) {
+	tests := []struct {
+		analyzer string
+		// rule is the classification rule for the same failure, which must agree on the category
+		rule     string
+		message  string
+		nearMiss string
+	}{
+		{
+			analyzer: "oom-killed",
+			rule:     "oom-killed",
+			message:  "Last State: Terminated, Reason: OOMKilled, Exit Code: 137",
+			nearMiss: "the job finished with exit code 13",
+		},
+		{
+			analyzer: "crashloop",
+			rule:     "crashloop",
+			message:  "Warning BackOff: Back-off restarting failed container web",
+			nearMiss: "the container crashed once during the night",
+		},
+		{
+			analyzer: "image-pull-auth",
+			rule:     "image-pull",
+			message:  `Failed to pull image "registry.example.com/shop/web:1.4": unauthorized: authentication required`,
+			nearMiss: `Failed to pull image "registry.example.com/shop/web@sha256:4031aa": manifest unknown`,
+		},
+		{
+			analyzer: "image-pull",
+			rule:     "image-pull",
+			message:  "web-1 is stuck in ImagePullBackOff",
+			nearMiss: `Successfully pulled image "nginx:1.27" in 2.1s`,
+		},
+		{
+			analyzer: "insufficient-cpu",
+			rule:     "insufficient-resources",
+			message:  "0/3 nodes are available: 3 Insufficient cpu.",
+			nearMiss: "cpu usage is insufficiently monitored",
+		},
+		{
+			analyzer: "failed-mount",
+			rule:     "failed-mount",
+			message:  `MountVolume.SetUp failed for volume "config" : configmap "web-config" not found`,
+			nearMiss: "the volume mounted fine after the restart",
+		},
+		{
+			analyzer: "dns-nxdomain",
+			rule:     "dns",
+			message:  "dial tcp: lookup db.shop.svc.cluster.local: no such host",
+			nearMiss: "which host does the db service run on?",
+		},
+	}
+
+	if len(tests) != len(defaultOfflineAnalyzers) {
+		t.Fatalf("got %d test cases for %d analyzers, want one per analyzer", len(tests), len(defaultOfflineAnalyzers))
+	}
+
+	analyzers := NewOfflineAnalyzers()
+	for _, tt := range tests {
+		t.Run(tt.analyzer, func(t *testing.T) {
+			prescription, content, name, ok := analyzers.Analyze(tt.message)
+			if !ok || name != tt.analyzer {
+				t.Fatalf("got analyzer %q (%v) for %q, want %s", name, ok, tt.message, tt.analyzer)
+			}
+			if prescription.Diagnosis == "" || len(prescription.Commands) == 0 || len(prescription.Commands) > len(prescription.Steps) {
+				t.Fatalf("got prescription %+v, want a diagnosis and a command per step", prescription)
+			}
+			if !strings.Contains(content, prescription.Diagnosis) {
+				t.Fatalf("got content %q, want the rendered diagnosis", content)
+			}
+
+			analyzer := findOfflineAnalyzer(t, tt.analyzer)
+			if rule := findClassificationRule(t, tt.rule); rule.Category != analyzer.Category {
+				t.Fatalf("analyzer %s classifies as %s but rule %s as %s", tt.analyzer, analyzer.Category, tt.rule, rule.Category)
+			}
+
+			if _, _, name, ok := analyzers.Analyze(tt.nearMiss); ok && name == tt.analyzer {
+				t.Fatalf("analyzer %s matches the near miss %q", tt.analyzer, tt.nearMiss)
+			}
+		})
+	}
+}
+
+func TestOfflineAnalyzersImagePullAuthStaysOnOneLine(t *testing.T) {
+	message := "ErrImagePull for web-1\nlater on, the dashboard login returned 403"
+
+	_, _, name, ok := NewOfflineAnalyzers().Analyze(message)
+	if !ok || name != "image-pull" {
+		t.Fatalf("got analyzer %q (%v), want the generic image-pull analyzer", name, ok)
+	}
+}
+
+func TestOfflineAnalyzersCopyPrescriptions(t *testing.T) {
+	analyzers := NewOfflineAnalyzers()
+
+	first, _, _, _ := analyzers.Analyze("CrashLoopBackOff")
+	first.Steps[0].Title = "changed"
+	first.Commands = append(first.Commands, "kubectl delete ns prod")
+
+	second, _, _, _ := analyzers.Analyze("CrashLoopBackOff")
+	if second.Steps[0].Title == "changed" || len(second.Commands) != len(second.Steps) {
+		t.Fatalf("got %+v, want the built-in prescription untouched", second)
+	}
+}
+
+func TestOfflineAnalyzersNoMatch(t *testing.T) {
+	if _, _, name, ok := NewOfflineAnalyzers().Analyze("how do I write a helm chart?"); ok {
+		t.Fatalf("got analyzer %q, want no match", name)
+	}
+}
+
+// findOfflineAnalyzer returns the default analyzer with the given name
+func findOfflineAnalyzer(t *testing.T, name string) offlineAnalyzer {
+	t.Helper()
+
+	for _, analyzer := range defaultOfflineAnalyzers {
+		if analyzer.Name == name {
+			return analyzer
+		}
+	}
+	t.Fatalf("no offline analyzer named %s", name)
+	return offlineAnalyzer{}
+}
@@ -0,0 +1,275 @@
+package managers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"podscription-api/pkg/config"
+	"podscription-api/types"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// jsonPrefill starts the assistant turn so Claude answers with a bare JSON object
+const jsonPrefill = "{"
+
+// AnthropicManager handles Anthropic Messages API interactions
+type AnthropicManager struct {
+	promptBuilder
+	httpClient *http.Client
+	config     config.LLM
+}
+
+// NewAnthropicManager creates a new Anthropic manager
+func NewAnthropicManager(cfg config.LLM, templates *PromptTemplates) *AnthropicManager {
+	return &AnthropicManager{
+		promptBuilder: newPromptBuilder(templates),
+		// Request deadlines come from the caller's context so long streams are not cut off
+		httpClient: &http.Client{},
+		config:     cfg,
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// anthropicStreamEvent is the subset of streaming event payloads the manager consumes
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type anthropicError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Model returns the configured model
+func (m *AnthropicManager) Model() string {
+	return m.config.Model
+}
+
+// ClassifyIntent analyzes a user message to determine the Kubernetes troubleshooting category
+func (m *AnthropicManager) ClassifyIntent(ctx context.Context, message string) (*types.PodIntent, error) {
+	prompt, err := m.buildIntentClassificationPrompt(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build classification prompt: %w", err)
+	}
+
+	response, err := m.createMessage(ctx, prompt, 0.3, 200, jsonPrefill)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify intent: %w", err)
+	}
+
+	return m.parseIntentResponse(response)
+}
+
+// GenerateDiagnosis creates a medical-themed Kubernetes troubleshooting response
+func (m *AnthropicManager) GenerateDiagnosis(ctx context.Context, req DiagnosisRequest) (*types.Prescription, string, error) {
+	prompt, err := m.buildDiagnosisPrompt(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build diagnosis prompt: %w", err)
+	}
+
+	response, err := m.createMessage(ctx, prompt, m.config.Temperature, m.config.MaxTokens, jsonPrefill)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate diagnosis: %w", err)
+	}
+
+	prescription, content := m.parseDiagnosisResponse(response, req.Intent)
+
+	return prescription, content, nil
+}
+
+// StreamDiagnosis creates a diagnosis like GenerateDiagnosis, reporting text deltas as they arrive
+func (m *AnthropicManager) StreamDiagnosis(ctx context.Context, req DiagnosisRequest, onDelta DeltaFunc) (*types.Prescription, string, error) {
+	prompt, err := m.buildDiagnosisPrompt(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build diagnosis prompt: %w", err)
+	}
+
+	response, err := m.streamMessage(ctx, prompt, m.config.Temperature, m.config.MaxTokens, jsonPrefill, onDelta)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to stream diagnosis: %w", err)
+	}
+
+	prescription, content := m.parseDiagnosisResponse(response, req.Intent)
+
+	return prescription, content, nil
+}
+
+// createMessage sends a prompt to the Messages API and returns the concatenated text response.
+// A non-empty prefill starts the assistant turn and is included in the returned text.
+func (m *AnthropicManager) createMessage(ctx context.Context, prompt promptPair, temperature float32, maxTokens int, prefill string) (string, error) {
+	resp, err := m.send(ctx, anthropicRequest{
+		Model:       m.config.Model,
+		System:      prompt.System,
+		Messages:    buildAnthropicMessages(prompt, prefill),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var parsed anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var text strings.Builder
+	text.WriteString(prefill)
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	if text.Len() == len(prefill) {
+		return "", fmt.Errorf("no text content in response")
+	}
+
+	return text.String(), nil
+}
+
+// streamMessage sends a prompt to the Messages API with streaming enabled,
+// reporting each text delta and returning the full response text
+func (m *AnthropicManager) streamMessage(ctx context.Context, prompt promptPair, temperature float32, maxTokens int, prefill string, onDelta DeltaFunc) (string, error) {
+	resp, err := m.send(ctx, anthropicRequest{
+		Model:       m.config.Model,
+		System:      prompt.System,
+		Messages:    buildAnthropicMessages(prompt, prefill),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		Stream:      true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	if prefill != "" {
+		text.WriteString(prefill)
+		onDelta(prefill)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				text.WriteString(event.Delta.Text)
+				onDelta(event.Delta.Text)
+			}
+		case "error":
+			return "", fmt.Errorf("anthropic stream error: %s", event.Error.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	if text.Len() == len(prefill) {
+		return "", fmt.Errorf("no text content in response")
+	}
+
+	return text.String(), nil
+}
+
+// buildAnthropicMessages converts a prompt into Messages API turns, optionally prefilling the answer
+func buildAnthropicMessages(prompt promptPair, prefill string) []anthropicMessage {
+	messages := make([]anthropicMessage, 0, len(prompt.Turns)+3)
+	// The Messages API requires the user to speak first, bundle sessions open with the assistant
+	if len(prompt.Turns) > 0 && prompt.Turns[0].Role == types.MessageRoleAssistant {
+		messages = append(messages, anthropicMessage{Role: "user", Content: "(consultation opened)"})
+	}
+	for _, turn := range prompt.Turns {
+		messages = append(messages, anthropicMessage{Role: string(turn.Role), Content: turn.Content})
+	}
+	messages = append(messages, anthropicMessage{Role: "user", Content: prompt.User})
+	if prefill != "" {
+		messages = append(messages, anthropicMessage{Role: "assistant", Content: prefill})
+	}
+	return messages
+}
+
+// send posts a request to the Messages API and returns the successful response
+func (m *AnthropicManager) send(ctx context.Context, request anthropicRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	url := strings.TrimSuffix(m.config.BaseURL, "/") + "/v1/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", m.config.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		var apiErr anthropicError
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("anthropic API error (%d): %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic API error (%d)", resp.StatusCode)
+	}
+
+	return resp, nil
+}
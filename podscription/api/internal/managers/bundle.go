@@ -0,0 +1,170 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/bundles"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// openingFindings caps the findings listed in the opening message of a bundle session
+const openingFindings = 5
+
+// maxConcurrentIngests caps the uploads ingested at once; each one decompresses up to
+// hundreds of MiB and holds its index in memory while the upload streams in
+const maxConcurrentIngests = 2
+
+var (
+	// ErrBundlesDisabled is returned when support bundles are used without a bundle registry
+	ErrBundlesDisabled = errors.New("support bundles are not enabled")
+	// ErrBundlesBusy is returned when maxConcurrentIngests uploads are already being ingested
+	ErrBundlesBusy = errors.New("too many support bundles are being ingested")
+)
+
+// IngestBundle indexes an uploaded support bundle and opens a session grounded in it,
+// starting with a message that lists the top findings
+func (m *SessionManager) IngestBundle(name string, r io.Reader) (*types.BundleSummary, *types.Session, error) {
+	if m.bundles == nil {
+		return nil, nil, ErrBundlesDisabled
+	}
+
+	// Reject rather than queue, so that a burst of uploads cannot pin every request handler
+	select {
+	case m.ingestSlots <- struct{}{}:
+		defer func() { <-m.ingestSlots }()
+	default:
+		return nil, nil, ErrBundlesBusy
+	}
+
+	bundle, err := bundles.Ingest(name, r)
+	if err != nil {
+		m.logger.WithError(err).WithField("bundle", name).Warn("failed to ingest support bundle")
+		return nil, nil, fmt.Errorf("failed to ingest support bundle: %w", err)
+	}
+	if err := m.bundles.Save(bundle); err != nil {
+		m.logger.WithError(err).WithField("bundle_id", bundle.ID).Error("failed to save support bundle")
+		return nil, nil, fmt.Errorf("failed to save support bundle: %w", err)
+	}
+
+	session, err := m.store.CreateSession("Support bundle: " + name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if err := m.store.UpdateSession(session.ID, store.SessionUpdate{BundleID: &bundle.ID}); err != nil {
+		return nil, nil, fmt.Errorf("failed to bind session to bundle: %w", err)
+	}
+
+	opening := types.Message{
+		Role:    types.MessageRoleAssistant,
+		Content: openingMessage(bundle),
+	}
+	if err := m.store.AddMessage(session.ID, opening); err != nil {
+		return nil, nil, fmt.Errorf("failed to add opening message: %w", err)
+	}
+
+	session, err = m.store.GetSession(session.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bundle session: %w", err)
+	}
+
+	summary := bundle.Summary()
+	m.logger.WithFields(logrus.Fields{
+		"bundle_id":  bundle.ID,
+		"session_id": session.ID,
+		"resources":  summary.Resources,
+		"events":     summary.Events,
+		"logs":       summary.Logs,
+		"findings":   len(summary.Findings),
+	}).Info("ingested support bundle")
+
+	return &summary, session, nil
+}
+
+// GetBundle returns the summary of an ingested support bundle
+func (m *SessionManager) GetBundle(id uuid.UUID) (*types.BundleSummary, error) {
+	if m.bundles == nil {
+		return nil, ErrBundlesDisabled
+	}
+
+	bundle, err := m.bundles.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+
+	summary := bundle.Summary()
+	return &summary, nil
+}
+
+// bundleContext returns the excerpt of a session's bundle relevant to a message.
+// A missing bundle only costs the diagnosis its grounding.
+func (m *SessionManager) bundleContext(sessionID, bundleID uuid.UUID, content string) string {
+	if m.bundles == nil {
+		return ""
+	}
+
+	bundle, err := m.bundles.Get(bundleID)
+	if err != nil {
+		m.logger.WithError(err).WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"bundle_id":  bundleID,
+		}).Warn("failed to load support bundle")
+		return ""
+	}
+
+	return bundle.Context(content)
+}
+
+// openingMessage renders the Pod Doctor's first look at a support bundle
+func openingMessage(bundle *bundles.Bundle) string {
+	var b strings.Builder
+
+	summary := bundle.Summary()
+	fmt.Fprintf(&b, "## 🩺 Support Bundle Intake: %s\n\n", bundle.Name)
+	fmt.Fprintf(&b, "I've examined %s, %s and %s from this bundle.\n\n",
+		plural(summary.Resources, "resource"), plural(summary.Events, "event"), plural(summary.Logs, "container log"))
+
+	if len(summary.Findings) == 0 {
+		b.WriteString("Nothing in the bundle stands out as unhealthy. Tell me about the symptoms you're seeing and I'll look closer.")
+		return b.String()
+	}
+
+	b.WriteString("### Top Findings:\n")
+	for i, finding := range summary.Findings {
+		if i == openingFindings {
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s **%s**: %s\n", i+1, severityIcon(finding.Severity), finding.Object, finding.Summary)
+	}
+	if remaining := len(summary.Findings) - openingFindings; remaining > 0 {
+		fmt.Fprintf(&b, "\n...plus %s listed in the bundle summary.\n", plural(remaining, "other finding"))
+	}
+
+	b.WriteString("\n*Ask me about any of these and I'll diagnose it from the bundle's contents.*")
+	return b.String()
+}
+
+// severityIcon marks findings by severity in markdown
+func severityIcon(severity types.FindingSeverity) string {
+	switch severity {
+	case types.FindingSeverityCritical:
+		return "🔴"
+	case types.FindingSeverityWarning:
+		return "🟠"
+	default:
+		return "🔵"
+	}
+}
+
+// plural renders a count with its noun, e.g. "1 event" or "3 events"
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
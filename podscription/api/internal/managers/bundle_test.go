@@ -0,0 +1,65 @@
+package managers
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/bundles"
+	"podscription-api/internal/store"
+)
+
+func TestIngestBundleRejectsWhenBusy(t *testing.T) {
+	manager := newFakeSessionManager(t)
+	WithBundles(bundles.NewRegistry(""))(manager)
+
+	for i := 0; i < maxConcurrentIngests; i++ {
+		manager.ingestSlots <- struct{}{}
+	}
+	if _, _, err := manager.IngestBundle("busy.tar.gz", strings.NewReader("")); !errors.Is(err, ErrBundlesBusy) {
+		t.Fatalf("got %v, want ErrBundlesBusy", err)
+	}
+
+	<-manager.ingestSlots
+	if _, _, err := manager.IngestBundle("invalid.tar.gz", strings.NewReader("not a bundle")); !errors.Is(err, bundles.ErrInvalidBundle) {
+		t.Fatalf("got %v, want the upload ingested once a slot is free", err)
+	}
+	if len(manager.ingestSlots) != maxConcurrentIngests-1 {
+		t.Fatalf("got %d slots taken, want the failed upload to release its slot", len(manager.ingestSlots))
+	}
+}
+
+func TestDeleteSessionDeletesBundle(t *testing.T) {
+	dir := t.TempDir()
+	registry := bundles.NewRegistry(dir)
+	manager := newFakeSessionManager(t)
+	WithBundles(registry)(manager)
+
+	bundle := &bundles.Bundle{ID: uuid.New(), Name: "sample.tar.gz"}
+	if err := registry.Save(bundle); err != nil {
+		t.Fatalf("failed to save bundle: %v", err)
+	}
+	session, err := manager.CreateSession("Support bundle: sample.tar.gz")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := manager.store.UpdateSession(session.ID, store.SessionUpdate{BundleID: &bundle.ID}); err != nil {
+		t.Fatalf("failed to bind session to bundle: %v", err)
+	}
+
+	if err := manager.DeleteSession(session.ID); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	if _, err := registry.Get(bundle.ID); err == nil {
+		t.Fatal("got the bundle of a deleted session, want it deleted too")
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Fatalf("got %d files (%v), want the bundle index removed", len(entries), err)
+	}
+
+	if err := manager.DeleteSession(session.ID); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Fatalf("got %v deleting a deleted session, want ErrSessionNotFound", err)
+	}
+}
@@ -0,0 +1,72 @@
+package managers
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/cases"
+	"podscription-api/types"
+)
+
+// WithRelatedCases shows the diagnosis up to limit past sessions at least minSimilarity
+// similar to each new message, and returns them with the response
+func WithRelatedCases(limit int, minSimilarity float64) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.cases = cases.NewIndex()
+		m.caseLimit = limit
+		m.caseMinSimilarity = minSimilarity
+	}
+}
+
+// IndexCases indexes the stored sessions for related case retrieval
+func (m *SessionManager) IndexCases() error {
+	if m.cases == nil {
+		return nil
+	}
+
+	sessions, err := m.store.ListSessions()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		m.cases.Add(session)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"sessions": len(sessions),
+		"cases":    m.cases.Len(),
+	}).Info("indexed past cases")
+	return nil
+}
+
+// relatedCases finds the past sessions most similar to a message of another session
+func (m *SessionManager) relatedCases(sessionID uuid.UUID, evidence string, intent *types.PodIntent) []types.RelatedCase {
+	if m.cases == nil {
+		return nil
+	}
+
+	related := m.cases.Similar(cases.Query{
+		Exclude:  sessionID,
+		Text:     evidence,
+		Category: intent.Category,
+		Symptoms: intent.Symptoms,
+	}, m.caseLimit, m.caseMinSimilarity)
+
+	if len(related) > 0 {
+		m.logger.WithFields(logrus.Fields{
+			"session_id":     sessionID,
+			"related_cases":  len(related),
+			"top_session_id": related[0].SessionID,
+			"top_similarity": related[0].Similarity,
+		}).Info("found related cases")
+	}
+	return related
+}
+
+// indexCase refreshes a session's entry in the related case index
+func (m *SessionManager) indexCase(session *types.Session) {
+	if m.cases != nil {
+		m.cases.Add(session)
+	}
+}
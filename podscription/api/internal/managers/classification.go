@@ -0,0 +1,255 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"podscription-api/types"
+)
+
+const (
+	// minCategoryScore is the lowest score for a category to be kept on an intent
+	minCategoryScore = 0.2
+	// secondaryCategoryScore is the lowest score for a non-primary category to be raised in the diagnosis prompt
+	secondaryCategoryScore = 0.5
+	// unknownCategoryConfidence caps the confidence of classifications whose category was not recognised
+	unknownCategoryConfidence = 0.5
+	// defaultIntentConfidence is used when the model omits a score
+	defaultIntentConfidence = 0.7
+)
+
+// intentResponseSchema is the JSON schema every classification response must follow
+const intentResponseSchema = `{
+  "type": "object",
+  "required": ["categories", "symptoms"],
+  "properties": {
+    "categories": {
+      "type": "array",
+      "description": "Every category that applies, each scored independently",
+      "items": {
+        "type": "object",
+        "required": ["category", "score"],
+        "properties": {
+          "category": {"type": "string", "enum": ["networking", "storage", "pod-issues", "rbac", "performance", "general"]},
+          "score": {"type": "number", "minimum": 0, "maximum": 1}
+        }
+      }
+    },
+    "symptoms": {"type": "array", "items": {"type": "string"}, "description": "2-3 key symptoms detected"}
+  }
+}`
+
+// categoryAliases maps common model spellings onto the known categories
+var categoryAliases = map[string]types.IntentCategory{
+	"network":     types.IntentCategoryNetworking,
+	"dns":         types.IntentCategoryNetworking,
+	"ingress":     types.IntentCategoryNetworking,
+	"volume":      types.IntentCategoryStorage,
+	"volumes":     types.IntentCategoryStorage,
+	"pod":         types.IntentCategoryPodIssues,
+	"pods":        types.IntentCategoryPodIssues,
+	"pod-issue":   types.IntentCategoryPodIssues,
+	"permissions": types.IntentCategoryRBAC,
+	"security":    types.IntentCategoryRBAC,
+	"resources":   types.IntentCategoryPerformance,
+	"scaling":     types.IntentCategoryPerformance,
+}
+
+// intentScore is a classification score that accepts numbers, numeric strings and percentages
+type intentScore float64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *intentScore) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*s = intentScore(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid score: %s", data)
+	}
+
+	value, ok := parseScore(text)
+	if !ok {
+		return fmt.Errorf("invalid score: %q", text)
+	}
+	*s = intentScore(value)
+	return nil
+}
+
+// intentPayload mirrors intentResponseSchema
+type intentPayload struct {
+	Categories []struct {
+		Category string       `json:"category"`
+		Score    *intentScore `json:"score"`
+	} `json:"categories"`
+	Symptoms []string `json:"symptoms"`
+}
+
+// parseIntentResponse extracts intent information from the classification response.
+// Responses that are not valid JSON fall back to the legacy line-based format.
+func (m *promptBuilder) parseIntentResponse(response string) (*types.PodIntent, error) {
+	var payload intentPayload
+	if err := json.Unmarshal([]byte(extractJSONObject(response)), &payload); err != nil {
+		return m.parseLegacyIntentResponse(response), nil
+	}
+
+	scores := make(map[types.IntentCategory]float64)
+	for _, entry := range payload.Categories {
+		score := defaultIntentConfidence
+		if entry.Score != nil {
+			score = clampScore(float64(*entry.Score))
+		}
+
+		category, score := calibrateCategory(entry.Category, score)
+		if score > scores[category] {
+			scores[category] = score
+		}
+	}
+
+	intent := newScoredIntent(scores)
+	for _, symptom := range payload.Symptoms {
+		if symptom = strings.TrimSpace(symptom); symptom != "" {
+			intent.Symptoms = append(intent.Symptoms, symptom)
+		}
+	}
+
+	return intent, nil
+}
+
+// parseLegacyIntentResponse parses the "CATEGORY: / CONFIDENCE: / SYMPTOMS:" line format
+func (m *promptBuilder) parseLegacyIntentResponse(response string) *types.PodIntent {
+	category := "general"
+	confidence := defaultIntentConfidence
+	symptoms := []string{}
+
+	for _, line := range strings.Split(strings.TrimSpace(response), "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "CATEGORY:") {
+			category = strings.TrimPrefix(line, "CATEGORY:")
+		} else if strings.HasPrefix(line, "CONFIDENCE:") {
+			if value, ok := parseScore(strings.TrimPrefix(line, "CONFIDENCE:")); ok {
+				confidence = clampScore(value)
+			}
+		} else if strings.HasPrefix(line, "SYMPTOMS:") {
+			for _, symptom := range strings.Split(strings.TrimPrefix(line, "SYMPTOMS:"), ",") {
+				if symptom = strings.TrimSpace(symptom); symptom != "" {
+					symptoms = append(symptoms, symptom)
+				}
+			}
+		}
+	}
+
+	normalized, score := calibrateCategory(category, confidence)
+	intent := newScoredIntent(map[types.IntentCategory]float64{normalized: score})
+	intent.Symptoms = symptoms
+	return intent
+}
+
+// calibrateCategory maps a model-provided category onto a known one. Unknown categories
+// fall back to general with a capped score, since the model is either confused or out of scope.
+func calibrateCategory(value string, score float64) (types.IntentCategory, float64) {
+	category, ok := normalizeIntentCategory(value)
+	if !ok && score > unknownCategoryConfidence {
+		score = unknownCategoryConfidence
+	}
+	return category, score
+}
+
+// newScoredIntent builds an intent from per-category scores, ranking them from most to least likely
+func newScoredIntent(scores map[types.IntentCategory]float64) *types.PodIntent {
+	intent := &types.PodIntent{
+		Category:   types.IntentCategoryGeneral,
+		Confidence: unknownCategoryConfidence,
+		Symptoms:   []string{},
+	}
+
+	for category, score := range scores {
+		if score >= minCategoryScore {
+			intent.Categories = append(intent.Categories, types.CategoryScore{Category: category, Score: score})
+		}
+	}
+
+	sort.Slice(intent.Categories, func(i, j int) bool {
+		if intent.Categories[i].Score != intent.Categories[j].Score {
+			return intent.Categories[i].Score > intent.Categories[j].Score
+		}
+		return intent.Categories[i].Category < intent.Categories[j].Category
+	})
+
+	if len(intent.Categories) > 0 {
+		intent.Category = intent.Categories[0].Category
+		intent.Confidence = intent.Categories[0].Score
+	}
+
+	return intent
+}
+
+// normalizeIntentCategory maps a model-provided category onto a known one,
+// reporting false and falling back to general when it is not recognised
+func normalizeIntentCategory(value string) (types.IntentCategory, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	normalized = strings.Trim(normalized, "[]\"'` .")
+	normalized = strings.NewReplacer(" ", "-", "_", "-").Replace(normalized)
+
+	category := types.IntentCategory(normalized)
+	if category.Valid() {
+		return category, true
+	}
+	if alias, ok := categoryAliases[normalized]; ok {
+		return alias, true
+	}
+	return types.IntentCategoryGeneral, false
+}
+
+// parseScore parses a score such as "0.85", "1", "85%" or "0.9 (high)"
+func parseScore(text string) (float64, bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	value := strings.Trim(fields[0], "[](),")
+	percent := strings.HasSuffix(value, "%")
+	value = strings.TrimSuffix(value, "%")
+
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	if percent {
+		score /= 100
+	}
+	return score, true
+}
+
+// clampScore limits a score to [0, 1], treating values in (1, 100] as percentages
+func clampScore(score float64) float64 {
+	if score > 1 && score <= 100 {
+		score /= 100
+	}
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
+
+// secondaryCategories returns the non-primary categories that scored high enough to mention
+func secondaryCategories(intent *types.PodIntent) []types.CategoryScore {
+	var secondary []types.CategoryScore
+	for _, score := range intent.Categories {
+		if score.Category != intent.Category && score.Score >= secondaryCategoryScore {
+			secondary = append(secondary, score)
+		}
+	}
+	return secondary
+}
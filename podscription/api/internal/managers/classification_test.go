@@ -0,0 +1,186 @@
+package managers
+
+import (
+	"math"
+	"testing"
+
+	"podscription-api/types"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+		ok   bool
+	}{
+		{"0.85", 0.85, true},
+		{"1", 1, true},
+		{"85%", 0.85, true},
+		{"85", 85, true},
+		{" 0.9 (high)", 0.9, true},
+		{"[0.4]", 0.4, true},
+		{"-0.2", -0.2, true},
+		{"high", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := parseScore(tt.text)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("got %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClampScore(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  float64
+	}{
+		{0.85, 0.85},
+		{1, 1},
+		{0, 0},
+		{85, 0.85},
+		{100, 1},
+		{150, 1},
+		{1.5, 0.015},
+		{-0.3, 0},
+		{-40, 0},
+	}
+
+	for _, tt := range tests {
+		if got := clampScore(tt.score); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("clampScore(%v) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestCalibrateCategory(t *testing.T) {
+	tests := []struct {
+		value     string
+		score     float64
+		category  types.IntentCategory
+		wantScore float64
+	}{
+		{"networking", 0.9, types.IntentCategoryNetworking, 0.9},
+		{" Pod Issues ", 0.8, types.IntentCategoryPodIssues, 0.8},
+		{"DNS", 0.7, types.IntentCategoryNetworking, 0.7},
+		{"`storage`", 0.6, types.IntentCategoryStorage, 0.6},
+		{"databases", 0.95, types.IntentCategoryGeneral, unknownCategoryConfidence},
+		{"", 0.3, types.IntentCategoryGeneral, 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			category, score := calibrateCategory(tt.value, tt.score)
+			if category != tt.category || score != tt.wantScore {
+				t.Fatalf("got %s %v, want %s %v", category, score, tt.category, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestParseIntentResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		category   types.IntentCategory
+		confidence float64
+		categories []types.IntentCategory
+		symptoms   int
+	}{
+		{
+			name:       "single category",
+			response:   `{"categories": [{"category": "storage", "score": 0.85}], "symptoms": ["PVC pending"]}`,
+			category:   types.IntentCategoryStorage,
+			confidence: 0.85,
+			categories: []types.IntentCategory{types.IntentCategoryStorage},
+			symptoms:   1,
+		},
+		{
+			name:       "multi-label ranked by score",
+			response:   `{"categories": [{"category": "rbac", "score": 0.6}, {"category": "networking", "score": "90%"}, {"category": "storage", "score": 0.1}], "symptoms": ["403", " ", "timeout"]}`,
+			category:   types.IntentCategoryNetworking,
+			confidence: 0.9,
+			categories: []types.IntentCategory{types.IntentCategoryNetworking, types.IntentCategoryRBAC},
+			symptoms:   2,
+		},
+		{
+			name:       "percentages and out of range scores",
+			response:   `{"categories": [{"category": "performance", "score": 85}, {"category": "pod-issues", "score": -1}], "symptoms": []}`,
+			category:   types.IntentCategoryPerformance,
+			confidence: 0.85,
+			categories: []types.IntentCategory{types.IntentCategoryPerformance},
+		},
+		{
+			name:       "score above one",
+			response:   `{"categories": [{"category": "storage", "score": 250}]}`,
+			category:   types.IntentCategoryStorage,
+			confidence: 1,
+			categories: []types.IntentCategory{types.IntentCategoryStorage},
+		},
+		{
+			name:       "missing score uses the default",
+			response:   `{"categories": [{"category": "pods"}]}`,
+			category:   types.IntentCategoryPodIssues,
+			confidence: defaultIntentConfidence,
+			categories: []types.IntentCategory{types.IntentCategoryPodIssues},
+		},
+		{
+			name:       "unknown category is capped as general",
+			response:   `{"categories": [{"category": "databases", "score": 0.99}]}`,
+			category:   types.IntentCategoryGeneral,
+			confidence: unknownCategoryConfidence,
+			categories: []types.IntentCategory{types.IntentCategoryGeneral},
+		},
+		{
+			name:       "missing category",
+			response:   `{"symptoms": ["something odd"]}`,
+			category:   types.IntentCategoryGeneral,
+			confidence: unknownCategoryConfidence,
+			symptoms:   1,
+		},
+		{
+			name:       "legacy format",
+			response:   "CATEGORY: networking\nCONFIDENCE: 85%\nSYMPTOMS: timeouts, NXDOMAIN",
+			category:   types.IntentCategoryNetworking,
+			confidence: 0.85,
+			categories: []types.IntentCategory{types.IntentCategoryNetworking},
+			symptoms:   2,
+		},
+		{
+			name:       "legacy format with an unknown category",
+			response:   "CATEGORY: billing\nCONFIDENCE: 0.9",
+			category:   types.IntentCategoryGeneral,
+			confidence: unknownCategoryConfidence,
+			categories: []types.IntentCategory{types.IntentCategoryGeneral},
+		},
+	}
+
+	builder := newTestPromptBuilder(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent, err := builder.parseIntentResponse(tt.response)
+			if err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			if intent.Category != tt.category || math.Abs(intent.Confidence-tt.confidence) > 1e-9 {
+				t.Fatalf("got %s %v, want %s %v", intent.Category, intent.Confidence, tt.category, tt.confidence)
+			}
+			if len(intent.Categories) != len(tt.categories) {
+				t.Fatalf("got categories %+v, want %v", intent.Categories, tt.categories)
+			}
+			for i, category := range tt.categories {
+				if intent.Categories[i].Category != category {
+					t.Fatalf("got categories %+v, want %v", intent.Categories, tt.categories)
+				}
+			}
+			if len(intent.Symptoms) != tt.symptoms {
+				t.Fatalf("got symptoms %q, want %d", intent.Symptoms, tt.symptoms)
+			}
+		})
+	}
+}
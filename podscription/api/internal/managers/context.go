@@ -0,0 +1,291 @@
+package managers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"podscription-api/types"
+)
+
+const (
+	// promptReserveTokens is kept free of history for the system prompt, the new message and its evidence
+	promptReserveTokens = 4000
+	// minHistoryTokens is the smallest history budget, whatever the window
+	minHistoryTokens = 512
+	// defaultContextWindow is assumed for models missing from contextWindows
+	defaultContextWindow = 8192
+	// maxSummaryItems caps each list of the rolling summary before it is trimmed to its token budget
+	maxSummaryItems = 50
+	// maxFindingRunes caps the text of a single summary finding
+	maxFindingRunes = 200
+)
+
+// contextWindows are the context windows, in tokens, of well-known models by name prefix;
+// the longest matching prefix wins
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"o1":            200000,
+	"o3":            200000,
+	"o4":            200000,
+	"claude":        200000,
+	"llama3":        8192,
+	"llama3.1":      131072,
+	"llama3.2":      131072,
+	"mistral":       32768,
+	"qwen2.5":       32768,
+}
+
+// ContextBuilder fits a conversation into the model's context window: the most recent turns
+// are kept verbatim and older ones are folded into the session's rolling summary
+type ContextBuilder struct {
+	// historyTokens is the budget of the summary and the recent turns together
+	historyTokens int
+	// summaryTokens is the part of historyTokens set aside for the summary
+	summaryTokens int
+}
+
+// conversationContext is the part of a conversation shown to the model with a new message
+type conversationContext struct {
+	Summary *types.ConversationSummary
+	Recent  []types.Message
+}
+
+// NewContextBuilder creates a context builder for a model. A zero window is looked up by model
+// name; the history gets what the window leaves after the response and the prompt, at most maxHistoryTokens.
+func NewContextBuilder(model string, window, responseTokens, maxHistoryTokens int) *ContextBuilder {
+	if window <= 0 {
+		window = modelContextWindow(model)
+	}
+
+	budget := window - responseTokens - promptReserveTokens
+	if maxHistoryTokens > 0 && budget > maxHistoryTokens {
+		budget = maxHistoryTokens
+	}
+	if budget < minHistoryTokens {
+		budget = minHistoryTokens
+	}
+
+	return &ContextBuilder{
+		historyTokens: budget,
+		summaryTokens: budget / 4,
+	}
+}
+
+// HistoryTokens returns the token budget of a conversation's history
+func (b *ContextBuilder) HistoryTokens() int {
+	return b.historyTokens
+}
+
+// build selects the recent turns of a session that fit the budget and folds the turns before
+// them into its summary, reporting whether the summary changed and needs saving
+func (b *ContextBuilder) build(session *types.Session) (conversationContext, bool) {
+	messages := session.Messages
+
+	through := 0
+	if session.Summary != nil {
+		through = min(session.Summary.Through, len(messages))
+	}
+
+	// Keep as many of the newest turns as fit, and always the last one
+	start, used := len(messages), 0
+	for start > through {
+		cost := estimateTokens(turnContent(messages[start-1]))
+		if used+cost > b.historyTokens-b.summaryTokens && start < len(messages) {
+			break
+		}
+		used += cost
+		start--
+	}
+
+	context := conversationContext{Summary: session.Summary, Recent: messages[start:]}
+	if start == through {
+		return context, false
+	}
+
+	summary := &types.ConversationSummary{}
+	if session.Summary != nil {
+		summary.Findings = slices.Clone(session.Summary.Findings)
+		summary.Commands = slices.Clone(session.Summary.Commands)
+		summary.Results = slices.Clone(session.Summary.Results)
+	}
+	for _, message := range messages[through:start] {
+		foldTurn(summary, message)
+	}
+	summary.Through = start
+	summary.UpdatedAt = time.Now()
+	b.trim(summary)
+
+	context.Summary = summary
+	return context, true
+}
+
+// foldTurn adds what a turn established to a summary: the problem reported and the facts of the
+// output attached by the user, or the diagnosis and commands prescribed by the assistant
+func foldTurn(summary *types.ConversationSummary, message types.Message) {
+	if message.Role == types.MessageRoleUser {
+		if reported := firstSentence(message.Content); reported != "" {
+			summary.Findings = append(summary.Findings, "Reported: "+reported)
+		}
+		for _, attachment := range message.Attachments {
+			source := string(attachment.Kind)
+			if attachment.Name != "" {
+				source += fmt.Sprintf(" %q", attachment.Name)
+			}
+			for _, fact := range attachment.Facts {
+				summary.Results = append(summary.Results, source+": "+fact)
+			}
+		}
+		return
+	}
+
+	if message.Prescription == nil {
+		return
+	}
+	summary.Findings = append(summary.Findings, "Diagnosed: "+message.Prescription.Diagnosis)
+	for _, command := range message.Prescription.Commands {
+		if !slices.Contains(summary.Commands, command) {
+			summary.Commands = append(summary.Commands, command)
+		}
+	}
+}
+
+// firstSentence returns the first sentence or line of text, capped at maxFindingRunes
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	if runes := []rune(text); len(runes) > maxFindingRunes {
+		text = string(runes[:maxFindingRunes]) + "…"
+	}
+	return text
+}
+
+// trim drops the oldest entries of the summary's longest lists until it fits its budget
+func (b *ContextBuilder) trim(summary *types.ConversationSummary) {
+	lists := []*[]string{&summary.Findings, &summary.Commands, &summary.Results}
+	for _, list := range lists {
+		if len(*list) > maxSummaryItems {
+			*list = (*list)[len(*list)-maxSummaryItems:]
+		}
+	}
+
+	for estimateTokens(formatSummary(summary)) > b.summaryTokens {
+		longest := lists[0]
+		for _, list := range lists[1:] {
+			if len(*list) > len(*longest) {
+				longest = list
+			}
+		}
+		if len(*longest) == 0 {
+			return
+		}
+		*longest = (*longest)[1:]
+	}
+}
+
+// modelContextWindow returns the context window of a model, by its longest known name prefix
+func modelContextWindow(model string) int {
+	model = strings.ToLower(model)
+	window, matched := defaultContextWindow, 0
+	for prefix, size := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matched {
+			window, matched = size, len(prefix)
+		}
+	}
+	return window
+}
+
+// estimateTokens approximates the tokens of text at four characters each, the usual average
+// for English prose; no tokenizer is bundled for the supported providers
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// historyTurns renders earlier messages as chat turns, joining consecutive messages of the same role
+func historyTurns(history []types.Message) []chatTurn {
+	turns := make([]chatTurn, 0, len(history))
+	for _, message := range history {
+		content := turnContent(message)
+		if last := len(turns) - 1; last >= 0 && turns[last].Role == message.Role {
+			turns[last].Content += "\n\n" + content
+			continue
+		}
+		turns = append(turns, chatTurn{Role: message.Role, Content: content})
+	}
+	return turns
+}
+
+// turnContent renders an earlier message as its chat turn. Attachments are replaced by their
+// extracted facts, and prescriptions by their diagnosis and numbered steps, which follow-ups refer to.
+func turnContent(message types.Message) string {
+	var b strings.Builder
+
+	if message.Role == types.MessageRoleAssistant && message.Prescription != nil {
+		p := message.Prescription
+		fmt.Fprintf(&b, "Diagnosis: %s", p.Diagnosis)
+		if p.Treatment != "" {
+			fmt.Fprintf(&b, "\nTreatment: %s", p.Treatment)
+		}
+		for i, step := range p.Steps {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, step.Title)
+			if step.Command != "" {
+				fmt.Fprintf(&b, ": `%s`", step.Command)
+			}
+		}
+		return b.String()
+	}
+
+	b.WriteString(message.Content)
+	for _, attachment := range message.Attachments {
+		fmt.Fprintf(&b, "\n\n[Attached %s", attachment.Kind)
+		if attachment.Name != "" {
+			fmt.Fprintf(&b, " %q", attachment.Name)
+		}
+		if len(attachment.Facts) > 0 {
+			fmt.Fprintf(&b, ": %s]", strings.Join(attachment.Facts, "; "))
+		} else {
+			b.WriteString(", no recognisable facts]")
+		}
+	}
+	return b.String()
+}
+
+// formatSummary renders a rolling summary as it is shown to the model
+func formatSummary(summary *types.ConversationSummary) string {
+	if summary == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"Key findings", summary.Findings},
+		{"Commands already prescribed", summary.Commands},
+		{"Results reported", summary.Results},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s:\n", section.title)
+		for _, item := range section.items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,230 @@
+package managers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"podscription-api/types"
+)
+
+// longConversation returns turns of about 100 tokens each: questions with attached logs, and
+// diagnoses that prescribe the same command every time. Answers repeat their treatment as
+// their content, so that a turn costs the same however it is shown to the model.
+func longConversation(turns int) []types.Message {
+	padding := strings.Repeat("x", 320)
+	messages := make([]types.Message, 0, turns)
+	for i := 0; i < turns; i++ {
+		if i%2 == 0 {
+			messages = append(messages, types.Message{
+				Role:    types.MessageRoleUser,
+				Content: fmt.Sprintf("Question %d about the web pod. %s", i, padding),
+				Attachments: []types.Attachment{{
+					Kind:  types.AttachmentKindLogs,
+					Name:  fmt.Sprintf("web-%d", i),
+					Facts: []string{fmt.Sprintf("exit code %d", i)},
+				}},
+			})
+			continue
+		}
+		messages = append(messages, types.Message{
+			Role:    types.MessageRoleAssistant,
+			Content: fmt.Sprintf("Answer %d. %s", i, padding),
+			Prescription: &types.Prescription{
+				Diagnosis: fmt.Sprintf("Diagnosis %d", i),
+				Treatment: fmt.Sprintf("Answer %d. %s", i, padding),
+				Commands:  []string{"kubectl logs web", fmt.Sprintf("kubectl describe pod web-%d", i)},
+			},
+		})
+	}
+	return messages
+}
+
+func TestNewContextBuilderBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		window     int
+		response   int
+		maxHistory int
+		want       int
+	}{
+		{"capped by HISTORY_MAX_TOKENS", "gpt-4o", 0, 1000, 8000, 8000},
+		{"no cap", "gpt-4o", 0, 1000, 0, 128000 - 1000 - promptReserveTokens},
+		{"longest prefix wins", "gpt-4-turbo-preview", 0, 0, 0, 128000 - promptReserveTokens},
+		{"unknown model", "my-local-model", 0, 1000, 8000, defaultContextWindow - 1000 - promptReserveTokens},
+		{"explicit window", "gpt-4o", 16000, 2000, 0, 16000 - 2000 - promptReserveTokens},
+		{"floor", "gpt-4", 0, 6000, 8000, minHistoryTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewContextBuilder(tt.model, tt.window, tt.response, tt.maxHistory)
+			if got := builder.HistoryTokens(); got != tt.want {
+				t.Fatalf("got a budget of %d tokens, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextBuilderKeepsNewestTurns(t *testing.T) {
+	// 800 tokens of history, 200 of them for the summary, leave room for six turns
+	builder := NewContextBuilder("", promptReserveTokens+800, 0, 0)
+	messages := longConversation(20)
+	session := &types.Session{Messages: messages}
+
+	context, changed := builder.build(session)
+	if !changed {
+		t.Fatal("got an unchanged summary, want the older turns folded into it")
+	}
+	if len(context.Recent) != 6 || context.Recent[0].Content != messages[14].Content {
+		t.Fatalf("got %d recent turns, want the newest 6", len(context.Recent))
+	}
+	var used int
+	for _, message := range context.Recent {
+		used += estimateTokens(turnContent(message))
+	}
+	if used > builder.historyTokens-builder.summaryTokens {
+		t.Fatalf("recent turns take %d tokens, over the budget of %d", used, builder.historyTokens-builder.summaryTokens)
+	}
+
+	// A short conversation fits entirely and needs no summary
+	short := &types.Session{Messages: messages[:4]}
+	if context, changed := builder.build(short); changed || context.Summary != nil || len(context.Recent) != 4 {
+		t.Fatalf("got %d recent turns and summary %+v, want the whole conversation", len(context.Recent), context.Summary)
+	}
+
+	// The last turn is kept even when it alone is over the budget
+	huge := &types.Session{Messages: []types.Message{{Role: types.MessageRoleUser, Content: strings.Repeat("y", 10000)}}}
+	if context, _ := builder.build(huge); len(context.Recent) != 1 {
+		t.Fatalf("got %d recent turns, want the last turn kept", len(context.Recent))
+	}
+}
+
+func TestContextBuilderFoldsOlderTurnsIntoSummary(t *testing.T) {
+	// A generous summary budget, so that nothing is trimmed
+	builder := &ContextBuilder{historyTokens: 2600, summaryTokens: 2000}
+	messages := longConversation(20)
+	session := &types.Session{Messages: messages}
+
+	context, changed := builder.build(session)
+	if !changed || context.Summary == nil {
+		t.Fatal("got no summary, want the older turns folded into it")
+	}
+	summary := context.Summary
+	if summary.Through != 14 {
+		t.Fatalf("got a summary through %d turns, want 14", summary.Through)
+	}
+	if len(summary.Findings) != 14 || summary.Findings[0] != "Reported: Question 0 about the web pod." ||
+		summary.Findings[1] != "Diagnosed: Diagnosis 1" {
+		t.Fatalf("got findings %q, want each folded turn's problem or diagnosis", summary.Findings)
+	}
+	if len(summary.Commands) != 8 || summary.Commands[0] != "kubectl logs web" {
+		t.Fatalf("got commands %q, want each command once", summary.Commands)
+	}
+	if len(summary.Results) != 7 || summary.Results[0] != `logs "web-0": exit code 0` {
+		t.Fatalf("got results %q, want the facts of the attachments", summary.Results)
+	}
+
+	// A saved summary is reused until more turns fall out of the budget
+	session.Summary = summary
+	if context, changed := builder.build(session); changed || context.Summary != summary || len(context.Recent) != 6 {
+		t.Fatalf("got changed=%v with %d recent turns, want the saved summary reused", changed, len(context.Recent))
+	}
+
+	session.Messages = longConversation(22)
+	context, changed = builder.build(session)
+	if !changed || context.Summary.Through != 16 {
+		t.Fatalf("got changed=%v through %d, want the summary extended to 16 turns", changed, context.Summary.Through)
+	}
+	if len(context.Summary.Findings) != 16 || !slices.Equal(context.Summary.Findings[:14], summary.Findings) {
+		t.Fatalf("got findings %q, want the earlier findings kept", context.Summary.Findings)
+	}
+	if len(summary.Findings) != 14 {
+		t.Fatalf("the saved summary was changed to %d findings", len(summary.Findings))
+	}
+}
+
+func TestContextBuilderTrimsSummaryToBudget(t *testing.T) {
+	builder := NewContextBuilder("", promptReserveTokens+800, 0, 0)
+	context, _ := builder.build(&types.Session{Messages: longConversation(60)})
+
+	summary := context.Summary
+	if tokens := estimateTokens(formatSummary(summary)); tokens > builder.summaryTokens {
+		t.Fatalf("summary takes %d tokens, over its budget of %d", tokens, builder.summaryTokens)
+	}
+	if summary.Through != 54 {
+		t.Fatalf("got a summary through %d turns, want 54", summary.Through)
+	}
+	// The oldest entries are dropped first
+	if len(summary.Findings) == 0 || summary.Findings[len(summary.Findings)-1] != "Diagnosed: Diagnosis 53" {
+		t.Fatalf("got findings %q, want the newest kept", summary.Findings)
+	}
+	if slices.Contains(summary.Findings, "Reported: Question 0 about the web pod.") {
+		t.Fatalf("got findings %q, want the oldest dropped", summary.Findings)
+	}
+}
+
+func TestBuildDiagnosisPromptAlternatesRoles(t *testing.T) {
+	builder := newTestPromptBuilder(t)
+	user := func(content string) types.Message {
+		return types.Message{Role: types.MessageRoleUser, Content: content}
+	}
+	assistant := func(diagnosis string) types.Message {
+		return types.Message{Role: types.MessageRoleAssistant, Prescription: &types.Prescription{Diagnosis: diagnosis}}
+	}
+
+	tests := []struct {
+		name    string
+		history []types.Message
+		// asked is an earlier message that has to be asked again with the new one
+		asked string
+	}{
+		{"no history", nil, ""},
+		{"answered", []types.Message{user("web-1 restarts"), assistant("OOMKilled")}, ""},
+		{"failed diagnosis", []types.Message{user("web-1 restarts"), assistant("OOMKilled"), user("still restarting")}, "still restarting"},
+		{"repeated question", []types.Message{user("web-1 restarts"), user("any idea?"), assistant("OOMKilled")}, ""},
+		{"bundle session", []types.Message{assistant("3 findings"), user("why is web pending?"), assistant("Unschedulable")}, ""},
+		{"bundle session unanswered", []types.Message{assistant("3 findings"), user("why is web pending?")}, "why is web pending?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := builder.buildDiagnosisPrompt(DiagnosisRequest{
+				Message: "what next?",
+				Intent:  &types.PodIntent{Category: types.IntentCategoryPodIssues},
+				History: tt.history,
+			})
+			if err != nil {
+				t.Fatalf("failed to build prompt: %v", err)
+			}
+			if tt.asked != "" && !strings.Contains(prompt.User, tt.asked) {
+				t.Fatalf("got user prompt %q, want the unanswered %q asked again", prompt.User, tt.asked)
+			}
+			if !strings.Contains(prompt.User, "what next?") {
+				t.Fatalf("got user prompt %q, want the new message", prompt.User)
+			}
+
+			openAIRoles := make([]string, 0)
+			for _, message := range buildOpenAIMessages(prompt)[1:] {
+				openAIRoles = append(openAIRoles, message.Role)
+			}
+			anthropicRoles := make([]string, 0)
+			for _, message := range buildAnthropicMessages(prompt, "{") {
+				anthropicRoles = append(anthropicRoles, message.Role)
+			}
+			for api, roles := range map[string][]string{"openai": openAIRoles, "anthropic": anthropicRoles} {
+				for i := 1; i < len(roles); i++ {
+					if roles[i] == roles[i-1] {
+						t.Fatalf("got %s roles %v, want them to alternate", api, roles)
+					}
+				}
+			}
+			if anthropicRoles[0] != "user" {
+				t.Fatalf("got anthropic roles %v, want the user to speak first", anthropicRoles)
+			}
+			if openAIRoles[len(openAIRoles)-1] != "user" {
+				t.Fatalf("got openai roles %v, want the new message last", openAIRoles)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package managers
+
+import (
+	"podscription-api/types"
+)
+
+// promptBuilder builds provider-agnostic prompts and parses model responses
+type promptBuilder struct {
+	templates          *PromptTemplates
+	specializedPrompts *SpecializedPrompts
+}
+
+// newPromptBuilder creates a prompt builder backed by the given templates
+func newPromptBuilder(templates *PromptTemplates) promptBuilder {
+	return promptBuilder{
+		templates:          templates,
+		specializedPrompts: &SpecializedPrompts{templates: templates},
+	}
+}
+
+type promptPair struct {
+	System string
+	// Turns are the earlier turns of the conversation, sent between System and User
+	Turns []chatTurn
+	User  string
+}
+
+// chatTurn is an earlier message of the conversation, sent to the model as its own turn
+type chatTurn struct {
+	Role    types.MessageRole
+	Content string
+}
+
+// PromptVersion returns the version of the prompt templates in use
+func (m *promptBuilder) PromptVersion() string {
+	return m.templates.Version()
+}
+
+// buildIntentClassificationPrompt creates prompts for intent classification
+func (m *promptBuilder) buildIntentClassificationPrompt(message string) (promptPair, error) {
+	return m.templates.render(templateClassification, promptData{
+		Message: message,
+		Schema:  intentResponseSchema,
+	})
+}
+
+// buildDiagnosisPrompt creates prompts for medical-themed diagnosis
+func (m *promptBuilder) buildDiagnosisPrompt(req DiagnosisRequest) (promptPair, error) {
+	intent := req.Intent
+
+	// Every doctor answers with the same structured prescription and
+	// raises secondary concerns from multi-label classifications
+	data := promptData{
+		Message:   req.Message,
+		History:   formatSummary(req.Summary),
+		FollowUp:  len(req.History) > 0 || req.Summary != nil,
+		Category:  intent.Category,
+		Secondary: secondaryCategories(intent),
+		Schema:    diagnosisResponseSchema,
+		Evidence:  req.Attachments,
+		Cluster:   req.ClusterContext,
+		Bundle:    req.BundleContext,
+		Cases:     req.RelatedCases,
+		Knowledge: req.Knowledge,
+		Steps:     req.StepReports,
+	}
+
+	// Route each category to its specialist doctor
+	var prompt promptPair
+	var err error
+	switch intent.Category {
+	case types.IntentCategoryNetworking:
+		prompt, err = m.specializedPrompts.GetNetworkingPrompt(data)
+	case types.IntentCategoryStorage:
+		prompt, err = m.specializedPrompts.GetStoragePrompt(data)
+	case types.IntentCategoryPodIssues:
+		prompt, err = m.specializedPrompts.GetPodIssuesPrompt(data)
+	case types.IntentCategoryRBAC:
+		prompt, err = m.specializedPrompts.GetRBACPrompt(data)
+	case types.IntentCategoryPerformance:
+		prompt, err = m.specializedPrompts.GetPerformancePrompt(data)
+	default:
+		// Fall back to generic Pod Doctor prompt for general questions
+		prompt, err = m.buildGenericDiagnosisPrompt(data)
+	}
+	if err != nil {
+		return promptPair{}, err
+	}
+
+	// Earlier messages go before the new one as the turns they were. A user message left
+	// unanswered by a failed diagnosis is asked again with the new one, as the APIs expect
+	// the roles to alternate.
+	prompt.Turns = historyTurns(req.History)
+	if last := len(prompt.Turns) - 1; last >= 0 && prompt.Turns[last].Role == types.MessageRoleUser {
+		prompt.User = prompt.Turns[last].Content + "\n\n" + prompt.User
+		prompt.Turns = prompt.Turns[:last]
+	}
+	return prompt, nil
+}
+
+// buildGenericDiagnosisPrompt creates generic prompts for general questions
+func (m *promptBuilder) buildGenericDiagnosisPrompt(data promptData) (promptPair, error) {
+	return m.templates.render(templateGeneral, data)
+}
@@ -0,0 +1,123 @@
+package managers
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"podscription-api/types"
+)
+
+//go:embed fixtures/fake.json
+var defaultFakeFixtures []byte
+
+// fakeFixture is a canned model response selected by a message pattern
+type fakeFixture struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern,omitempty"`
+	// Classification is the raw model response, a structured classification object
+	Classification json.RawMessage `json:"classification"`
+	// Diagnosis is the raw model response, a structured diagnosis object
+	Diagnosis json.RawMessage `json:"diagnosis"`
+
+	re *regexp.Regexp
+}
+
+// fakeFixtureSet is the on-disk fixture file format
+type fakeFixtureSet struct {
+	Fixtures []fakeFixture `json:"fixtures"`
+	Default  fakeFixture   `json:"default"`
+}
+
+// FakeProvider is a deterministic, offline Provider that replays canned responses.
+// Fixtures are matched in order against the user's message; the first match wins.
+type FakeProvider struct {
+	promptBuilder
+	fixtures []fakeFixture
+	fallback fakeFixture
+}
+
+// NewFakeProvider creates a fake provider from the fixture file at path,
+// or from the built-in fixtures when path is empty
+func NewFakeProvider(path string, templates *PromptTemplates) (*FakeProvider, error) {
+	data := defaultFakeFixtures
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fake fixtures: %w", err)
+		}
+	}
+
+	var set fakeFixtureSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse fake fixtures: %w", err)
+	}
+
+	for i := range set.Fixtures {
+		re, err := regexp.Compile(set.Fixtures[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for fixture %q: %w", set.Fixtures[i].Name, err)
+		}
+		set.Fixtures[i].re = re
+	}
+
+	return &FakeProvider{
+		promptBuilder: newPromptBuilder(templates),
+		fixtures:      set.Fixtures,
+		fallback:      set.Default,
+	}, nil
+}
+
+// Model identifies the fake provider in place of a model
+func (p *FakeProvider) Model() string {
+	return "fake"
+}
+
+// ClassifyIntent returns the canned classification for the first matching fixture
+func (p *FakeProvider) ClassifyIntent(ctx context.Context, message string) (*types.PodIntent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to classify intent: %w", err)
+	}
+
+	return p.parseIntentResponse(string(p.match(message).Classification))
+}
+
+// GenerateDiagnosis returns the canned diagnosis for the first matching fixture
+func (p *FakeProvider) GenerateDiagnosis(ctx context.Context, req DiagnosisRequest) (*types.Prescription, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to generate diagnosis: %w", err)
+	}
+
+	prescription, content := p.parseDiagnosisResponse(string(p.match(req.Message).Diagnosis), req.Intent)
+	return prescription, content, nil
+}
+
+// StreamDiagnosis replays the canned diagnosis for the first matching fixture word by word
+func (p *FakeProvider) StreamDiagnosis(ctx context.Context, req DiagnosisRequest, onDelta DeltaFunc) (*types.Prescription, string, error) {
+	response := string(p.match(req.Message).Diagnosis)
+
+	for _, delta := range strings.SplitAfter(response, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, "", fmt.Errorf("failed to stream diagnosis: %w", err)
+		}
+		onDelta(delta)
+	}
+
+	prescription, content := p.parseDiagnosisResponse(response, req.Intent)
+	return prescription, content, nil
+}
+
+// match returns the first fixture whose pattern matches the message
+func (p *FakeProvider) match(message string) fakeFixture {
+	for _, fixture := range p.fixtures {
+		if fixture.re.MatchString(message) {
+			return fixture
+		}
+	}
+	return p.fallback
+}
@@ -0,0 +1,118 @@
+package managers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// newFakeSessionManager wires a session manager to an in-memory store and the built-in fake fixtures
+func newFakeSessionManager(t *testing.T) *SessionManager {
+	t.Helper()
+
+	templates, err := LoadPromptTemplates("")
+	if err != nil {
+		t.Fatalf("failed to load prompt templates: %v", err)
+	}
+	provider, err := NewFakeProvider("", templates)
+	if err != nil {
+		t.Fatalf("failed to load fake fixtures: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	memory, err := store.NewMemoryStore("", 0, logger)
+	if err != nil {
+		t.Fatalf("failed to open memory store: %v", err)
+	}
+	return NewSessionManager(memory, provider, logger)
+}
+
+func TestFakeProviderProcessMessage(t *testing.T) {
+	tests := []struct {
+		message   string
+		category  types.IntentCategory
+		diagnosis string
+	}{
+		{"my pod keeps restarting with CrashLoopBackOff", types.IntentCategoryPodIssues, "Recurrent Container Arrest"},
+		{"ErrImagePull: pull access denied", types.IntentCategoryPodIssues, "Acute Image Malabsorption"},
+		{"lookup db.shop.svc: no such host", types.IntentCategoryNetworking, "Name Resolution Aphasia"},
+		{"my PVC is stuck pending", types.IntentCategoryStorage, "Persistent Volume Binding Deficiency"},
+		{"pods is forbidden for serviceaccount ci", types.IntentCategoryRBAC, "Authorization Immunodeficiency"},
+		{"something is off with my cluster", types.IntentCategoryGeneral, "Undifferentiated Cluster Malaise"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			manager := newFakeSessionManager(t)
+			session, err := manager.CreateSession("")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+
+			response, err := manager.ProcessMessage(context.Background(), session.ID, tt.message, nil)
+			if err != nil {
+				t.Fatalf("failed to process message: %v", err)
+			}
+			updated, message := response.Session, response.Message
+
+			if message.Role != types.MessageRoleAssistant {
+				t.Fatalf("got a %s message, want the assistant's answer", message.Role)
+			}
+			if message.Intent == nil || message.Intent.Category != tt.category {
+				t.Fatalf("got intent %+v, want category %s", message.Intent, tt.category)
+			}
+			if message.Prescription == nil || !strings.Contains(message.Prescription.Diagnosis, tt.diagnosis) {
+				t.Fatalf("got prescription %+v, want diagnosis %q", message.Prescription, tt.diagnosis)
+			}
+			if len(updated.Messages) != 2 || updated.Messages[0].Content != tt.message {
+				t.Fatalf("got %d messages, want the question and its answer", len(updated.Messages))
+			}
+			if stored := updated.Messages[1]; stored.ID != message.ID || !stored.Timestamp.Equal(message.Timestamp) {
+				t.Fatalf("got message %s at %v, want the stored answer %s at %v", message.ID, message.Timestamp, stored.ID, stored.Timestamp)
+			}
+		})
+	}
+}
+
+func TestFakeProviderProcessMessageStream(t *testing.T) {
+	manager := newFakeSessionManager(t)
+	session, err := manager.CreateSession("")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	var intent *types.PodIntent
+	var streamed strings.Builder
+	deltas := 0
+	response, err := manager.ProcessMessageStream(context.Background(), session.ID, "my pod keeps restarting", nil, StreamCallbacks{
+		OnIntent: func(classified *types.PodIntent) {
+			if streamed.Len() > 0 {
+				t.Error("intent reported after the diagnosis started streaming")
+			}
+			intent = classified
+		},
+		OnDelta: func(delta string) {
+			deltas++
+			streamed.WriteString(delta)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to process message: %v", err)
+	}
+	message := response.Message
+
+	if intent == nil || intent.Category != types.IntentCategoryPodIssues {
+		t.Fatalf("got intent %+v, want pod-issues", intent)
+	}
+	if deltas < 2 {
+		t.Fatalf("got %d deltas, want the diagnosis in several chunks", deltas)
+	}
+	if !strings.HasPrefix(streamed.String(), message.Prescription.Diagnosis) || strings.Contains(streamed.String(), `"diagnosis"`) {
+		t.Fatalf("streamed %q, want the decoded diagnosis %q without its JSON", streamed.String(), message.Prescription.Diagnosis)
+	}
+}
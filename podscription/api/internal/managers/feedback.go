@@ -0,0 +1,126 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// maxFeedbackExamples caps the negative feedback listed for each group of a feedback report
+const maxFeedbackExamples = 10
+
+var (
+	// ErrMessageNotFound is returned when a session has no assistant message with the given ID
+	ErrMessageNotFound = errors.New("assistant message not found")
+	// ErrCommandNotPrescribed is returned when feedback flags a command the message did not prescribe
+	ErrCommandNotPrescribed = errors.New("command not prescribed by the message")
+)
+
+// SetFeedback rates an assistant message, replacing any earlier feedback on it. Flagged commands
+// must be among those the message prescribed.
+func (m *SessionManager) SetFeedback(sessionID, messageID uuid.UUID, rating types.FeedbackRating, comment string, wrongCommands []string) (*types.Feedback, error) {
+	session, err := m.store.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	index := slices.IndexFunc(session.Messages, func(message types.Message) bool {
+		return message.ID == messageID && message.Role == types.MessageRoleAssistant
+	})
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	message := session.Messages[index]
+
+	prescribed := prescribedCommands(message.Prescription)
+	for _, command := range wrongCommands {
+		if !slices.Contains(prescribed, command) {
+			return nil, fmt.Errorf("%w: %s", ErrCommandNotPrescribed, command)
+		}
+	}
+
+	feedback := &types.Feedback{
+		Rating:        rating,
+		Comment:       comment,
+		WrongCommands: wrongCommands,
+		UpdatedAt:     time.Now(),
+	}
+	if err := m.store.SetMessageFeedback(sessionID, messageID, feedback); err != nil {
+		if errors.Is(err, store.ErrMessageNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		return nil, fmt.Errorf("failed to update message: %w", err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id":     sessionID,
+		"message_id":     messageID,
+		"rating":         rating,
+		"wrong_commands": len(wrongCommands),
+		"prompt_version": message.PromptVersion,
+		"model":          message.Model,
+	}).Info("recorded message feedback")
+
+	return feedback, nil
+}
+
+// FeedbackReport groups the feedback on the stored assistant messages by the prompt version, model
+// and intent category that produced them, the groups with the most negative feedback first
+func (m *SessionManager) FeedbackReport() (*types.FeedbackReport, error) {
+	groups, err := m.store.FeedbackGroups(maxFeedbackExamples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group feedback: %w", err)
+	}
+
+	report := &types.FeedbackReport{Groups: groups}
+	for i := range report.Groups {
+		group := &report.Groups[i]
+		group.NegativeRate = math.Round(float64(group.Negative)/float64(group.Rated)*1000) / 1000
+		report.Rated += group.Rated
+		report.Negative += group.Negative
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Negative != b.Negative {
+			return a.Negative > b.Negative
+		}
+		if a.NegativeRate != b.NegativeRate {
+			return a.NegativeRate > b.NegativeRate
+		}
+		if a.PromptVersion != b.PromptVersion {
+			return a.PromptVersion > b.PromptVersion
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.Analyzer != b.Analyzer {
+			return a.Analyzer < b.Analyzer
+		}
+		return a.Category < b.Category
+	})
+	return report, nil
+}
+
+// prescribedCommands lists the commands of a prescription and of its steps
+func prescribedCommands(prescription *types.Prescription) []string {
+	if prescription == nil {
+		return nil
+	}
+
+	commands := slices.Clone(prescription.Commands)
+	for _, step := range prescription.Steps {
+		if step.Command != "" && !slices.Contains(commands, step.Command) {
+			commands = append(commands, step.Command)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,40 @@
+package managers
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/knowledge"
+	"podscription-api/types"
+)
+
+// WithKnowledge shows the diagnosis up to limit runbook excerpts relevant to each message,
+// recording the excerpts as the sources of the answer
+func WithKnowledge(base *knowledge.Base, limit int) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.knowledge = base
+		m.knowledgeLimit = limit
+	}
+}
+
+// knowledgeSources finds the runbook excerpts relevant to a message
+func (m *SessionManager) knowledgeSources(sessionID uuid.UUID, evidence string, intent *types.PodIntent) []types.KnowledgeSource {
+	if m.knowledge == nil {
+		return nil
+	}
+
+	sources := m.knowledge.Search(knowledge.Query{
+		Text:     evidence,
+		Category: intent.Category,
+		Symptoms: intent.Symptoms,
+	}, m.knowledgeLimit)
+
+	if len(sources) > 0 {
+		m.logger.WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"sources":    len(sources),
+			"top_source": sources[0].Path,
+			"top_score":  sources[0].Score,
+		}).Info("found runbook excerpts")
+	}
+	return sources
+}
@@ -0,0 +1,156 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	attachmentparser "podscription-api/internal/attachments"
+	"podscription-api/types"
+)
+
+// ErrStepNotFound is returned when a session's treatment plan has no step with the given ID
+var ErrStepNotFound = errors.New("treatment step not found")
+
+// GetPlan returns the treatment plan of a session
+func (m *SessionManager) GetPlan(sessionID uuid.UUID) (*types.TreatmentPlan, error) {
+	session, err := m.store.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if session.Plan == nil {
+		return &types.TreatmentPlan{Steps: make([]types.PlanStep, 0)}, nil
+	}
+	return session.Plan, nil
+}
+
+// UpdatePlanStep records how a prescribed step went. The outcome is given to the model
+// with the next message of the session, even when it was reported before.
+func (m *SessionManager) UpdatePlanStep(sessionID, stepID uuid.UUID, status types.StepStatus, output *string) (*types.TreatmentPlan, error) {
+	var step types.PlanStep
+	plan, err := m.store.UpdatePlan(sessionID, func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error) {
+		if plan == nil {
+			return nil, fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
+		}
+		index := slices.IndexFunc(plan.Steps, func(step types.PlanStep) bool { return step.ID == stepID })
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
+		}
+
+		now := time.Now()
+		plan.Steps[index].Status = status
+		if output != nil {
+			plan.Steps[index].Output = *output
+		}
+		plan.Steps[index].UpdatedAt = &now
+		plan.Steps[index].Reported = false
+		step = plan.Steps[index]
+		return plan, nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrStepNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update treatment plan: %w", err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id":    sessionID,
+		"step_id":       stepID,
+		"status":        status,
+		"output_length": len(step.Output),
+	}).Info("updated treatment step")
+
+	return plan, nil
+}
+
+// stepReports returns the steps whose outcome the model has not seen yet, and their
+// output as attachments of the next message
+func stepReports(plan *types.TreatmentPlan) ([]types.PlanStep, []types.Attachment) {
+	if plan == nil {
+		return nil, nil
+	}
+
+	var reports []types.PlanStep
+	var outputs []types.Attachment
+	for _, step := range plan.Steps {
+		if step.Status == types.StepStatusPending || step.Reported {
+			continue
+		}
+		reports = append(reports, step)
+		if step.Output != "" {
+			outputs = append(outputs, types.Attachment{
+				Kind:    attachmentparser.KindForCommand(step.Command),
+				Name:    stepAttachmentName(step),
+				Content: step.Output,
+			})
+		}
+	}
+	return reports, outputs
+}
+
+// stepAttachmentName names the attachment holding a step's output
+func stepAttachmentName(step types.PlanStep) string {
+	return fmt.Sprintf("step %d: %s", step.Number, step.Title)
+}
+
+// savePlan advances a session's treatment plan after a diagnosis. The diagnosis is already saved,
+// so failing only costs a repeated report of the steps.
+func (m *SessionManager) savePlan(session *types.Session, reports []types.PlanStep, message types.Message) {
+	var steps []types.TreatmentStep
+	if message.Prescription != nil {
+		steps = message.Prescription.Steps
+	}
+	if len(reports) == 0 && len(steps) == 0 {
+		return
+	}
+
+	// Advance the stored plan, the user may have reported steps during the diagnosis
+	plan, err := m.store.UpdatePlan(session.ID, func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error) {
+		return advancePlan(plan, reports, message.ID, steps), nil
+	})
+	if err != nil {
+		m.logger.WithError(err).WithField("session_id", session.ID).Warn("failed to save treatment plan")
+		return
+	}
+	session.Plan = plan
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id": session.ID,
+		"plan_steps": len(plan.Steps),
+	}).Info("updated treatment plan")
+}
+
+// advancePlan marks the reported steps as seen by the model, unless they changed since, and adds
+// the steps of a new prescription
+func advancePlan(plan *types.TreatmentPlan, reports []types.PlanStep, messageID uuid.UUID, steps []types.TreatmentStep) *types.TreatmentPlan {
+	advanced := &types.TreatmentPlan{Steps: make([]types.PlanStep, 0)}
+	if plan != nil {
+		advanced.Steps = append(advanced.Steps, plan.Steps...)
+	}
+
+	for _, report := range reports {
+		for i := range advanced.Steps {
+			step := &advanced.Steps[i]
+			if step.ID == report.ID && step.UpdatedAt != nil && report.UpdatedAt != nil && step.UpdatedAt.Equal(*report.UpdatedAt) {
+				step.Reported = true
+			}
+		}
+	}
+
+	for i, step := range steps {
+		advanced.Steps = append(advanced.Steps, types.PlanStep{
+			ID:        uuid.New(),
+			MessageID: messageID,
+			Number:    i + 1,
+			Title:     step.Title,
+			Command:   step.Command,
+			Status:    types.StepStatusPending,
+		})
+	}
+	return advanced
+}
@@ -0,0 +1,132 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// recordingProvider is a FakeProvider that keeps the diagnosis requests and prompts it was given
+type recordingProvider struct {
+	*FakeProvider
+	requests []DiagnosisRequest
+	prompts  []promptPair
+}
+
+func (p *recordingProvider) GenerateDiagnosis(ctx context.Context, req DiagnosisRequest) (*types.Prescription, string, error) {
+	prompt, err := p.buildDiagnosisPrompt(req)
+	if err != nil {
+		return nil, "", err
+	}
+	p.requests = append(p.requests, req)
+	p.prompts = append(p.prompts, prompt)
+	return p.FakeProvider.GenerateDiagnosis(ctx, req)
+}
+
+func TestPlanStepResultsFeedNextDiagnosis(t *testing.T) {
+	manager := newFakeSessionManager(t)
+	provider := &recordingProvider{FakeProvider: manager.provider.(*FakeProvider)}
+	manager.provider = provider
+
+	session, err := manager.CreateSession("")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	first, err := manager.ProcessMessage(context.Background(), session.ID, "my pod keeps restarting", nil)
+	if err != nil {
+		t.Fatalf("failed to process message: %v", err)
+	}
+
+	// The prescription's steps open the plan
+	plan, err := manager.GetPlan(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if len(plan.Steps) == 0 || len(plan.Steps) != len(first.Message.Prescription.Steps) {
+		t.Fatalf("got %d plan steps, want the %d prescribed", len(plan.Steps), len(first.Message.Prescription.Steps))
+	}
+	step := plan.Steps[0]
+	if step.MessageID != first.Message.ID || step.Number != 1 || step.Status != types.StepStatusPending {
+		t.Fatalf("got step %+v, want the first pending step of the answer", step)
+	}
+
+	output := "Last State: Terminated\n  Reason: OOMKilled\n  Exit Code: 137"
+	updated, err := manager.UpdatePlanStep(session.ID, step.ID, types.StepStatusDone, &output)
+	if err != nil {
+		t.Fatalf("failed to update step: %v", err)
+	}
+	if got := updated.Steps[0]; got.Status != types.StepStatusDone || got.Output != output || got.UpdatedAt == nil || got.Reported {
+		t.Fatalf("got step %+v, want it done with its output and not reported yet", got)
+	}
+	if _, err := manager.UpdatePlanStep(session.ID, uuid.New(), types.StepStatusDone, nil); !errors.Is(err, ErrStepNotFound) {
+		t.Fatalf("got %v for an unknown step, want ErrStepNotFound", err)
+	}
+
+	// The next diagnosis is told how the step went and gets its output as an attachment
+	second, err := manager.ProcessMessage(context.Background(), session.ID, "what now?", nil)
+	if err != nil {
+		t.Fatalf("failed to process message: %v", err)
+	}
+	req := provider.requests[1]
+	if len(req.StepReports) != 1 || req.StepReports[0].ID != step.ID || req.StepReports[0].Status != types.StepStatusDone {
+		t.Fatalf("got step reports %+v, want the done step", req.StepReports)
+	}
+	if len(req.Attachments) != 1 || req.Attachments[0].Name != stepAttachmentName(step) || req.Attachments[0].Content != output {
+		t.Fatalf("got attachments %+v, want the step's output", req.Attachments)
+	}
+	prompt := provider.prompts[1].User
+	if !strings.Contains(prompt, `Step 1 "`+step.Title+`"`) || !strings.Contains(prompt, string(types.StepStatusDone)) ||
+		!strings.Contains(prompt, `output attached as "`+stepAttachmentName(step)+`"`) {
+		t.Fatalf("got prompt %q, want the step's outcome", prompt)
+	}
+
+	// Once reported, the step is not reported again, and the new prescription's steps are added
+	plan, err = manager.GetPlan(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if !plan.Steps[0].Reported {
+		t.Fatalf("got step %+v, want it marked as reported", plan.Steps[0])
+	}
+	added := plan.Steps[len(first.Message.Prescription.Steps):]
+	if len(added) != len(second.Message.Prescription.Steps) || added[0].MessageID != second.Message.ID {
+		t.Fatalf("got %d new steps, want the %d of the second answer", len(added), len(second.Message.Prescription.Steps))
+	}
+	if _, err := manager.ProcessMessage(context.Background(), session.ID, "thanks", nil); err != nil {
+		t.Fatalf("failed to process message: %v", err)
+	}
+	if reports := provider.requests[2].StepReports; len(reports) != 0 {
+		t.Fatalf("got step reports %+v, want none after they were reported", reports)
+	}
+}
+
+func TestAdvancePlanKeepsStepsChangedSinceReport(t *testing.T) {
+	reportedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	changedAt := reportedAt.Add(time.Minute)
+	unchanged := types.PlanStep{ID: uuid.New(), Status: types.StepStatusDone, UpdatedAt: &reportedAt}
+	changed := types.PlanStep{ID: uuid.New(), Status: types.StepStatusFailed, UpdatedAt: &changedAt}
+	plan := &types.TreatmentPlan{Steps: []types.PlanStep{unchanged, changed}}
+
+	// The second step was reported as done, then changed to failed during the diagnosis
+	reports := []types.PlanStep{unchanged, {ID: changed.ID, Status: types.StepStatusDone, UpdatedAt: &reportedAt}}
+	messageID := uuid.New()
+	advanced := advancePlan(plan, reports, messageID, []types.TreatmentStep{{Title: "Raise the memory limit"}})
+
+	if len(advanced.Steps) != 3 {
+		t.Fatalf("got %d steps, want the two reported and the new one", len(advanced.Steps))
+	}
+	if !advanced.Steps[0].Reported || advanced.Steps[1].Reported {
+		t.Fatalf("got reported %v and %v, want only the unchanged step reported", advanced.Steps[0].Reported, advanced.Steps[1].Reported)
+	}
+	if added := advanced.Steps[2]; added.MessageID != messageID || added.Number != 1 || added.Status != types.StepStatusPending {
+		t.Fatalf("got step %+v, want a pending step of the new message", added)
+	}
+	if plan.Steps[0].Reported {
+		t.Fatal("advancePlan changed the plan it was given")
+	}
+}
@@ -0,0 +1,251 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"podscription-api/types"
+)
+
+// diagnosisResponseSchema is the JSON schema every diagnosis response must follow
+const diagnosisResponseSchema = `{
+  "type": "object",
+  "required": ["diagnosis", "explanation", "treatment", "steps", "followUp"],
+  "properties": {
+    "diagnosis": {"type": "string", "description": "Medical-style name of the condition"},
+    "explanation": {"type": "string", "description": "Brief explanation of the issue using medical metaphors"},
+    "treatment": {"type": "string", "description": "One or two sentence overview of the treatment plan"},
+    "steps": {
+      "type": "array",
+      "description": "Ordered treatment steps, most informative and least risky first",
+      "items": {
+        "type": "object",
+        "required": ["title", "rationale", "risk"],
+        "properties": {
+          "title": {"type": "string", "description": "Short name of the step"},
+          "rationale": {"type": "string", "description": "Why this step helps narrow down or fix the issue"},
+          "command": {"type": "string", "description": "A single kubectl or shell command, empty for manual actions"},
+          "expectedOutput": {"type": "string", "description": "What a healthy or a tell-tale result looks like"},
+          "risk": {"type": "string", "enum": ["low", "medium", "high"], "description": "low for read-only, medium for changes that can be undone, high for disruptive or destructive changes"}
+        }
+      }
+    },
+    "followUp": {"type": "string", "description": "Follow-up care and prevention advice"},
+    "closing": {"type": "string", "description": "A medical-themed joke or memorable phrase"}
+  }
+}`
+
+// diagnosisPayload mirrors diagnosisResponseSchema
+type diagnosisPayload struct {
+	Diagnosis   string                `json:"diagnosis"`
+	Explanation string                `json:"explanation"`
+	Treatment   string                `json:"treatment"`
+	Steps       []types.TreatmentStep `json:"steps"`
+	FollowUp    string                `json:"followUp"`
+	Closing     string                `json:"closing"`
+}
+
+// parseDiagnosisResponse builds a prescription from a structured diagnosis response
+// and returns it together with its markdown rendering. Responses that are not valid
+// JSON, e.g. from local models without JSON mode, fall back to markdown scraping.
+func (m *promptBuilder) parseDiagnosisResponse(response string, intent *types.PodIntent) (*types.Prescription, string) {
+	var payload diagnosisPayload
+	if err := json.Unmarshal([]byte(extractJSONObject(response)), &payload); err != nil || payload.Diagnosis == "" {
+		return m.parseMarkdownDiagnosis(response), response
+	}
+
+	prescription := &types.Prescription{
+		Diagnosis:   strings.TrimSpace(payload.Diagnosis),
+		Explanation: strings.TrimSpace(payload.Explanation),
+		Treatment:   strings.TrimSpace(payload.Treatment),
+		Steps:       make([]types.TreatmentStep, 0, len(payload.Steps)),
+		FollowUp:    strings.TrimSpace(payload.FollowUp),
+		Closing:     strings.TrimSpace(payload.Closing),
+	}
+
+	for _, step := range payload.Steps {
+		step.Title = strings.TrimSpace(step.Title)
+		step.Command = strings.Trim(strings.TrimSpace(step.Command), "`")
+		step.Risk = normalizeRiskLevel(step.Risk, step.Command)
+		if step.Title == "" && step.Command == "" {
+			continue
+		}
+		prescription.Steps = append(prescription.Steps, step)
+		if step.Command != "" {
+			prescription.Commands = append(prescription.Commands, step.Command)
+		}
+	}
+
+	if prescription.Treatment == "" {
+		prescription.Treatment = fmt.Sprintf("Follow the %d prescribed steps in order.", len(prescription.Steps))
+	}
+
+	category := types.IntentCategoryGeneral
+	if intent != nil {
+		category = intent.Category
+	}
+
+	return prescription, renderPrescription(prescription, category)
+}
+
+// renderPrescription renders a prescription as the Pod Doctor's markdown consultation notes
+func renderPrescription(p *types.Prescription, category types.IntentCategory) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## %s: %s\n\n", diagnosisHeading(category), p.Diagnosis)
+	if p.Explanation != "" {
+		fmt.Fprintf(&b, "%s\n\n", p.Explanation)
+	}
+
+	b.WriteString("### 💊 Prescribed Treatment:\n")
+	if p.Treatment != "" {
+		fmt.Fprintf(&b, "%s\n\n", p.Treatment)
+	}
+	for i, step := range p.Steps {
+		if step.Command != "" {
+			fmt.Fprintf(&b, "%d. **%s**: `%s`\n", i+1, step.Title, step.Command)
+		} else {
+			fmt.Fprintf(&b, "%d. **%s**\n", i+1, step.Title)
+		}
+		if step.Rationale != "" {
+			fmt.Fprintf(&b, "   - %s\n", step.Rationale)
+		}
+		if step.ExpectedOutput != "" {
+			fmt.Fprintf(&b, "   - *Expected:* %s\n", step.ExpectedOutput)
+		}
+		if step.Risk == types.RiskLevelMedium || step.Risk == types.RiskLevelHigh {
+			fmt.Fprintf(&b, "   - ⚠️ *Risk:* %s\n", step.Risk)
+		}
+	}
+
+	if p.FollowUp != "" {
+		fmt.Fprintf(&b, "\n### Follow-up Care:\n%s\n", p.FollowUp)
+	}
+
+	if p.Closing != "" {
+		fmt.Fprintf(&b, "\n*%s*\n", strings.Trim(p.Closing, "*"))
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// diagnosisHeading returns the specialist heading used for a category
+func diagnosisHeading(category types.IntentCategory) string {
+	switch category {
+	case types.IntentCategoryNetworking:
+		return "🌐 Network Diagnosis"
+	case types.IntentCategoryStorage:
+		return "💾 Storage Diagnosis"
+	case types.IntentCategoryPodIssues:
+		return "📦 Pod Diagnosis"
+	case types.IntentCategoryRBAC:
+		return "🔐 Access Diagnosis"
+	case types.IntentCategoryPerformance:
+		return "📈 Performance Diagnosis"
+	default:
+		return "🩺 Diagnosis"
+	}
+}
+
+// normalizeRiskLevel maps a model-provided risk onto a known level,
+// inferring it from the command when the model left it out
+func normalizeRiskLevel(risk types.RiskLevel, command string) types.RiskLevel {
+	switch types.RiskLevel(strings.ToLower(strings.TrimSpace(string(risk)))) {
+	case types.RiskLevelLow:
+		return types.RiskLevelLow
+	case types.RiskLevelMedium:
+		return types.RiskLevelMedium
+	case types.RiskLevelHigh:
+		return types.RiskLevelHigh
+	default:
+		return inferRiskLevel(command)
+	}
+}
+
+// inferRiskLevel treats well-known read-only kubectl verbs as low risk and anything else as medium
+func inferRiskLevel(command string) types.RiskLevel {
+	readOnlyPrefixes := []string{"kubectl get", "kubectl describe", "kubectl logs", "kubectl top", "kubectl auth can-i", "kubectl explain", "kubectl events"}
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(command, prefix) {
+			return types.RiskLevelLow
+		}
+	}
+	return types.RiskLevelMedium
+}
+
+// extractJSONObject strips markdown fences and surrounding prose from a JSON object response
+func extractJSONObject(response string) string {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return response
+	}
+	return response[start : end+1]
+}
+
+// parseMarkdownDiagnosis extracts prescription information from a free-form markdown diagnosis
+func (m *promptBuilder) parseMarkdownDiagnosis(response string) *types.Prescription {
+	// Extract diagnosis from the response (simple parsing)
+	diagnosis := "Kubernetes Issue Diagnosis"
+	if strings.Contains(response, "Diagnosis:") {
+		parts := strings.Split(response, "Diagnosis:")
+		if len(parts) > 1 {
+			diagnosisPart := strings.Split(parts[1], "\n")[0]
+			diagnosis = strings.TrimSpace(strings.TrimPrefix(diagnosisPart, "🩺"))
+		}
+	}
+
+	// Extract commands (look for backticked commands)
+	steps := []types.TreatmentStep{}
+	commands := []string{}
+	lines := strings.Split(response, "\n")
+	for _, line := range lines {
+		if strings.Contains(line, "`") {
+			// Extract commands from backticks
+			start := strings.Index(line, "`")
+			end := strings.LastIndex(line, "`")
+			if start != -1 && end != -1 && start != end {
+				command := strings.TrimSpace(line[start+1 : end])
+				if command != "" && strings.HasPrefix(command, "kubectl") {
+					commands = append(commands, command)
+					steps = append(steps, types.TreatmentStep{
+						Title:   markdownStepTitle(line[:start], len(steps)+1),
+						Command: command,
+						Risk:    inferRiskLevel(command),
+					})
+				}
+			}
+		}
+	}
+
+	// Extract follow-up (look for Follow-up section)
+	followUp := ""
+	if strings.Contains(response, "Follow-up Care:") {
+		parts := strings.Split(response, "Follow-up Care:")
+		if len(parts) > 1 {
+			followUpPart := strings.Split(parts[1], "*")[0] // Stop at the joke
+			followUp = strings.TrimSpace(followUpPart)
+		}
+	}
+
+	return &types.Prescription{
+		Diagnosis: diagnosis,
+		Treatment: "Refer to the detailed diagnosis above for treatment recommendations.",
+		Steps:     steps,
+		Commands:  commands,
+		FollowUp:  followUp,
+	}
+}
+
+// markdownStepTitle extracts a step title like "1. **Check logs**:" from the text before a command
+func markdownStepTitle(prefix string, index int) string {
+	title := strings.TrimSpace(prefix)
+	title = strings.TrimLeft(title, "0123456789.-) ")
+	title = strings.Trim(strings.TrimSuffix(strings.TrimSpace(title), ":"), "* ")
+	title = strings.TrimSuffix(title, ":")
+	if title == "" {
+		return fmt.Sprintf("Step %d", index)
+	}
+	return title
+}
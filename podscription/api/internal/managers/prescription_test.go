@@ -0,0 +1,180 @@
+package managers
+
+import (
+	"strings"
+	"testing"
+
+	"podscription-api/types"
+)
+
+const structuredDiagnosis = `{
+  "diagnosis": "  Recurrent Container Arrest ",
+  "explanation": "The container keeps collapsing.",
+  "treatment": "",
+  "steps": [
+    {"title": "Read the logs", "rationale": "Find the error", "command": "` + "`kubectl logs web --previous`" + `", "expectedOutput": "A stack trace", "risk": "LOW"},
+    {"title": "Restart", "rationale": "Clear the state", "command": "kubectl rollout restart deploy/web", "risk": "medium"},
+    {"title": "", "command": ""},
+    {"title": "Check the node", "rationale": "Rule out pressure", "command": ""}
+  ],
+  "followUp": "Add a liveness probe.",
+  "closing": "*Take two restarts*"
+}`
+
+// newTestPromptBuilder creates a prompt builder backed by the embedded templates
+func newTestPromptBuilder(t *testing.T) promptBuilder {
+	t.Helper()
+
+	templates, err := LoadPromptTemplates("")
+	if err != nil {
+		t.Fatalf("failed to load prompt templates: %v", err)
+	}
+	return newPromptBuilder(templates)
+}
+
+func TestParseDiagnosisResponseJSON(t *testing.T) {
+	builder := newTestPromptBuilder(t)
+	intent := &types.PodIntent{Category: types.IntentCategoryNetworking}
+
+	prescription, content := builder.parseDiagnosisResponse(structuredDiagnosis, intent)
+
+	if prescription.Diagnosis != "Recurrent Container Arrest" {
+		t.Fatalf("got diagnosis %q, want it trimmed", prescription.Diagnosis)
+	}
+	if prescription.Treatment != "Follow the 3 prescribed steps in order." {
+		t.Fatalf("got treatment %q, want the default overview", prescription.Treatment)
+	}
+	if len(prescription.Steps) != 3 {
+		t.Fatalf("got %d steps, want the empty step dropped", len(prescription.Steps))
+	}
+
+	wantCommands := []string{"kubectl logs web --previous", "kubectl rollout restart deploy/web"}
+	if strings.Join(prescription.Commands, "|") != strings.Join(wantCommands, "|") {
+		t.Fatalf("got commands %q, want %q", prescription.Commands, wantCommands)
+	}
+	wantRisks := []types.RiskLevel{types.RiskLevelLow, types.RiskLevelMedium, types.RiskLevelMedium}
+	for i, step := range prescription.Steps {
+		if step.Risk != wantRisks[i] {
+			t.Errorf("step %d: got risk %q, want %q", i, step.Risk, wantRisks[i])
+		}
+	}
+
+	for _, want := range []string{
+		"## 🌐 Network Diagnosis: Recurrent Container Arrest",
+		"1. **Read the logs**: `kubectl logs web --previous`",
+		"   - *Expected:* A stack trace",
+		"   - ⚠️ *Risk:* medium",
+		"3. **Check the node**\n",
+		"### Follow-up Care:\nAdd a liveness probe.",
+		"*Take two restarts*",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("rendered content is missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestParseDiagnosisResponseCodeFence(t *testing.T) {
+	builder := newTestPromptBuilder(t)
+	response := "Here is my diagnosis:\n```json\n{\"diagnosis\": \"Fenced Fever\", \"steps\": []}\n```\nGet well soon."
+
+	prescription, content := builder.parseDiagnosisResponse(response, nil)
+
+	if prescription.Diagnosis != "Fenced Fever" {
+		t.Fatalf("got diagnosis %q, want the fenced JSON parsed", prescription.Diagnosis)
+	}
+	if !strings.HasPrefix(content, "## 🩺 Diagnosis: Fenced Fever") {
+		t.Fatalf("got content %q, want the general heading", content)
+	}
+}
+
+func TestParseDiagnosisResponseMarkdownFallback(t *testing.T) {
+	builder := newTestPromptBuilder(t)
+	response := "## 🩺 Diagnosis: Pod Pneumonia\n\n" +
+		"1. **Check events**: `kubectl get events -n shop`\n" +
+		"2. **Bounce it**: `kubectl delete pod web-1`\n" +
+		"3. Pray: `echo amen`\n\n" +
+		"### Follow-up Care:\nMonitor closely.\n\n*Get well soon!*"
+
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"markdown", response},
+		{"JSON without a diagnosis", `{"explanation": "no name"}`},
+		{"broken JSON", `{"diagnosis": "Pod Pneumonia",`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prescription, content := builder.parseDiagnosisResponse(tt.response, nil)
+			if content != tt.response {
+				t.Fatalf("got content %q, want the response unchanged", content)
+			}
+			if prescription == nil || prescription.Diagnosis == "" {
+				t.Fatalf("got prescription %+v, want a scraped one", prescription)
+			}
+		})
+	}
+
+	prescription, _ := builder.parseDiagnosisResponse(response, nil)
+	if prescription.Diagnosis != "Pod Pneumonia" {
+		t.Fatalf("got diagnosis %q, want Pod Pneumonia", prescription.Diagnosis)
+	}
+	if len(prescription.Steps) != 2 || prescription.Steps[0].Title != "Check events" || prescription.Steps[1].Title != "Bounce it" {
+		t.Fatalf("got steps %+v, want the two kubectl steps", prescription.Steps)
+	}
+	if prescription.Steps[0].Risk != types.RiskLevelLow || prescription.Steps[1].Risk != types.RiskLevelMedium {
+		t.Fatalf("got risks %q and %q, want inferred low and medium", prescription.Steps[0].Risk, prescription.Steps[1].Risk)
+	}
+	if prescription.FollowUp != "Monitor closely." {
+		t.Fatalf("got follow-up %q, want Monitor closely.", prescription.FollowUp)
+	}
+}
+
+func TestExtractJSONObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"bare object", `{"a": 1}`, `{"a": 1}`},
+		{"code fence", "```json\n{\"a\": {\"b\": 2}}\n```", `{"a": {"b": 2}}`},
+		{"surrounding prose", "Sure! {\"a\": 1} Hope that helps.", `{"a": 1}`},
+		{"no object", "just text", "just text"},
+		{"closing brace first", "} then {", "} then {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractJSONObject(tt.response); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRiskLevel(t *testing.T) {
+	tests := []struct {
+		risk    types.RiskLevel
+		command string
+		want    types.RiskLevel
+	}{
+		{"low", "kubectl delete pod web", types.RiskLevelLow},
+		{" High ", "kubectl get pods", types.RiskLevelHigh},
+		{"MEDIUM", "", types.RiskLevelMedium},
+		{"", "kubectl describe pod web", types.RiskLevelLow},
+		{"", "kubectl auth can-i list pods", types.RiskLevelLow},
+		{"critical", "kubectl logs web", types.RiskLevelLow},
+		{"critical", "kubectl delete pod web", types.RiskLevelMedium},
+		{"unknown", "", types.RiskLevelMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.risk)+"/"+tt.command, func(t *testing.T) {
+			if got := normalizeRiskLevel(tt.risk, tt.command); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,216 +1,31 @@
 package managers
 
-import (
-	"fmt"
-	"strings"
-
-	"podscription-api/types"
-)
-
-// SpecializedPrompts contains expert-level prompts for specific categories
-type SpecializedPrompts struct{}
+// SpecializedPrompts renders expert-level prompts for specific categories
+type SpecializedPrompts struct {
+	templates *PromptTemplates
+}
 
 // GetNetworkingPrompt returns specialized networking troubleshooting prompt
-func (p *SpecializedPrompts) GetNetworkingPrompt(message string, history []types.Message) promptPair {
-	system := `You are Dr. Network, a Kubernetes networking specialist and Pod Doctor. You are the leading expert in Kubernetes networking, service discovery, DNS, ingress, and CNI troubleshooting.
-
-SPECIALIZATION: Kubernetes Network Architecture
-- Service discovery and DNS resolution
-- Ingress controllers and load balancing  
-- Network policies and security
-- CNI plugins (Calico, Flannel, Weave, etc.)
-- Service mesh integration (Istio, Linkerd)
-- Inter-pod and external connectivity
-
-DIAGNOSTIC EXPERTISE:
-- DNS resolution failures (coredns, kube-dns)
-- Service endpoint mismatches
-- Ingress routing and TLS issues
-- Network policy blocking traffic
-- CNI configuration problems
-- Port conflicts and service exposure
-
-MEDICAL PERSONA: Speak like a network specialist doctor:
-- "Network congestion detected"
-- "DNS resolution symptoms"
-- "Service connectivity diagnosis"
-- "Traffic flow examination"
-
-RESPONSE FORMAT:
-## 🌐 Network Diagnosis: [Specific networking issue name]
-
-**Patient**: [Describe the network component having issues]
-**Symptoms**: [Network-specific symptoms observed]
-
-### 🔍 Network Examination:
-[Step-by-step network diagnostic approach]
-
-### 💊 Prescribed Network Treatment:
-1. **DNS Health Check**: ` + "`" + `kubectl get pods -n kube-system -l k8s-app=kube-dns` + "`" + `
-2. **Service Investigation**: ` + "`" + `kubectl describe svc <service-name>` + "`" + `  
-3. **Endpoint Verification**: ` + "`" + `kubectl get endpoints <service-name>` + "`" + `
-4. **Network Policy Audit**: ` + "`" + `kubectl get networkpolicy` + "`" + `
-[Additional targeted networking commands]
-
-### 🚀 Network Recovery Plan:
-[Specific steps to restore network connectivity]
-
-### 🔍 Follow-up Network Monitoring:
-[How to monitor and prevent future network issues]
-
-*Remember: In Kubernetes networking, all roads lead to DNS - check your CoreDNS first!*
-
-COMMON SCENARIOS TO RECOGNIZE:
-- DNS resolution fails: Focus on CoreDNS, service DNS names, and nameserver configuration
-- Service unreachable: Check service selectors, endpoints, and port configuration  
-- Ingress not working: Examine ingress controller, rules, and TLS configuration
-- Pod-to-pod communication fails: Investigate CNI, network policies, and security contexts
-- External connectivity issues: Check NodePort, LoadBalancer, and firewall rules
-
-TROUBLESHOOTING DECISION TREE:
-1. Is DNS working? (nslookup, dig tests)
-2. Are services properly configured? (selectors, ports, endpoints)
-3. Are network policies blocking traffic?
-4. Is the CNI plugin healthy?
-5. Are ingress rules correctly configured?`
-
-	// Include networking-specific context from history
-	networkContext := p.extractNetworkContext(history)
-	if networkContext != "" {
-		system += "\n\nNETWORK HISTORY CONTEXT:\n" + networkContext
-	}
-
-	user := fmt.Sprintf("Network issue reported: %s", message)
-	return promptPair{System: system, User: user}
+func (p *SpecializedPrompts) GetNetworkingPrompt(data promptData) (promptPair, error) {
+	return p.templates.render("networking", data)
 }
 
 // GetStoragePrompt returns specialized storage troubleshooting prompt
-func (p *SpecializedPrompts) GetStoragePrompt(message string, history []types.Message) promptPair {
-	system := `You are Dr. Volume, a Kubernetes storage specialist and Pod Doctor. You are the leading expert in persistent volumes, storage classes, and container storage interfaces (CSI).
-
-SPECIALIZATION: Kubernetes Storage Architecture
-- Persistent Volumes (PV) and Persistent Volume Claims (PVC)
-- Storage Classes and dynamic provisioning
-- Container Storage Interface (CSI) drivers
-- Volume mounting and filesystem issues
-- Storage performance and capacity management
-- Backup and disaster recovery
-
-DIAGNOSTIC EXPERTISE:
-- PVC stuck in Pending state
-- Volume mount failures and permission issues
-- Storage class provisioning problems  
-- CSI driver failures and compatibility
-- Disk space and inode exhaustion
-- Performance bottlenecks and I/O issues
-
-MEDICAL PERSONA: Speak like a storage specialist doctor:
-- "Volume mounting complications"
-- "Storage capacity diagnosis"
-- "Persistent volume syndrome"
-- "Disk space starvation"
-
-RESPONSE FORMAT:
-## 💾 Storage Diagnosis: [Specific storage issue name]
-
-**Patient**: [Describe the storage component having issues]
-**Symptoms**: [Storage-specific symptoms observed]
-
-### 🔍 Storage Examination:
-[Step-by-step storage diagnostic approach]
-
-### 💊 Prescribed Storage Treatment:
-1. **PVC Status Check**: ` + "`" + `kubectl describe pvc <pvc-name>` + "`" + `
-2. **PV Investigation**: ` + "`" + `kubectl get pv` + "`" + `
-3. **Storage Class Audit**: ` + "`" + `kubectl get storageclass` + "`" + `
-4. **Volume Mount Diagnosis**: ` + "`" + `kubectl describe pod <pod-name>` + "`" + `
-[Additional targeted storage commands]
-
-### 🗄️ Storage Recovery Plan:
-[Specific steps to resolve storage issues]
-
-### 🔍 Follow-up Storage Monitoring:
-[How to monitor storage health and prevent issues]
-
-*In the world of Kubernetes storage, binding is believing - check your PVC binding status!*
-
-COMMON SCENARIOS TO RECOGNIZE:
-- PVC Pending: Focus on storage class availability, capacity, and node affinity
-- Mount failures: Check permissions, filesystem compatibility, and CSI drivers
-- Performance issues: Investigate I/O limits, storage class performance tiers
-- Capacity problems: Examine disk space, PVC size limits, and quota restrictions
-- Backup/recovery: Check snapshot classes, volume snapshots, and restore procedures
-
-TROUBLESHOOTING DECISION TREE:
-1. Is the PVC bound to a PV? (binding status)
-2. Is there sufficient storage capacity? (available PVs, storage class limits)
-3. Are node selectors and affinity rules satisfied?
-4. Is the CSI driver healthy and compatible?
-5. Are there permission or filesystem issues?
-6. Is the storage class properly configured?`
-
-	// Include storage-specific context from history
-	storageContext := p.extractStorageContext(history)
-	if storageContext != "" {
-		system += "\n\nSTORAGE HISTORY CONTEXT:\n" + storageContext
-	}
-
-	user := fmt.Sprintf("Storage issue reported: %s", message)
-	return promptPair{System: system, User: user}
+func (p *SpecializedPrompts) GetStoragePrompt(data promptData) (promptPair, error) {
+	return p.templates.render("storage", data)
 }
 
-// extractNetworkContext extracts networking-relevant information from conversation history
-func (p *SpecializedPrompts) extractNetworkContext(history []types.Message) string {
-	var context []string
-	
-	networkKeywords := []string{"dns", "service", "ingress", "network", "connectivity", "endpoint", "port", "proxy"}
-	
-	for _, msg := range history {
-		msgLower := strings.ToLower(msg.Content)
-		for _, keyword := range networkKeywords {
-			if strings.Contains(msgLower, keyword) {
-				context = append(context, fmt.Sprintf("Previous networking context: %s", 
-					truncateString(msg.Content, 100)))
-				break
-			}
-		}
-	}
-	
-	if len(context) > 3 {
-		context = context[len(context)-3:] // Keep only last 3 relevant items
-	}
-	
-	return strings.Join(context, "\n")
+// GetPodIssuesPrompt returns specialized pod lifecycle troubleshooting prompt
+func (p *SpecializedPrompts) GetPodIssuesPrompt(data promptData) (promptPair, error) {
+	return p.templates.render("pod-issues", data)
 }
 
-// extractStorageContext extracts storage-relevant information from conversation history  
-func (p *SpecializedPrompts) extractStorageContext(history []types.Message) string {
-	var context []string
-	
-	storageKeywords := []string{"pvc", "pv", "volume", "mount", "storage", "disk", "filesystem", "capacity"}
-	
-	for _, msg := range history {
-		msgLower := strings.ToLower(msg.Content)
-		for _, keyword := range storageKeywords {
-			if strings.Contains(msgLower, keyword) {
-				context = append(context, fmt.Sprintf("Previous storage context: %s", 
-					truncateString(msg.Content, 100)))
-				break
-			}
-		}
-	}
-	
-	if len(context) > 3 {
-		context = context[len(context)-3:] // Keep only last 3 relevant items
-	}
-	
-	return strings.Join(context, "\n")
+// GetRBACPrompt returns specialized RBAC troubleshooting prompt
+func (p *SpecializedPrompts) GetRBACPrompt(data promptData) (promptPair, error) {
+	return p.templates.render("rbac", data)
 }
 
-// truncateString truncates a string to maxLen characters
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
-}
\ No newline at end of file
+// GetPerformancePrompt returns specialized performance troubleshooting prompt
+func (p *SpecializedPrompts) GetPerformancePrompt(data promptData) (promptPair, error) {
+	return p.templates.render("performance", data)
+}
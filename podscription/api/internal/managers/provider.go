@@ -0,0 +1,76 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+
+	"podscription-api/pkg/config"
+	"podscription-api/types"
+)
+
+// Provider defines the interface for language model backends used by the Pod Doctor
+type Provider interface {
+	// ClassifyIntent analyzes a user message to determine the Kubernetes troubleshooting category
+	ClassifyIntent(ctx context.Context, message string) (*types.PodIntent, error)
+	// GenerateDiagnosis creates a prescription and the full response text for a user message
+	GenerateDiagnosis(ctx context.Context, req DiagnosisRequest) (*types.Prescription, string, error)
+	// StreamDiagnosis behaves like GenerateDiagnosis but reports the response text as it is generated
+	StreamDiagnosis(ctx context.Context, req DiagnosisRequest, onDelta DeltaFunc) (*types.Prescription, string, error)
+	// PromptVersion identifies the prompt templates used to build requests
+	PromptVersion() string
+	// Model names the model that answers requests
+	Model() string
+}
+
+// DiagnosisRequest holds the message to diagnose and the context gathered for it
+type DiagnosisRequest struct {
+	Message string
+	Intent  *types.PodIntent
+	// History holds the recent turns that fit the model's context, oldest first
+	History []types.Message
+	// Summary condenses the turns before History
+	Summary *types.ConversationSummary
+	// Attachments are the outputs attached to the message, with their extracted facts
+	Attachments []types.Attachment
+	// ClusterContext is a compact summary of live cluster state related to the message
+	ClusterContext string
+	// BundleContext is an excerpt of the session's support bundle relevant to the message
+	BundleContext string
+	// RelatedCases are similar past sessions and the diagnoses they reached
+	RelatedCases []types.RelatedCase
+	// Knowledge are runbook excerpts the diagnosis should cite by their Ref
+	Knowledge []types.KnowledgeSource
+	// StepReports are the prescribed steps the engineer reported on since the last answer
+	StepReports []types.PlanStep
+}
+
+// DeltaFunc receives incremental chunks of a streamed model response
+type DeltaFunc func(delta string)
+
+// NewProvider creates the LLM provider selected by the configuration
+func NewProvider(cfg config.LLM, templates *PromptTemplates) (Provider, error) {
+	switch cfg.Provider {
+	case config.ProviderOpenAI, config.ProviderAzureOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("an API key is required for the %s provider", cfg.Provider)
+		}
+		if cfg.Provider == config.ProviderAzureOpenAI && cfg.BaseURL == "" {
+			return nil, fmt.Errorf("a base URL is required for the %s provider", cfg.Provider)
+		}
+		return NewOpenAIManager(cfg, templates), nil
+	case config.ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("a base URL is required for the %s provider", cfg.Provider)
+		}
+		return NewOpenAIManager(cfg, templates), nil
+	case config.ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("an API key is required for the %s provider", cfg.Provider)
+		}
+		return NewAnthropicManager(cfg, templates), nil
+	case config.ProviderFake:
+		return NewFakeProvider(cfg.FixturesPath, templates)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+}
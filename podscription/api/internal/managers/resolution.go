@@ -0,0 +1,149 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/types"
+)
+
+// ErrPrescriptionNotFound is returned when a resolution names a message of the session without a prescription
+var ErrPrescriptionNotFound = errors.New("prescription not found")
+
+// SetResolution resolves, abandons or reopens a session. fixedBy names the assistant message whose
+// prescription fixed the problem, and its diagnosis is the root cause unless one is given.
+func (m *SessionManager) SetResolution(sessionID uuid.UUID, status types.ResolutionStatus, rootCause string, fixedBy *uuid.UUID) (*types.Resolution, error) {
+	session, err := m.store.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	resolution := &types.Resolution{Status: status, RootCause: rootCause, FixedBy: fixedBy}
+	if fixedBy != nil {
+		var prescription *types.Prescription
+		for _, message := range session.Messages {
+			if message.ID == *fixedBy && message.Prescription != nil {
+				prescription = message.Prescription
+				break
+			}
+		}
+		if prescription == nil {
+			return nil, fmt.Errorf("%w: %s", ErrPrescriptionNotFound, *fixedBy)
+		}
+		if resolution.RootCause == "" {
+			resolution.RootCause = prescription.Diagnosis
+		}
+	}
+
+	// Close the session against the stored resolution, which may have changed since the read
+	resolution, err = m.store.UpdateResolution(sessionID, func(previous *types.Resolution) (*types.Resolution, error) {
+		if status != types.ResolutionStatusOpen {
+			// Editing a closed session keeps the time it was closed
+			closedAt := time.Now()
+			if previous != nil && previous.Status == status && previous.ClosedAt != nil {
+				closedAt = *previous.ClosedAt
+			}
+			resolution.ClosedAt = &closedAt
+			if status == types.ResolutionStatusResolved {
+				resolution.TimeToResolution = math.Round(closedAt.Sub(consultationStart(session)).Seconds())
+			}
+		}
+		return resolution, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	session.Resolution = resolution
+	m.indexCase(session)
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id":         sessionID,
+		"status":             status,
+		"fixed_by":           fixedBy,
+		"time_to_resolution": resolution.TimeToResolution,
+	}).Info("updated session resolution")
+
+	return resolution, nil
+}
+
+// ResolutionStats aggregates the outcomes of the stored sessions by the intent category of
+// their first message. Sessions without messages are left out.
+func (m *SessionManager) ResolutionStats() (*types.ResolutionStatsResponse, error) {
+	tallies, err := m.store.ResolutionTallies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally resolutions: %w", err)
+	}
+
+	type tally struct {
+		stats     types.ResolutionStats
+		durations []float64
+	}
+	categories := make(map[types.IntentCategory]*tally)
+	total := &tally{}
+	for _, counted := range tallies {
+		if categories[counted.Category] == nil {
+			categories[counted.Category] = &tally{stats: types.ResolutionStats{Category: counted.Category}}
+		}
+
+		for _, t := range []*tally{categories[counted.Category], total} {
+			t.stats.Sessions += counted.Sessions
+			switch counted.Status {
+			case types.ResolutionStatusResolved:
+				t.stats.Resolved += counted.Sessions
+				t.durations = append(t.durations, counted.TimesToResolution...)
+			case types.ResolutionStatusAbandoned:
+				t.stats.Abandoned += counted.Sessions
+			default:
+				t.stats.Open += counted.Sessions
+			}
+		}
+	}
+
+	response := &types.ResolutionStatsResponse{
+		Categories: make([]types.ResolutionStats, 0, len(categories)),
+		Total:      summarizeResolutions(total.stats, total.durations),
+	}
+	for _, category := range types.IntentCategories {
+		if t, ok := categories[category]; ok {
+			response.Categories = append(response.Categories, summarizeResolutions(t.stats, t.durations))
+		}
+	}
+	return response, nil
+}
+
+// summarizeResolutions fills in the rate and times of resolution of a tally
+func summarizeResolutions(stats types.ResolutionStats, durations []float64) types.ResolutionStats {
+	if closed := stats.Resolved + stats.Abandoned; closed > 0 {
+		stats.ResolutionRate = math.Round(float64(stats.Resolved)/float64(closed)*1000) / 1000
+	}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	slices.Sort(durations)
+	var sum float64
+	for _, duration := range durations {
+		sum += duration
+	}
+	stats.MeanTimeToResolution = math.Round(sum / float64(len(durations)))
+	middle := len(durations) / 2
+	if len(durations)%2 == 0 {
+		stats.MedianTimeToResolution = math.Round((durations[middle-1] + durations[middle]) / 2)
+	} else {
+		stats.MedianTimeToResolution = durations[middle]
+	}
+	return stats
+}
+
+// consultationStart is when a session's first message was sent, or when it was created
+func consultationStart(session *types.Session) time.Time {
+	if len(session.Messages) > 0 && !session.Messages[0].Timestamp.IsZero() {
+		return session.Messages[0].Timestamp
+	}
+	return session.CreatedAt
+}
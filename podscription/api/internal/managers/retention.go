@@ -0,0 +1,78 @@
+package managers
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/store"
+)
+
+// RetentionPolicy decides what happens to sessions nobody has touched for a while
+type RetentionPolicy struct {
+	// MaxAge is how long after its last update a session is kept active
+	MaxAge time.Duration
+	// Purge deletes expired sessions instead of archiving them
+	Purge bool
+	// Interval is how often sessions are checked
+	Interval time.Duration
+}
+
+// RunRetention applies policy at start and then every policy.Interval until ctx is done
+func (m *SessionManager) RunRetention(ctx context.Context, policy RetentionPolicy) {
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.applyRetention(policy)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyRetention archives or deletes the sessions last updated before policy.MaxAge ago.
+// Failures are logged and retried on the next run.
+func (m *SessionManager) applyRetention(policy RetentionPolicy) {
+	// Archived sessions are expired again only to be purged
+	ids, err := m.store.ExpiredSessions(time.Now().Add(-policy.MaxAge), policy.Purge)
+	if err != nil {
+		m.logger.WithError(err).Error("failed to list expired sessions for retention")
+		return
+	}
+
+	expired, failed := 0, 0
+	for _, id := range ids {
+		if policy.Purge {
+			// Delete through the manager, which also removes the session's support bundle and drops it
+			// from the related cases
+			err = m.DeleteSession(id)
+		} else {
+			// UpdateSession refreshes UpdatedAt, so the archive time restarts the clock for a later purge
+			archived := true
+			err = m.store.UpdateSession(id, store.SessionUpdate{Archived: &archived})
+		}
+		if err != nil {
+			m.logger.WithError(err).WithField("session_id", id).Error("failed to apply retention to session")
+			failed++
+			continue
+		}
+		expired++
+	}
+
+	if expired > 0 || failed > 0 {
+		action := "archived"
+		if policy.Purge {
+			action = "deleted"
+		}
+		m.logger.WithFields(logrus.Fields{
+			"action":   action,
+			"sessions": expired,
+			"failed":   failed,
+			"max_age":  policy.MaxAge.String(),
+		}).Info("applied session retention policy")
+	}
+}
@@ -0,0 +1,100 @@
+package managers
+
+import (
+	"regexp"
+	"sort"
+
+	"podscription-api/types"
+)
+
+// classificationRule maps a well-known Kubernetes error signature onto a category
+type classificationRule struct {
+	Name       string
+	Category   types.IntentCategory
+	Confidence float64
+	Symptom    string
+	Pattern    *regexp.Regexp
+}
+
+// defaultClassificationRules cover error strings and event reasons that identify a category on their own
+var defaultClassificationRules = []classificationRule{
+	// Pod lifecycle
+	{"crashloop", types.IntentCategoryPodIssues, 0.95, "CrashLoopBackOff", regexp.MustCompile(`(?i)crash\s*loop\s*back\s*-?off|back-off restarting failed container`)},
+	{"image-pull", types.IntentCategoryPodIssues, 0.95, "ImagePullBackOff", regexp.MustCompile(`(?i)image\s*pull\s*back\s*-?off|err\s*image\s*pull|invalidimagename|failed to pull image`)},
+	{"oom-killed", types.IntentCategoryPodIssues, 0.9, "OOMKilled", regexp.MustCompile(`(?i)oom\s*-?killed|exit code:?\s*137|out of memory`)},
+	{"container-config", types.IntentCategoryPodIssues, 0.9, "CreateContainerConfigError", regexp.MustCompile(`(?i)createcontainerconfigerror|createcontainererror|runcontainererror`)},
+	{"probe-failure", types.IntentCategoryPodIssues, 0.85, "probe failures", regexp.MustCompile(`(?i)(liveness|readiness|startup) probe failed`)},
+	{"failed-scheduling", types.IntentCategoryPodIssues, 0.85, "FailedScheduling", regexp.MustCompile(`(?i)failedscheduling|\d+/\d+ nodes are available|didn't match pod's node affinity|untolerated taint`)},
+
+	// Storage
+	{"pvc-pending", types.IntentCategoryStorage, 0.95, "PVC stuck Pending", regexp.MustCompile(`(?i)\b(pvc|persistentvolumeclaim|claim)\b.{0,40}\bpending\b|pending.{0,40}\b(pvc|persistentvolumeclaim)\b`)},
+	{"failed-mount", types.IntentCategoryStorage, 0.95, "FailedMount", regexp.MustCompile(`(?i)failedmount|failedattachvolume|mountvolume\.(setup|mountdevice) failed|unable to attach or mount volumes|multi-attach error`)},
+	{"provisioning", types.IntentCategoryStorage, 0.9, "ProvisioningFailed", regexp.MustCompile(`(?i)provisioningfailed|waiting for first consumer|no persistent volumes available|storageclass\.storage\.k8s\.io "[^"]*" not found`)},
+
+	// Networking
+	{"dns", types.IntentCategoryNetworking, 0.9, "DNS resolution failure", regexp.MustCompile(`(?i)nxdomain|servfail|no such host|could not resolve host|temporary failure in name resolution`)},
+	{"no-endpoints", types.IntentCategoryNetworking, 0.9, "service has no endpoints", regexp.MustCompile(`(?i)no endpoints available for service|endpoints "[^"]*" not found`)},
+	{"connection", types.IntentCategoryNetworking, 0.75, "connection failures", regexp.MustCompile(`(?i)connection refused|connection timed out|dial tcp .*: i/o timeout|502 bad gateway|503 service (temporarily )?unavailable`)},
+
+	// RBAC
+	{"forbidden", types.IntentCategoryRBAC, 0.95, "forbidden API request", regexp.MustCompile(`(?i)is forbidden: user "[^"]*" cannot|cannot (get|list|watch|create|update|patch|delete|deletecollection) resource`)},
+	{"unauthorized", types.IntentCategoryRBAC, 0.8, "unauthorized request", regexp.MustCompile(`(?i)\bunauthorized\b|you must be logged in to the server`)},
+	{"pod-security", types.IntentCategoryRBAC, 0.9, "PodSecurity violation", regexp.MustCompile(`(?i)violates podsecurity|pod security admission`)},
+
+	// Performance
+	{"throttling", types.IntentCategoryPerformance, 0.9, "CPU throttling", regexp.MustCompile(`(?i)cputhrottlinghigh|cpu throttl`)},
+	{"hpa-metrics", types.IntentCategoryPerformance, 0.9, "HPA cannot read metrics", regexp.MustCompile(`(?i)failedgetresourcemetric|failedcomputemetricsreplicas|unable to get metrics for resource|<unknown>/\d+%`)},
+	{"node-pressure", types.IntentCategoryPerformance, 0.9, "node pressure eviction", regexp.MustCompile(`(?i)(memory|disk|pid)pressure|the node was low on resource|\bevicted\b`)},
+	{"insufficient-resources", types.IntentCategoryPerformance, 0.7, "insufficient cluster resources", regexp.MustCompile(`(?i)insufficient (cpu|memory|ephemeral-storage)`)},
+}
+
+// RuleClassifier classifies messages locally using known Kubernetes error signatures
+type RuleClassifier struct {
+	rules         []classificationRule
+	minConfidence float64
+}
+
+// NewRuleClassifier creates a rule classifier that is trusted on its own when the
+// best matching rule reaches minConfidence
+func NewRuleClassifier(minConfidence float64) *RuleClassifier {
+	return &RuleClassifier{
+		rules:         defaultClassificationRules,
+		minConfidence: minConfidence,
+	}
+}
+
+// Classify matches the message against every rule. It returns the resulting intent,
+// or nil when no rule matched, and whether the match is confident enough to skip the LLM.
+func (c *RuleClassifier) Classify(message string) (*types.PodIntent, bool) {
+	scores := make(map[types.IntentCategory]float64)
+	var symptoms []string
+	var matches []classificationRule
+
+	for _, rule := range c.rules {
+		if !rule.Pattern.MatchString(message) {
+			continue
+		}
+		matches = append(matches, rule)
+		if rule.Confidence > scores[rule.Category] {
+			scores[rule.Category] = rule.Confidence
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	// Report the most telling symptoms first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	for _, rule := range matches {
+		symptoms = append(symptoms, rule.Symptom)
+	}
+
+	intent := newScoredIntent(scores)
+	intent.Symptoms = symptoms
+	intent.Source = types.IntentSourceRules
+
+	return intent, intent.Confidence >= c.minConfidence
+}
@@ -0,0 +1,130 @@
+package managers
+
+import (
+	"slices"
+	"testing"
+
+	"podscription-api/types"
+)
+
+func TestRuleClassifierRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		message  string
+		category types.IntentCategory
+	}{
+		{"crashloop", "pod web-1 is in CrashLoopBackOff", types.IntentCategoryPodIssues},
+		{"image-pull", "Failed to pull image \"nginx:latestt\"", types.IntentCategoryPodIssues},
+		{"oom-killed", "Last State: Terminated, Reason: OOMKilled", types.IntentCategoryPodIssues},
+		{"container-config", "Error: CreateContainerConfigError", types.IntentCategoryPodIssues},
+		{"probe-failure", "Warning Unhealthy: Readiness probe failed: HTTP 500", types.IntentCategoryPodIssues},
+		{"failed-scheduling", "0/3 nodes are available: 3 node(s) had untolerated taint", types.IntentCategoryPodIssues},
+		{"pvc-pending", "my PVC data-db-0 has been Pending for an hour", types.IntentCategoryStorage},
+		{"failed-mount", "Warning FailedMount: Unable to attach or mount volumes", types.IntentCategoryStorage},
+		{"provisioning", "waiting for first consumer to be created before binding", types.IntentCategoryStorage},
+		{"dns", "dial tcp: lookup api.shop.svc: no such host", types.IntentCategoryNetworking},
+		{"no-endpoints", "no endpoints available for service \"web\"", types.IntentCategoryNetworking},
+		{"connection", "curl: connection refused on port 8080", types.IntentCategoryNetworking},
+		{"forbidden", `pods is forbidden: User "ci" cannot list resource "pods"`, types.IntentCategoryRBAC},
+		{"unauthorized", "error: You must be logged in to the server (Unauthorized)", types.IntentCategoryRBAC},
+		{"pod-security", "pods \"web\" is forbidden: violates PodSecurity \"restricted:latest\"", types.IntentCategoryRBAC},
+		{"throttling", "alert CPUThrottlingHigh firing for web", types.IntentCategoryPerformance},
+		{"hpa-metrics", "HPA shows <unknown>/80% as target", types.IntentCategoryPerformance},
+		{"node-pressure", "The node was low on resource: memory", types.IntentCategoryPerformance},
+		{"insufficient-resources", "0/3 nodes: Insufficient cpu", types.IntentCategoryPerformance},
+	}
+
+	if len(tests) != len(defaultClassificationRules) {
+		t.Fatalf("got %d test cases for %d rules, want one per rule", len(tests), len(defaultClassificationRules))
+	}
+
+	classifier := NewRuleClassifier(0.85)
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule := findClassificationRule(t, tt.rule)
+			if !rule.Pattern.MatchString(tt.message) {
+				t.Fatalf("rule %s does not match %q", tt.rule, tt.message)
+			}
+			if rule.Category != tt.category {
+				t.Fatalf("rule %s classifies as %s, want %s", tt.rule, rule.Category, tt.category)
+			}
+
+			intent, _ := classifier.Classify(tt.message)
+			if intent == nil {
+				t.Fatalf("got no intent for %q", tt.message)
+			}
+			if intent.Source != types.IntentSourceRules {
+				t.Fatalf("got source %s, want rules", intent.Source)
+			}
+			if !slices.Contains(intent.Symptoms, rule.Symptom) {
+				t.Fatalf("got symptoms %q, want %q", intent.Symptoms, rule.Symptom)
+			}
+		})
+	}
+}
+
+func TestRuleClassifierConfidence(t *testing.T) {
+	tests := []struct {
+		name          string
+		minConfidence float64
+		message       string
+		category      types.IntentCategory
+		confident     bool
+	}{
+		{"above the threshold", 0.85, "Back-off restarting failed container", types.IntentCategoryPodIssues, true},
+		{"at the threshold", 0.85, "Liveness probe failed: connection reset", types.IntentCategoryPodIssues, true},
+		{"below the threshold", 0.85, "connection timed out talking to redis", types.IntentCategoryNetworking, false},
+		{"weak rule only", 0.85, "Insufficient memory on every node", types.IntentCategoryPerformance, false},
+		{"raised threshold", 0.95, "NXDOMAIN for db.prod.svc", types.IntentCategoryNetworking, false},
+		{"lowered threshold", 0.7, "Insufficient memory on every node", types.IntentCategoryPerformance, true},
+		{"strongest rule wins", 0.85, "connection refused after OOMKilled", types.IntentCategoryPodIssues, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent, confident := NewRuleClassifier(tt.minConfidence).Classify(tt.message)
+			if intent == nil {
+				t.Fatalf("got no intent for %q", tt.message)
+			}
+			if intent.Category != tt.category || confident != tt.confident {
+				t.Fatalf("got %s (confident %v, %.2f), want %s (confident %v)", intent.Category, confident, intent.Confidence, tt.category, tt.confident)
+			}
+		})
+	}
+}
+
+func TestRuleClassifierMultipleMatches(t *testing.T) {
+	intent, _ := NewRuleClassifier(0.85).Classify("Insufficient cpu, then the pod went into CrashLoopBackOff")
+	if intent == nil || len(intent.Categories) != 2 {
+		t.Fatalf("got intent %+v, want two categories", intent)
+	}
+	if intent.Symptoms[0] != "CrashLoopBackOff" {
+		t.Fatalf("got symptoms %q, want the most telling one first", intent.Symptoms)
+	}
+}
+
+func TestRuleClassifierNoMatch(t *testing.T) {
+	for _, message := range []string{
+		"how do I write a helm chart?",
+		"the ingress seems a bit slow today",
+		"is a claim for 10Gi enough for postgres?",
+	} {
+		intent, confident := NewRuleClassifier(0.85).Classify(message)
+		if intent != nil || confident {
+			t.Errorf("Classify(%q) = %+v, %v, want no match", message, intent, confident)
+		}
+	}
+}
+
+// findClassificationRule returns the default rule with the given name
+func findClassificationRule(t *testing.T, name string) classificationRule {
+	t.Helper()
+
+	for _, rule := range defaultClassificationRules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	t.Fatalf("no classification rule named %s", name)
+	return classificationRule{}
+}
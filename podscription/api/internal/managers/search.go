@@ -0,0 +1,23 @@
+package managers
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/store"
+	"podscription-api/types"
+)
+
+// Search finds up to limit messages across all sessions matching text, best first
+func (m *SessionManager) Search(text string, limit int) ([]types.SearchHit, error) {
+	hits, err := m.store.Search(store.SearchQuery{Text: text, Limit: limit})
+	if err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"query": text,
+			"error": err,
+		}).Error("failed to search sessions")
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+
+	return hits, nil
+}
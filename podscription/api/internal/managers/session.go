@@ -3,27 +3,98 @@ package managers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	attachmentparser "podscription-api/internal/attachments"
+	"podscription-api/internal/bundles"
+	"podscription-api/internal/cases"
+	"podscription-api/internal/cluster"
+	"podscription-api/internal/knowledge"
 	"podscription-api/internal/store"
 	"podscription-api/types"
 )
 
 // SessionManager handles session-related operations
 type SessionManager struct {
-	store       store.Store
-	openAI      *OpenAIManager
-	logger      *logrus.Logger
+	store     store.Store
+	provider  Provider
+	logger    *logrus.Logger
+	rules     *RuleClassifier
+	analyzers *OfflineAnalyzers
+	collector *cluster.Collector
+	// collectTimeout bounds how long gathering cluster state may delay a diagnosis
+	collectTimeout time.Duration
+	bundles        *bundles.Registry
+	// ingestSlots holds a token per support bundle being ingested
+	ingestSlots chan struct{}
+	// cases indexes past sessions to show similar ones with new diagnoses
+	cases             *cases.Index
+	caseLimit         int
+	caseMinSimilarity float64
+	// knowledge holds the runbooks cited by diagnoses
+	knowledge      *knowledge.Base
+	knowledgeLimit int
+	// contextBuilder fits conversations into the model's context window
+	contextBuilder *ContextBuilder
+}
+
+// SessionManagerOption configures optional session manager capabilities
+type SessionManagerOption func(*SessionManager)
+
+// WithRuleClassifier classifies messages with local rules before asking the LLM
+func WithRuleClassifier(rules *RuleClassifier) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.rules = rules
+	}
+}
+
+// WithOfflineAnalyzers answers well-known failures with built-in prescriptions when the LLM fails
+func WithOfflineAnalyzers(analyzers *OfflineAnalyzers) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.analyzers = analyzers
+	}
+}
+
+// WithClusterCollector adds read-only state of the objects mentioned in a conversation to diagnoses
+func WithClusterCollector(collector *cluster.Collector, timeout time.Duration) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.collector = collector
+		m.collectTimeout = timeout
+	}
+}
+
+// WithBundles grounds the answers of sessions opened from an uploaded support bundle in its contents
+func WithBundles(registry *bundles.Registry) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.bundles = registry
+		m.ingestSlots = make(chan struct{}, maxConcurrentIngests)
+	}
+}
+
+// WithContextBuilder sizes the conversation history shown to the model for its context window
+func WithContextBuilder(builder *ContextBuilder) SessionManagerOption {
+	return func(m *SessionManager) {
+		m.contextBuilder = builder
+	}
 }
 
 // NewSessionManager creates a new session manager
-func NewSessionManager(store store.Store, openAI *OpenAIManager, logger *logrus.Logger) *SessionManager {
-	return &SessionManager{
-		store:  store,
-		openAI: openAI,
-		logger: logger,
+func NewSessionManager(store store.Store, provider Provider, logger *logrus.Logger, opts ...SessionManagerOption) *SessionManager {
+	m := &SessionManager{
+		store:          store,
+		provider:       provider,
+		logger:         logger,
+		contextBuilder: NewContextBuilder("", 0, 0, 0),
+	}
+
+	for _, opt := range opts {
+		opt(m)
 	}
+
+	return m
 }
 
 // CreateSession creates a new chat session
@@ -56,102 +127,336 @@ func (m *SessionManager) GetSession(id uuid.UUID) (*types.Session, error) {
 	return session, nil
 }
 
-// ListSessions returns all sessions
-func (m *SessionManager) ListSessions() ([]*types.Session, error) {
-	sessions, err := m.store.ListSessions()
+// ListSessions returns a page of summaries of the sessions matching query, with the
+// cursor of the next page when there is one
+func (m *SessionManager) ListSessions(query store.SessionQuery) (*types.SessionPage, error) {
+	limit := query.Limit
+	// Ask for one more summary than requested to learn whether another page follows
+	query.Limit++
+	summaries, err := m.store.ListSessionSummaries(query)
 	if err != nil {
 		m.logger.WithError(err).Error("failed to list sessions")
 		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
 
-	return sessions, nil
+	page := &types.SessionPage{Sessions: summaries}
+	if len(summaries) > limit {
+		page.Sessions = summaries[:limit]
+		last := page.Sessions[limit-1]
+		page.NextCursor = store.Cursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.String()
+	}
+
+	return page, nil
+}
+
+// UpdateSession renames and/or archives a session; nil fields are left unchanged
+func (m *SessionManager) UpdateSession(id uuid.UUID, name *string, archived *bool) (*types.Session, error) {
+	if err := m.store.UpdateSession(id, store.SessionUpdate{Name: name, Archived: archived}); err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"session_id": id,
+			"error":      err,
+		}).Error("failed to update session")
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	session, err := m.store.GetSession(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	m.indexCase(session)
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id": id,
+		"name":       session.Name,
+		"archived":   session.Archived,
+	}).Info("updated session")
+
+	return session, nil
+}
+
+// DeleteSession permanently removes a session, its messages and the support bundle it was opened from
+func (m *SessionManager) DeleteSession(id uuid.UUID) error {
+	session, err := m.store.GetSession(id)
+	if err == nil {
+		err = m.store.DeleteSession(id)
+	}
+	if err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"session_id": id,
+			"error":      err,
+		}).Error("failed to delete session")
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	// A bundle belongs to the one session opened from it; a leftover index only costs disk space
+	if session.BundleID != nil && m.bundles != nil {
+		if err := m.bundles.Delete(*session.BundleID); err != nil {
+			m.logger.WithError(err).WithField("bundle_id", *session.BundleID).Warn("failed to delete support bundle")
+		}
+	}
+
+	if m.cases != nil {
+		m.cases.Remove(id)
+	}
+
+	m.logger.WithField("session_id", id).Info("deleted session")
+	return nil
+}
+
+// StreamCallbacks receive progress while a message is processed with ProcessMessageStream
+type StreamCallbacks struct {
+	OnIntent func(intent *types.PodIntent)
+	// OnDelta receives the readable text of the diagnosis, never the raw JSON it is decoded from
+	OnDelta DeltaFunc
 }
 
 // ProcessMessage processes a user message and generates an AI response
-func (m *SessionManager) ProcessMessage(ctx context.Context, sessionID uuid.UUID, content string) (*types.Session, *types.Message, error) {
+func (m *SessionManager) ProcessMessage(ctx context.Context, sessionID uuid.UUID, content string, attachments []types.Attachment) (*types.ChatResponse, error) {
+	return m.processMessage(ctx, sessionID, content, attachments, nil)
+}
+
+// ProcessMessageStream processes a user message like ProcessMessage, reporting the
+// classified intent and the diagnosis text through callbacks as they become available
+func (m *SessionManager) ProcessMessageStream(ctx context.Context, sessionID uuid.UUID, content string, attachments []types.Attachment, callbacks StreamCallbacks) (*types.ChatResponse, error) {
+	return m.processMessage(ctx, sessionID, content, attachments, &callbacks)
+}
+
+// processMessage runs the classify, diagnose and persist pipeline, streaming when callbacks are set
+func (m *SessionManager) processMessage(ctx context.Context, sessionID uuid.UUID, content string, attachments []types.Attachment, callbacks *StreamCallbacks) (*types.ChatResponse, error) {
 	// Get the session
 	session, err := m.store.GetSession(sessionID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("session not found: %w", err)
+		return nil, fmt.Errorf("session not found: %w", err)
+	}
+
+	// The output of treatment steps reported since the last answer comes with the message
+	reports, outputs := stepReports(session.Plan)
+	attachments = append(attachments, outputs...)
+
+	// Extract the facts from attached output once, they are stored with the message
+	for i := range attachments {
+		attachments[i].Facts = attachmentparser.Extract(attachments[i])
 	}
 
 	// Add the user message
 	userMessage := types.Message{
-		Role:    types.MessageRoleUser,
-		Content: content,
+		Role:        types.MessageRoleUser,
+		Content:     content,
+		Attachments: attachments,
 	}
 
 	if err := m.store.AddMessage(sessionID, userMessage); err != nil {
-		return nil, nil, fmt.Errorf("failed to add user message: %w", err)
+		return nil, fmt.Errorf("failed to add user message: %w", err)
 	}
 
 	m.logger.WithFields(logrus.Fields{
-		"session_id": sessionID,
+		"session_id":     sessionID,
 		"content_length": len(content),
+		"attachments":    len(attachments),
 	}).Info("processing user message")
 
-	// Classify the intent
-	intent, err := m.openAI.ClassifyIntent(ctx, content)
-	if err != nil {
-		m.logger.WithError(err).Error("failed to classify intent")
-		// Continue with a default intent rather than failing
-		intent = &types.PodIntent{
-			Category:   types.IntentCategoryGeneral,
-			Confidence: 0.5,
-			Symptoms:   []string{"unknown issue"},
-		}
+	// Local rules, the classifier and the offline analyzers also look at the attached facts
+	evidence := content
+	if summary := attachmentparser.Summarize(attachments); summary != "" {
+		evidence += "\n\n" + summary
 	}
 
+	// Classify the intent
+	intent := m.classifyIntent(ctx, evidence)
+
 	m.logger.WithFields(logrus.Fields{
-		"session_id": sessionID,
+		"session_id":      sessionID,
 		"intent_category": intent.Category,
-		"confidence": intent.Confidence,
+		"confidence":      intent.Confidence,
+		"intent_source":   intent.Source,
 	}).Info("classified user intent")
 
-	// Get recent message history for context
-	recentHistory := m.getRecentHistory(session.Messages, 5)
+	if callbacks != nil && callbacks.OnIntent != nil {
+		callbacks.OnIntent(intent)
+	}
+
+	// Keep the recent turns that fit the model's context, summarizing the ones before
+	conversation, summarized := m.contextBuilder.build(session)
+
+	// Recall how similar problems were diagnosed before
+	related := m.relatedCases(sessionID, evidence, intent)
+
+	// Look up what the team's runbooks say about it
+	sources := m.knowledgeSources(sessionID, evidence, intent)
 
 	// Generate the diagnosis
-	prescription, treatment, err := m.openAI.GenerateDiagnosis(ctx, content, intent, recentHistory)
+	var prescription *types.Prescription
+	var treatment string
+	req := DiagnosisRequest{
+		Message:      content,
+		Intent:       intent,
+		History:      conversation.Recent,
+		Summary:      conversation.Summary,
+		Attachments:  attachments,
+		RelatedCases: related,
+		Knowledge:    sources,
+		StepReports:  reports,
+	}
+	// A bundle session is about the snapshot, not whatever cluster the server can reach
+	if session.BundleID != nil {
+		req.BundleContext = m.bundleContext(sessionID, *session.BundleID, evidence)
+	} else {
+		req.ClusterContext = m.collectClusterContext(ctx, sessionID, evidence, conversation.Recent)
+	}
+	if callbacks != nil && callbacks.OnDelta != nil {
+		prescription, treatment, err = m.provider.StreamDiagnosis(ctx, req, newProseStream(callbacks.OnDelta).Write)
+	} else {
+		prescription, treatment, err = m.provider.GenerateDiagnosis(ctx, req)
+	}
+	promptVersion, model := m.provider.PromptVersion(), m.provider.Model()
+	analyzer := ""
 	if err != nil {
 		m.logger.WithError(err).Error("failed to generate diagnosis")
-		return nil, nil, fmt.Errorf("failed to generate diagnosis: %w", err)
+
+		// Fall back to a built-in prescription for well-known failures
+		var ok bool
+		if m.analyzers != nil {
+			prescription, treatment, analyzer, ok = m.analyzers.Analyze(evidence)
+		}
+		if !ok {
+			return nil, fmt.Errorf("failed to generate diagnosis: %w", err)
+		}
+		promptVersion, model = "", ""
+		// The offline analyzers never saw the runbooks
+		sources = nil
+
+		m.logger.WithFields(logrus.Fields{
+			"session_id": sessionID,
+			"analyzer":   analyzer,
+		}).Warn("answered with offline analyzer")
 	}
 
-	// Create the assistant message
+	// Create the assistant message, identified here so that it can be returned as stored
 	assistantMessage := types.Message{
-		Role:         types.MessageRoleAssistant,
-		Content:      treatment,
-		Intent:       intent,
-		Prescription: prescription,
+		ID:            uuid.New(),
+		Timestamp:     time.Now(),
+		Role:          types.MessageRoleAssistant,
+		Content:       treatment,
+		Intent:        intent,
+		Prescription:  prescription,
+		PromptVersion: promptVersion,
+		Model:         model,
+		Analyzer:      analyzer,
+		Sources:       sources,
 	}
 
 	// Add the assistant message
 	if err := m.store.AddMessage(sessionID, assistantMessage); err != nil {
-		return nil, nil, fmt.Errorf("failed to add assistant message: %w", err)
+		return nil, fmt.Errorf("failed to add assistant message: %w", err)
 	}
 
 	m.logger.WithFields(logrus.Fields{
-		"session_id": sessionID,
-		"diagnosis": prescription.Diagnosis,
+		"session_id":     sessionID,
+		"diagnosis":      prescription.Diagnosis,
 		"commands_count": len(prescription.Commands),
 	}).Info("generated diagnosis and response")
 
 	// Get the updated session
 	updatedSession, err := m.store.GetSession(sessionID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get updated session: %w", err)
+		return nil, fmt.Errorf("failed to get updated session: %w", err)
 	}
 
-	// Return the last message (the assistant's response)
-	lastMessage := updatedSession.Messages[len(updatedSession.Messages)-1]
-	return updatedSession, &lastMessage, nil
+	if summarized {
+		m.saveSummary(updatedSession, conversation.Summary)
+	}
+	m.savePlan(updatedSession, reports, assistantMessage)
+	m.indexCase(updatedSession)
+
+	return &types.ChatResponse{
+		Session:      *updatedSession,
+		Message:      assistantMessage,
+		RelatedCases: related,
+	}, nil
 }
 
-// getRecentHistory returns the most recent messages for context
-func (m *SessionManager) getRecentHistory(messages []types.Message, count int) []types.Message {
-	if len(messages) <= count {
-		return messages
+// classifyIntent classifies a message with the local rules when they are confident,
+// otherwise with the LLM, falling back to weaker rule matches or a general intent
+func (m *SessionManager) classifyIntent(ctx context.Context, content string) *types.PodIntent {
+	var ruleIntent *types.PodIntent
+	if m.rules != nil {
+		var confident bool
+		ruleIntent, confident = m.rules.Classify(content)
+		if confident {
+			return ruleIntent
+		}
 	}
-	return messages[len(messages)-count:]
-}
\ No newline at end of file
+
+	intent, err := m.provider.ClassifyIntent(ctx, content)
+	if err == nil {
+		intent.Source = types.IntentSourceLLM
+		return intent
+	}
+
+	m.logger.WithError(err).Error("failed to classify intent")
+
+	// Continue with the best local guess rather than failing
+	if ruleIntent != nil {
+		return ruleIntent
+	}
+
+	return &types.PodIntent{
+		Category:   types.IntentCategoryGeneral,
+		Confidence: 0.5,
+		Symptoms:   []string{"unknown issue"},
+		Source:     types.IntentSourceFallback,
+	}
+}
+
+// collectClusterContext summarizes the live state of the objects referenced in the message
+// or, failing that, in the user's recent messages. Failures only cost the diagnosis context.
+func (m *SessionManager) collectClusterContext(ctx context.Context, sessionID uuid.UUID, content string, history []types.Message) string {
+	if m.collector == nil {
+		return ""
+	}
+
+	refs := cluster.ParseReferences(content)
+	if len(refs) == 0 {
+		var previous []string
+		for _, msg := range history {
+			if msg.Role == types.MessageRoleUser {
+				previous = append(previous, msg.Content)
+			}
+		}
+		refs = cluster.ParseReferences(strings.Join(previous, "\n"))
+	}
+	if len(refs) == 0 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.collectTimeout)
+	defer cancel()
+
+	summary, err := m.collector.Summarize(ctx, refs)
+	if err != nil {
+		m.logger.WithError(err).WithField("session_id", sessionID).Warn("failed to collect cluster context")
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id": sessionID,
+		"references": len(refs),
+	}).Info("collected cluster context")
+
+	return summary
+}
+
+// saveSummary stores the rolling summary of a session's conversation. The diagnosis is already
+// saved, so failing only costs the next message a rebuild of the summary.
+func (m *SessionManager) saveSummary(session *types.Session, summary *types.ConversationSummary) {
+	if err := m.store.SetSummary(session.ID, summary); err != nil {
+		m.logger.WithError(err).WithField("session_id", session.ID).Warn("failed to save conversation summary")
+		return
+	}
+	session.Summary = summary
+
+	m.logger.WithFields(logrus.Fields{
+		"session_id":      session.ID,
+		"summary_through": summary.Through,
+	}).Info("updated conversation summary")
+}
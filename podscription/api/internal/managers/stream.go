@@ -0,0 +1,225 @@
+package managers
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// proseFields are the diagnosis payload fields holding text meant for the reader, in the
+// order the schema lists them; commands and risk levels only appear in the rendered answer
+var proseFields = map[string]bool{
+	"diagnosis":   true,
+	"explanation": true,
+	"treatment":   true,
+	"title":       true,
+	"rationale":   true,
+	"followUp":    true,
+	"closing":     true,
+}
+
+// proseSeparator separates the prose fields of a streamed diagnosis
+const proseSeparator = "\n\n"
+
+// proseStream turns the raw chunks of a streamed diagnosis into readable text. A structured
+// response only has the decoded values of its prose fields passed on, a free-form markdown
+// response is passed on as it is. Chunks may split tokens, escapes and characters anywhere.
+type proseStream struct {
+	onDelta DeltaFunc
+
+	// prefix holds the leading output until it shows whether the response is JSON
+	prefix  strings.Builder
+	decided bool
+	json    bool
+
+	// containers is the stack of open objects and arrays, '{' or '['
+	containers []byte
+	expectKey  bool
+	key        strings.Builder
+	lastKey    string
+	inString   bool
+	isKey      bool
+	prose      bool
+	escaped    bool
+	// unicode collects the hex digits of a \u escape, surrogate the first half of a pair
+	unicode   []byte
+	inUnicode bool
+	surrogate rune
+	values    int
+
+	// out collects the text of the current chunk, pending an incomplete trailing character
+	out []byte
+}
+
+// newProseStream reports the readable text of a streamed diagnosis to onDelta
+func newProseStream(onDelta DeltaFunc) *proseStream {
+	return &proseStream{onDelta: onDelta}
+}
+
+// Write consumes a raw chunk of the model response
+func (s *proseStream) Write(chunk string) {
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+		switch {
+		case !s.decided:
+			s.prefix.WriteByte(c)
+			s.decide()
+		case s.json:
+			s.consume(c)
+		default:
+			s.out = append(s.out, c)
+		}
+	}
+	s.flush()
+}
+
+// decide looks at the leading output, which may open a markdown code fence, for the
+// start of a JSON object
+func (s *proseStream) decide() {
+	prefix := strings.TrimLeft(s.prefix.String(), " \t\r\n")
+	if strings.HasPrefix(prefix, "```") {
+		newline := strings.IndexByte(prefix, '\n')
+		if newline == -1 {
+			return
+		}
+		prefix = strings.TrimLeft(prefix[newline+1:], " \t\r\n")
+	} else if strings.HasPrefix("```", prefix) {
+		return
+	}
+	if prefix == "" {
+		return
+	}
+
+	s.decided = true
+	if prefix == "{" {
+		s.json = true
+		s.consume('{')
+		return
+	}
+	s.out = append(s.out, s.prefix.String()...)
+}
+
+// consume advances the JSON scanner by one byte, collecting the prose it decodes
+func (s *proseStream) consume(c byte) {
+	if s.inString {
+		s.consumeString(c)
+		return
+	}
+
+	switch c {
+	case '"':
+		s.inString = true
+		s.isKey = s.expectKey
+		s.key.Reset()
+		s.prose = !s.isKey && s.inObject() && proseFields[s.lastKey]
+		if s.prose && s.values > 0 {
+			s.out = append(s.out, proseSeparator...)
+		}
+	case '{':
+		s.containers = append(s.containers, c)
+		s.expectKey = true
+	case '[':
+		s.containers = append(s.containers, c)
+		s.expectKey = false
+	case '}', ']':
+		if len(s.containers) > 0 {
+			s.containers = s.containers[:len(s.containers)-1]
+		}
+		s.expectKey = false
+	case ',':
+		s.expectKey = s.inObject()
+	case ':':
+		s.expectKey = false
+	}
+}
+
+// consumeString advances the scanner through a string, decoding escapes in prose values
+func (s *proseStream) consumeString(c byte) {
+	switch {
+	case s.inUnicode:
+		s.unicode = append(s.unicode, c)
+		if len(s.unicode) == 4 {
+			s.inUnicode = false
+			code, err := strconv.ParseUint(string(s.unicode), 16, 16)
+			if err == nil {
+				s.writeRune(rune(code))
+			}
+		}
+		return
+	case s.escaped:
+		s.escaped = false
+		switch c {
+		case 'u':
+			s.inUnicode = true
+			s.unicode = s.unicode[:0]
+		case 'n':
+			s.writeRune('\n')
+		case 't':
+			s.writeRune('\t')
+		case 'r', 'b', 'f':
+		default:
+			s.writeRune(rune(c))
+		}
+		return
+	case c == '\\':
+		s.escaped = true
+		return
+	case c == '"':
+		s.inString = false
+		if s.isKey {
+			s.lastKey = s.key.String()
+		} else if s.prose {
+			s.values++
+		}
+		return
+	}
+
+	if s.isKey {
+		s.key.WriteByte(c)
+	} else if s.prose {
+		s.out = append(s.out, c)
+	}
+}
+
+// writeRune adds an escaped character to a key or prose value, joining surrogate pairs
+func (s *proseStream) writeRune(r rune) {
+	if utf16.IsSurrogate(r) {
+		if s.surrogate == 0 {
+			s.surrogate = r
+			return
+		}
+		r = utf16.DecodeRune(s.surrogate, r)
+	}
+	s.surrogate = 0
+
+	if s.isKey {
+		s.key.WriteRune(r)
+	} else if s.prose {
+		s.out = utf8.AppendRune(s.out, r)
+	}
+}
+
+// inObject reports whether the scanner is directly inside an object
+func (s *proseStream) inObject() bool {
+	return len(s.containers) > 0 && s.containers[len(s.containers)-1] == '{'
+}
+
+// flush reports the complete characters collected so far
+func (s *proseStream) flush() {
+	complete := len(s.out)
+	for start := len(s.out) - 1; start >= 0 && start >= len(s.out)-utf8.UTFMax; start-- {
+		if utf8.RuneStart(s.out[start]) {
+			if !utf8.FullRune(s.out[start:]) {
+				complete = start
+			}
+			break
+		}
+	}
+	if complete == 0 {
+		return
+	}
+
+	s.onDelta(string(s.out[:complete]))
+	s.out = append(s.out[:0], s.out[complete:]...)
+}
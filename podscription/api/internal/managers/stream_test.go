@@ -0,0 +1,136 @@
+package managers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestProseStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{
+			name:     "prose fields in order",
+			response: `{"diagnosis": "Pod Fever", "explanation": "It is hot.", "treatment": "Cool it down."}`,
+			want:     "Pod Fever\n\nIt is hot.\n\nCool it down.",
+		},
+		{
+			name:     "escaped quotes and backslashes",
+			response: `{"diagnosis": "The \"Restart\" Syndrome", "explanation": "Path C:\\pods"}`,
+			want:     "The \"Restart\" Syndrome\n\nPath C:\\pods",
+		},
+		{
+			name:     "escaped newlines and tabs",
+			response: `{"diagnosis": "Line one\nLine two\tindented"}`,
+			want:     "Line one\nLine two\tindented",
+		},
+		{
+			name:     "unicode escapes and surrogate pairs",
+			response: `{"diagnosis": "Caf\u00e9 \ud83e\ude7a Clinic"}`,
+			want:     "Café 🩺 Clinic",
+		},
+		{
+			name:     "raw multibyte characters",
+			response: `{"diagnosis": "🩺 Ärztliche Diagnose"}`,
+			want:     "🩺 Ärztliche Diagnose",
+		},
+		{
+			name:     "nested step objects",
+			response: `{"diagnosis": "D", "steps": [{"title": "Check logs", "rationale": "Find the error", "command": "kubectl logs pod", "risk": "low"}, {"title": "Restart", "rationale": "Clear state", "command": "kubectl delete pod x", "risk": "medium"}], "followUp": "Watch it"}`,
+			want:     "D\n\nCheck logs\n\nFind the error\n\nRestart\n\nClear state\n\nWatch it",
+		},
+		{
+			name:     "non-prose fields are skipped",
+			response: `{"diagnosis": "D", "expectedOutput": "Running", "command": "kubectl get pods", "risk": "high", "closing": "Bye"}`,
+			want:     "D\n\nBye",
+		},
+		{
+			name:     "prose keys nested in unknown objects",
+			response: `{"meta": {"diagnosis": "hidden", "tags": ["diagnosis", "x"]}, "diagnosis": "shown"}`,
+			want:     "hidden\n\nshown",
+		},
+		{
+			name:     "values that look like keys",
+			response: `{"command": "diagnosis", "diagnosis": "{\"not\": \"json\"}"}`,
+			want:     `{"not": "json"}`,
+		},
+		{
+			name:     "code fenced JSON",
+			response: "```json\n{\"diagnosis\": \"Fenced\"}\n```",
+			want:     "Fenced",
+		},
+		{
+			name:     "markdown passes through",
+			response: "## 🩺 Diagnosis: Pod Fever\n\nRun `kubectl get pods`.",
+			want:     "## 🩺 Diagnosis: Pod Fever\n\nRun `kubectl get pods`.",
+		},
+		{
+			name:     "markdown after leading whitespace",
+			response: "\n  Plain answer",
+			want:     "\n  Plain answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamProse(tt.response); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+
+			// The provider may cut its chunks anywhere, including inside escapes and characters
+			for i := 0; i <= len(tt.response); i++ {
+				for j := i; j <= len(tt.response); j++ {
+					got := streamProse(tt.response[:i], tt.response[i:j], tt.response[j:])
+					if got != tt.want {
+						t.Fatalf("split at %d and %d: got %q, want %q", i, j, got, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProseStreamByteByByte(t *testing.T) {
+	response := `{"diagnosis": "Caf\u00e9 🩺 \ud83d\udc8a", "explanation": "Say \"ah\"\n"}`
+	want := "Café 🩺 💊\n\nSay \"ah\"\n"
+
+	chunks := make([]string, len(response))
+	for i := 0; i < len(response); i++ {
+		chunks[i] = response[i : i+1]
+	}
+
+	var deltas []string
+	stream := newProseStream(func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	for _, chunk := range chunks {
+		stream.Write(chunk)
+	}
+
+	if got := strings.Join(deltas, ""); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for _, delta := range deltas {
+		if delta == "" || !utf8.ValidString(delta) {
+			t.Fatalf("got delta %q, want non-empty valid UTF-8", delta)
+		}
+	}
+}
+
+// streamProse feeds chunks through a proseStream and returns everything it reported
+func streamProse(chunks ...string) string {
+	var b strings.Builder
+	stream := newProseStream(func(delta string) {
+		if !utf8.ValidString(delta) {
+			b.WriteString("<invalid>")
+		}
+		b.WriteString(delta)
+	})
+	for _, chunk := range chunks {
+		stream.Write(chunk)
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package managers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"podscription-api/types"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+const (
+	// commonTemplateFile holds blocks shared by every prompt template
+	commonTemplateFile = "common.tmpl"
+	// versionFile holds the declared version of the prompt template set
+	versionFile = "VERSION"
+
+	templateClassification = "classification"
+	templateGeneral        = "general"
+)
+
+// promptData is the data every prompt template is executed with
+type promptData struct {
+	Message string
+	// History is the summary of the turns too old to send as chat turns
+	History string
+	// FollowUp is set when the message continues a conversation
+	FollowUp  bool
+	Category  types.IntentCategory
+	Secondary []types.CategoryScore
+	Schema    string
+	Evidence  []types.Attachment
+	Cluster   string
+	Bundle    string
+	Cases     []types.RelatedCase
+	Knowledge []types.KnowledgeSource
+	Steps     []types.PlanStep
+}
+
+// PromptTemplates holds the parsed prompt templates and the version identifying them
+type PromptTemplates struct {
+	templates map[string]*template.Template
+	version   string
+}
+
+// LoadPromptTemplates loads the embedded prompt templates. When dir is set, any
+// template (or VERSION) file present there replaces the embedded one of the same name.
+func LoadPromptTemplates(dir string) (*PromptTemplates, error) {
+	entries, err := fs.ReadDir(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embedded templates: %w", err)
+	}
+
+	sources := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		data, err := fs.ReadFile(embeddedTemplates, path.Join("templates", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded template %s: %w", name, err)
+		}
+
+		if dir != "" {
+			override, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				data = override
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read template override %s: %w", name, err)
+			}
+		}
+
+		sources[name] = data
+	}
+
+	templates := make(map[string]*template.Template)
+	for name, data := range sources {
+		if name == commonTemplateFile || name == versionFile {
+			continue
+		}
+
+		key := strings.TrimSuffix(name, ".tmpl")
+		t, err := template.New(key).Option("missingkey=error").Parse(string(sources[commonTemplateFile]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", commonTemplateFile, err)
+		}
+		if _, err := t.Parse(string(data)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		if t.Lookup("system") == nil || t.Lookup("user") == nil {
+			return nil, fmt.Errorf("template %s must define both \"system\" and \"user\"", name)
+		}
+
+		templates[key] = t
+	}
+
+	return &PromptTemplates{
+		templates: templates,
+		version:   templateSetVersion(sources),
+	}, nil
+}
+
+// Version returns the declared template version suffixed with a content hash,
+// so locally edited templates never share a version with the originals
+func (t *PromptTemplates) Version() string {
+	return t.version
+}
+
+// render executes the named template's system and user blocks
+func (t *PromptTemplates) render(name string, data promptData) (promptPair, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return promptPair{}, fmt.Errorf("prompt template not found: %s", name)
+	}
+
+	var system, user bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return promptPair{}, fmt.Errorf("failed to render %s system prompt: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&user, "user", data); err != nil {
+		return promptPair{}, fmt.Errorf("failed to render %s user prompt: %w", name, err)
+	}
+
+	return promptPair{System: system.String(), User: user.String()}, nil
+}
+
+// templateSetVersion combines the declared version with a hash of every template source
+func templateSetVersion(sources map[string][]byte) string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write(sources[name])
+		hash.Write([]byte{0})
+	}
+
+	declared := strings.TrimSpace(string(sources[versionFile]))
+	if declared == "" {
+		declared = "unversioned"
+	}
+
+	return fmt.Sprintf("%s+%x", declared, hash.Sum(nil)[:4])
+}
@@ -4,34 +4,81 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/internal/fileutil"
 	"podscription-api/types"
 )
 
-// MemoryStore implements an in-memory store with optional file persistence
+// MemoryStore implements an in-memory store with optional file persistence.
+// Changes are flushed to the file in the background, at most once per flush interval,
+// as an atomic snapshot so that a crash never leaves a partially written file behind.
 type MemoryStore struct {
 	sessions map[uuid.UUID]*types.Session
+	// index serves searches over the sessions' messages
+	index    *searchIndex
 	mu       sync.RWMutex
 	filePath string
+	logger   *logrus.Logger
+
+	// version counts changes; flushedVersion is the last version written to the file
+	version        uint64
+	flushedVersion uint64
+	flushMu        sync.Mutex
+	flushInterval  time.Duration
+	dirty          chan struct{}
+	stop           chan struct{}
+	done           chan struct{}
+	lastErr        error
 }
 
-// NewMemoryStore creates a new memory store
-func NewMemoryStore(filePath string) *MemoryStore {
+// NewMemoryStore creates a new memory store, loading the sessions persisted at filePath
+// and flushing changes back to it every flushInterval. An unreadable file is kept as
+// a backup next to filePath and the store starts empty.
+func NewMemoryStore(filePath string, flushInterval time.Duration, logger *logrus.Logger) (*MemoryStore, error) {
 	store := &MemoryStore{
-		sessions: make(map[uuid.UUID]*types.Session),
-		filePath: filePath,
+		sessions:      make(map[uuid.UUID]*types.Session),
+		index:         newSearchIndex(),
+		filePath:      filePath,
+		logger:        logger,
+		flushInterval: flushInterval,
+		dirty:         make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
 
-	// Try to load existing data
-	if filePath != "" {
-		store.loadFromFile()
+	if filePath == "" {
+		close(store.done)
+		return store, nil
 	}
 
-	return store
+	if err := store.loadFromFile(); err != nil {
+		return nil, err
+	}
+
+	go store.flushLoop()
+	return store, nil
+}
+
+// Close flushes pending changes and stops background persistence
+func (s *MemoryStore) Close() error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	return s.lastErr
 }
 
 // CreateSession creates a new session
@@ -52,8 +99,12 @@ func (s *MemoryStore) CreateSession(name string) (*types.Session, error) {
 	}
 
 	s.sessions[session.ID] = session
-	s.saveToFile()
-	return session, nil
+	s.markDirty()
+
+	// Return a copy, the background flush reads the stored session
+	sessionCopy := *session
+	sessionCopy.Messages = make([]types.Message, 0)
+	return &sessionCopy, nil
 }
 
 // GetSession retrieves a session by ID
@@ -63,7 +114,7 @@ func (s *MemoryStore) GetSession(id uuid.UUID) (*types.Session, error) {
 
 	session, exists := s.sessions[id]
 	if !exists {
-		return nil, fmt.Errorf("session not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
 	}
 
 	// Return a copy to prevent external modification
@@ -74,19 +125,82 @@ func (s *MemoryStore) GetSession(id uuid.UUID) (*types.Session, error) {
 	return &sessionCopy, nil
 }
 
-// UpdateSession updates an existing session
-func (s *MemoryStore) UpdateSession(session *types.Session) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// UpdateSession changes the attributes set in update
+func (s *MemoryStore) UpdateSession(id uuid.UUID, update SessionUpdate) error {
+	return s.updateSession(id, func(session *types.Session) error {
+		if update.Name != nil {
+			session.Name = *update.Name
+		}
+		if update.Archived != nil {
+			session.Archived = *update.Archived
+		}
+		if update.BundleID != nil {
+			bundleID := *update.BundleID
+			session.BundleID = &bundleID
+		}
+		return nil
+	})
+}
+
+// SetSummary replaces a session's rolling conversation summary
+func (s *MemoryStore) SetSummary(id uuid.UUID, summary *types.ConversationSummary) error {
+	return s.updateSession(id, func(session *types.Session) error {
+		session.Summary = summary
+		return nil
+	})
+}
 
-	if _, exists := s.sessions[session.ID]; !exists {
-		return fmt.Errorf("session not found: %s", session.ID)
+// UpdatePlan replaces a session's treatment plan with the one fn derives from the current plan
+func (s *MemoryStore) UpdatePlan(id uuid.UUID, fn PlanFunc) (*types.TreatmentPlan, error) {
+	var updated *types.TreatmentPlan
+	err := s.updateSession(id, func(session *types.Session) error {
+		plan, err := cloneJSON(session.Plan)
+		if err != nil {
+			return err
+		}
+		if updated, err = fn(plan); err != nil {
+			return err
+		}
+		session.Plan, err = cloneJSON(updated)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return updated, nil
+}
 
-	session.UpdatedAt = time.Now()
-	s.sessions[session.ID] = session
-	s.saveToFile()
-	return nil
+// UpdateResolution replaces a session's resolution with the one fn derives from the current one
+func (s *MemoryStore) UpdateResolution(id uuid.UUID, fn ResolutionFunc) (*types.Resolution, error) {
+	var updated *types.Resolution
+	err := s.updateSession(id, func(session *types.Session) error {
+		resolution, err := cloneJSON(session.Resolution)
+		if err != nil {
+			return err
+		}
+		if updated, err = fn(resolution); err != nil {
+			return err
+		}
+		session.Resolution, err = cloneJSON(updated)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetMessageFeedback replaces the feedback on a message of a session
+func (s *MemoryStore) SetMessageFeedback(sessionID, messageID uuid.UUID, feedback *types.Feedback) error {
+	return s.updateSession(sessionID, func(session *types.Session) error {
+		for i := range session.Messages {
+			if session.Messages[i].ID == messageID {
+				session.Messages[i].Feedback = feedback
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	})
 }
 
 // ListSessions returns all sessions
@@ -106,6 +220,92 @@ func (s *MemoryStore) ListSessions() ([]*types.Session, error) {
 	return sessions, nil
 }
 
+// ListSessionSummaries returns up to query.Limit summaries of the sessions matching query
+func (s *MemoryStore) ListSessionSummaries(query SessionQuery) ([]types.SessionSummary, error) {
+	s.mu.RLock()
+	summaries := make([]types.SessionSummary, 0)
+	for _, session := range s.sessions {
+		if query.matches(session) {
+			summaries = append(summaries, summarize(session))
+		}
+	}
+	s.mu.RUnlock()
+
+	query.sortSummaries(summaries)
+	if query.Limit > 0 && len(summaries) > query.Limit {
+		summaries = summaries[:query.Limit]
+	}
+	return summaries, nil
+}
+
+// ExpiredSessions returns the IDs of the sessions last updated before cutoff
+func (s *MemoryStore) ExpiredSessions(cutoff time.Time, includeArchived bool) ([]uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]uuid.UUID, 0)
+	for id, session := range s.sessions {
+		if session.UpdatedAt.Before(cutoff) && (includeArchived || !session.Archived) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// ResolutionTallies counts the sessions with messages by intent category and resolution status
+func (s *MemoryStore) ResolutionTallies() ([]ResolutionTally, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return tallyResolutions(s.sessions), nil
+}
+
+// FeedbackGroups aggregates the feedback on messages by prompt version, model, analyzer and intent category
+func (s *MemoryStore) FeedbackGroups(examples int) ([]types.FeedbackGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(feedbackGroups)
+	for _, session := range s.sessions {
+		for _, message := range session.Messages {
+			groups.addMessage(session.ID, message)
+		}
+	}
+	return groups.list(examples), nil
+}
+
+// Search returns up to query.Limit messages matching query.Text, best first
+func (s *MemoryStore) Search(query SearchQuery) ([]types.SearchHit, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []types.SearchHit{}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits := make([]types.SearchHit, 0)
+	for _, match := range s.index.search(terms, query.Limit) {
+		session := s.sessions[match.sessionID]
+		for _, message := range session.Messages {
+			if message.ID != match.messageID {
+				continue
+			}
+			hits = append(hits, types.SearchHit{
+				SessionID:   session.ID,
+				SessionName: session.Name,
+				MessageID:   message.ID,
+				Role:        message.Role,
+				Timestamp:   message.Timestamp,
+				Snippet:     match.snippet,
+				Score:       match.score,
+			})
+			break
+		}
+	}
+	return hits, nil
+}
+
 // AddMessage adds a message to a session
 func (s *MemoryStore) AddMessage(sessionID uuid.UUID, message types.Message) error {
 	s.mu.Lock()
@@ -113,54 +313,175 @@ func (s *MemoryStore) AddMessage(sessionID uuid.UUID, message types.Message) err
 
 	session, exists := s.sessions[sessionID]
 	if !exists {
-		return fmt.Errorf("session not found: %s", sessionID)
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
 	}
 
-	message.ID = uuid.New()
-	message.Timestamp = time.Now()
+	newMessageDefaults(&message)
 	session.Messages = append(session.Messages, message)
 	session.UpdatedAt = time.Now()
+	s.index.add(sessionID, message)
 
-	s.saveToFile()
+	s.markDirty()
 	return nil
 }
 
-// loadFromFile loads sessions from file if it exists
-func (s *MemoryStore) loadFromFile() {
-	if s.filePath == "" {
-		return
+// DeleteSession removes a session and its messages
+func (s *MemoryStore) DeleteSession(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
 	}
 
-	data, err := os.ReadFile(s.filePath)
-	if err != nil {
-		// File doesn't exist or can't be read, start fresh
-		return
+	for _, message := range s.sessions[id].Messages {
+		s.index.remove(message.ID)
 	}
+	delete(s.sessions, id)
+	s.markDirty()
+	return nil
+}
 
-	var sessions map[uuid.UUID]*types.Session
-	if err := json.Unmarshal(data, &sessions); err != nil {
-		// Invalid data, start fresh
-		return
+// updateSession applies fn to a copy of a stored session under the write lock and stores the
+// copy unless fn fails. Readers hold shallow copies of the session, so the stored one is
+// replaced rather than changed in place.
+func (s *MemoryStore) updateSession(id uuid.UUID, fn func(session *types.Session) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.sessions[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+
+	updated := *existing
+	updated.Messages = make([]types.Message, len(existing.Messages))
+	copy(updated.Messages, existing.Messages)
+	if err := fn(&updated); err != nil {
+		return err
 	}
 
-	s.sessions = sessions
+	updated.UpdatedAt = time.Now()
+	s.sessions[id] = &updated
+	s.markDirty()
+	return nil
 }
 
-// saveToFile saves sessions to file
-func (s *MemoryStore) saveToFile() {
+// cloneJSON deep-copies a value through its JSON encoding, which is also how it is persisted
+func cloneJSON[T any](value *T) (*T, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy value: %w", err)
+	}
+	var clone T
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy value: %w", err)
+	}
+	return &clone, nil
+}
+
+// markDirty records a change and schedules a flush; callers hold s.mu
+func (s *MemoryStore) markDirty() {
 	if s.filePath == "" {
 		return
 	}
 
-	// Create directory if it doesn't exist
-	if dir := filepath.Dir(s.filePath); dir != "." {
-		os.MkdirAll(dir, 0755)
+	s.version++
+	select {
+	case s.dirty <- struct{}{}:
+	default:
+		// A flush is already scheduled and will include this change
 	}
+}
 
+// flushLoop writes changes to the file, batching the changes made within a flush interval
+func (s *MemoryStore) flushLoop() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.dirty:
+			select {
+			case <-time.After(s.flushInterval):
+			case <-s.stop:
+				s.flush()
+				return
+			}
+			s.flush()
+		case <-s.stop:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush writes a snapshot of the sessions when they changed since the last flush
+func (s *MemoryStore) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.RLock()
+	version := s.version
+	if version == s.flushedVersion {
+		s.mu.RUnlock()
+		return
+	}
 	data, err := json.MarshalIndent(s.sessions, "", "  ")
+	s.mu.RUnlock()
+
+	if err == nil {
+		err = fileutil.WriteFileAtomic(s.filePath, data)
+	}
 	if err != nil {
+		s.lastErr = fmt.Errorf("failed to persist sessions to %s: %w", s.filePath, err)
+		s.logger.WithError(err).WithField("path", s.filePath).Error("failed to persist sessions")
+
+		// Retry after the next interval, the file still holds the previous snapshot
+		select {
+		case s.dirty <- struct{}{}:
+		default:
+		}
 		return
 	}
 
-	os.WriteFile(s.filePath, data, 0644)
-}
\ No newline at end of file
+	s.flushedVersion = version
+	s.lastErr = nil
+}
+
+// loadFromFile loads sessions from the file if it exists. A file that cannot be
+// decoded is renamed to a timestamped backup so that it is not overwritten.
+func (s *MemoryStore) loadFromFile() error {
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read sessions from %s: %w", s.filePath, err)
+	}
+
+	var sessions map[uuid.UUID]*types.Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		backup := fmt.Sprintf("%s.corrupt-%s", s.filePath, time.Now().Format("20060102-150405"))
+		if renameErr := os.Rename(s.filePath, backup); renameErr != nil {
+			return fmt.Errorf("failed to decode sessions from %s (%v) and to back it up: %w", s.filePath, err, renameErr)
+		}
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"path":   s.filePath,
+			"backup": backup,
+		}).Warn("sessions file is corrupt, starting empty and keeping a backup")
+		return nil
+	}
+
+	if sessions != nil {
+		s.sessions = sessions
+	}
+	for _, session := range s.sessions {
+		for _, message := range session.Messages {
+			s.index.add(session.ID, message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"podscription-api/types"
+)
+
+// newTestMemoryStore opens a memory store persisting to path, closed when the test ends
+func newTestMemoryStore(t *testing.T, path string) *MemoryStore {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := NewMemoryStore(path, 0, logger)
+	if err != nil {
+		t.Fatalf("failed to open memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestMemoryStorePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	store := newTestMemoryStore(t, path)
+
+	session, err := store.CreateSession("persisted")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.AddMessage(session.ID, types.Message{Role: types.MessageRoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("failed to flush memory store: %v", err)
+	}
+
+	// Only the snapshot remains, no temporary files
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "sessions.json" {
+		t.Fatalf("got %d files, want only the sessions file", len(entries))
+	}
+
+	reopened := newTestMemoryStore(t, path)
+	stored, err := reopened.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session after reopening: %v", err)
+	}
+	if stored.Name != "persisted" || len(stored.Messages) != 1 || stored.Messages[0].Content != "hello" {
+		t.Fatalf("got session %q with messages %+v, want it unchanged", stored.Name, stored.Messages)
+	}
+}
+
+func TestMemoryStoreBacksUpCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sessions.json")
+	if err := os.WriteFile(path, []byte(`{"truncated":`), 0644); err != nil {
+		t.Fatalf("failed to write sessions file: %v", err)
+	}
+
+	store := newTestMemoryStore(t, path)
+	sessions, err := store.ListSessions()
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("got %d sessions (%v), want an empty store", len(sessions), err)
+	}
+
+	backups, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("got backups %v (%v), want the corrupt file kept", backups, err)
+	}
+	if data, err := os.ReadFile(backups[0]); err != nil || string(data) != `{"truncated":` {
+		t.Fatalf("got backup %q (%v), want the original content", data, err)
+	}
+}
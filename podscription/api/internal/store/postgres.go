@@ -0,0 +1,649 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"podscription-api/types"
+)
+
+// postgresQueryTimeout bounds every store operation, the Store interface carries no context
+const postgresQueryTimeout = 10 * time.Second
+
+// postgresMigrationLock is the advisory lock key serializing migrations across replicas
+const postgresMigrationLock = 7_420_613_001
+
+// postgresMigrations are applied in order and recorded in schema_migrations; append new
+// migrations to the end and never edit one that has been released
+var postgresMigrations = []string{
+	`CREATE TABLE sessions (
+		id         UUID PRIMARY KEY,
+		name       TEXT NOT NULL,
+		bundle_id  UUID,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX sessions_updated_at ON sessions (updated_at);
+
+	CREATE TABLE messages (
+		id             UUID PRIMARY KEY,
+		session_id     UUID NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		position       INTEGER NOT NULL,
+		role           TEXT NOT NULL,
+		content        TEXT NOT NULL,
+		created_at     TIMESTAMPTZ NOT NULL,
+		attachments    JSONB,
+		prompt_version TEXT NOT NULL DEFAULT '',
+		analyzer       TEXT NOT NULL DEFAULT '',
+		UNIQUE (session_id, position)
+	);
+
+	CREATE TABLE intents (
+		message_id UUID PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+		category   TEXT NOT NULL,
+		confidence DOUBLE PRECISION NOT NULL,
+		categories JSONB,
+		symptoms   JSONB,
+		source     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX intents_category ON intents (category);
+
+	CREATE TABLE prescriptions (
+		message_id  UUID PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+		diagnosis   TEXT NOT NULL,
+		explanation TEXT NOT NULL DEFAULT '',
+		treatment   TEXT NOT NULL DEFAULT '',
+		steps       JSONB,
+		commands    JSONB,
+		follow_up   TEXT NOT NULL DEFAULT '',
+		closing     TEXT NOT NULL DEFAULT ''
+	);`,
+	`ALTER TABLE sessions ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`DROP INDEX sessions_updated_at;
+	CREATE INDEX sessions_archived_updated_at ON sessions (archived, updated_at, id);`,
+	`CREATE TABLE message_search (
+		message_id UUID PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+		document   TEXT NOT NULL,
+		vector     TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', document)) STORED
+	);
+	CREATE INDEX message_search_vector ON message_search USING GIN (vector);
+	INSERT INTO message_search (message_id, document)
+	SELECT m.id, concat_ws(E'\n', m.content, p.diagnosis,
+		(SELECT string_agg(value, ' ') FROM jsonb_array_elements_text(i.symptoms) AS value),
+		(SELECT string_agg(value, E'\n') FROM jsonb_array_elements_text(p.commands) AS value))
+	FROM messages m
+	LEFT JOIN intents i ON i.message_id = m.id
+	LEFT JOIN prescriptions p ON p.message_id = m.id;`,
+	`ALTER TABLE messages ADD COLUMN sources JSONB;`,
+	`ALTER TABLE sessions ADD COLUMN summary JSONB;`,
+	`ALTER TABLE sessions ADD COLUMN plan JSONB;`,
+	`ALTER TABLE sessions ADD COLUMN resolution JSONB;`,
+	`ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE messages ADD COLUMN feedback JSONB;`,
+}
+
+// PostgresStore implements Store on PostgreSQL, so that several API replicas can share sessions
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore connects a pool of at most maxConns connections to dsn and migrates the schema
+func NewPostgresStore(dsn string, maxConns int) (*PostgresStore, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Postgres DSN: %w", err)
+	}
+	if maxConns > 0 {
+		config.MaxConns = int32(maxConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	store := &PostgresStore{pool: pool}
+	if err := store.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close closes every pooled connection
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+// migrate applies the migrations newer than the database's schema version. Replicas starting
+// together wait on an advisory lock so that each migration runs once.
+func (s *PostgresStore) migrate(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+			return fmt.Errorf("failed to lock schema: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`); err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+
+		var current int
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+
+		for version := current + 1; version <= len(postgresMigrations); version++ {
+			if _, err := tx.Exec(ctx, postgresMigrations[version-1]); err != nil {
+				return fmt.Errorf("failed to apply migration %d: %w", version, err)
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, version, time.Now()); err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateSession creates a new session
+func (s *PostgresStore) CreateSession(name string) (*types.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	session := &types.Session{
+		ID:        uuid.New(),
+		Name:      name,
+		Messages:  make([]types.Message, 0),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if session.Name == "" {
+		var count int
+		if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count sessions: %w", err)
+		}
+		session.Name = fmt.Sprintf("Session %d", count+1)
+	}
+
+	_, err := s.pool.Exec(ctx, `INSERT INTO sessions (id, name, bundle_id, archived, created_at, updated_at) VALUES ($1, $2, NULL, FALSE, $3, $3)`,
+		session.ID.String(), session.Name, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession retrieves a session by ID
+func (s *PostgresStore) GetSession(id uuid.UUID) (*types.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	session, err := scanSession(s.pool.QueryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id.String()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+messageColumns+` WHERE m.session_id = $1 ORDER BY m.position`, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		message, _, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		session.Messages = append(session.Messages, *message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return session, nil
+}
+
+// UpdateSession changes the attributes set in update
+func (s *PostgresStore) UpdateSession(id uuid.UUID, update SessionUpdate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	set, args := update.assignments(func(n int) string { return fmt.Sprintf("$%d", n) })
+	args = append(args, time.Now(), id.String())
+	set = append(set, fmt.Sprintf("updated_at = $%d", len(args)-1))
+
+	tag, err := s.pool.Exec(ctx, `UPDATE sessions SET `+strings.Join(set, ", ")+fmt.Sprintf(` WHERE id = $%d`, len(args)), args...)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// SetSummary replaces a session's rolling conversation summary
+func (s *PostgresStore) SetSummary(id uuid.UUID, summary *types.ConversationSummary) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `UPDATE sessions SET summary = $1, updated_at = $2 WHERE id = $3`,
+		encodeJSON(summary), time.Now(), id.String())
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// UpdatePlan replaces a session's treatment plan with the one fn derives from the current plan.
+// The session row is locked for the transaction, so replicas updating the plan take turns.
+func (s *PostgresStore) UpdatePlan(id uuid.UUID, fn PlanFunc) (*types.TreatmentPlan, error) {
+	return updatePostgresColumn(s, id, "plan", fn)
+}
+
+// UpdateResolution replaces a session's resolution with the one fn derives from the current one
+func (s *PostgresStore) UpdateResolution(id uuid.UUID, fn ResolutionFunc) (*types.Resolution, error) {
+	return updatePostgresColumn(s, id, "resolution", fn)
+}
+
+// updatePostgresColumn replaces a JSON column of a session with what fn derives from its current value
+func updatePostgresColumn[T any](s *PostgresStore, id uuid.UUID, column string, fn func(*T) (*T, error)) (*T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	var updated *T
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var raw sql.NullString
+		err := tx.QueryRow(ctx, `SELECT `+column+` FROM sessions WHERE id = $1 FOR UPDATE`, id.String()).Scan(&raw)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock session: %w", err)
+		}
+
+		var current *T
+		if err := decodeJSON(raw, &current); err != nil {
+			return fmt.Errorf("failed to decode session %s: %w", column, err)
+		}
+		if updated, err = fn(current); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE sessions SET `+column+` = $1, updated_at = $2 WHERE id = $3`,
+			encodeJSON(updated), time.Now(), id.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetMessageFeedback replaces the feedback on a message of a session
+func (s *PostgresStore) SetMessageFeedback(sessionID, messageID uuid.UUID, feedback *types.Feedback) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE messages SET feedback = $1 WHERE id = $2 AND session_id = $3`,
+			encodeJSON(feedback), messageID.String(), sessionID.String())
+		if err != nil {
+			return fmt.Errorf("failed to update message: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE sessions SET updated_at = $1 WHERE id = $2`, time.Now(), sessionID.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListSessions returns all sessions, most recently updated first
+func (s *PostgresStore) ListSessions() ([]*types.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+sessionColumns+` FROM sessions ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*types.Session, 0)
+	byID := make(map[uuid.UUID]*types.Session)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		sessions = append(sessions, session)
+		byID[session.ID] = session
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	messageRows, err := s.pool.Query(ctx, `SELECT `+messageColumns+` ORDER BY m.session_id, m.position`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	defer messageRows.Close()
+
+	for messageRows.Next() {
+		message, sessionID, err := scanMessage(messageRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		if session, ok := byID[sessionID]; ok {
+			session.Messages = append(session.Messages, *message)
+		}
+	}
+	if err := messageRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// ListSessionSummaries returns up to query.Limit summaries of the sessions matching query
+func (s *PostgresStore) ListSessionSummaries(query SessionQuery) ([]types.SessionSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	statement, args := query.summaryQuery(
+		func(n int) string { return fmt.Sprintf("$%d", n) },
+		"ILIKE",
+		func(t time.Time) interface{} { return t },
+	)
+
+	rows, err := s.pool.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]types.SessionSummary, 0)
+	for rows.Next() {
+		summary, err := scanSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		summaries = append(summaries, *summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// ExpiredSessions returns the IDs of the sessions last updated before cutoff
+func (s *PostgresStore) ExpiredSessions(cutoff time.Time, includeArchived bool) ([]uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	query := `SELECT id FROM sessions WHERE updated_at < $1`
+	if !includeArchived {
+		query += ` AND archived = FALSE`
+	}
+
+	rows, err := s.pool.Query(ctx, query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired sessions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIDs(rows)
+}
+
+// ResolutionTallies counts the sessions with messages by intent category and resolution status
+func (s *PostgresStore) ResolutionTallies() ([]ResolutionTally, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT category, status, COUNT(*),
+		CASE WHEN status = 'resolved' THEN json_agg(time_to_resolution)::TEXT END
+		FROM (
+			SELECT COALESCE((SELECT i.category FROM messages m JOIN intents i ON i.message_id = m.id
+					WHERE m.session_id = s.id ORDER BY m.position LIMIT 1), 'general') AS category,
+				COALESCE(s.resolution->>'status', 'open') AS status,
+				COALESCE((s.resolution->>'timeToResolution')::DOUBLE PRECISION, 0) AS time_to_resolution
+			FROM sessions s
+			WHERE EXISTS (SELECT 1 FROM messages m WHERE m.session_id = s.id)
+		) AS outcomes
+		GROUP BY category, status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	return readResolutionTallies(rows)
+}
+
+// postgresNegativeFeedback holds for a thumbs down or feedback flagging commands
+const postgresNegativeFeedback = `(m.feedback->>'rating' = 'down' OR jsonb_array_length(COALESCE(m.feedback->'wrongCommands', '[]'::JSONB)) > 0)`
+
+// FeedbackGroups aggregates the feedback on messages by prompt version, model, analyzer and intent category
+func (s *PostgresStore) FeedbackGroups(examples int) ([]types.FeedbackGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	groups := make(feedbackGroups)
+	queries := []struct {
+		query string
+		args  []interface{}
+		read  func(rows rowIterator) error
+	}{
+		{
+			query: `SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, ''),
+				COUNT(*), SUM(CASE WHEN ` + postgresNegativeFeedback + ` THEN 1 ELSE 0 END)
+				FROM messages m
+				LEFT JOIN intents i ON i.message_id = m.id
+				WHERE m.feedback IS NOT NULL
+				GROUP BY 1, 2, 3, 4`,
+			read: groups.readCounts,
+		},
+		{
+			query: `SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, ''), c.value, COUNT(*)
+				FROM messages m
+				LEFT JOIN intents i ON i.message_id = m.id
+				CROSS JOIN LATERAL jsonb_array_elements_text(m.feedback->'wrongCommands') AS c(value)
+				WHERE m.feedback IS NOT NULL
+				GROUP BY 1, 2, 3, 4, 5`,
+			read: groups.readWrongCommands,
+		},
+		{
+			query: `SELECT prompt_version, model, analyzer, category, session_id::TEXT, id::TEXT, diagnosis, feedback::TEXT
+				FROM (
+					SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, '') AS category,
+						m.session_id, m.id, COALESCE(p.diagnosis, '') AS diagnosis, m.feedback,
+						ROW_NUMBER() OVER (PARTITION BY m.prompt_version, m.model, m.analyzer, COALESCE(i.category, '')
+							ORDER BY (m.feedback->>'updatedAt')::TIMESTAMPTZ DESC) AS rank
+					FROM messages m
+					LEFT JOIN intents i ON i.message_id = m.id
+					LEFT JOIN prescriptions p ON p.message_id = m.id
+					WHERE m.feedback IS NOT NULL AND ` + postgresNegativeFeedback + `
+				) AS negative
+				WHERE rank <= $1`,
+			args: []interface{}{examples},
+			read: groups.readExamples,
+		},
+	}
+
+	for _, q := range queries {
+		rows, err := s.pool.Query(ctx, q.query, q.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to group feedback: %w", err)
+		}
+		err = q.read(rows)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to group feedback: %w", err)
+		}
+	}
+	return groups.list(examples), nil
+}
+
+// Search returns up to query.Limit messages matching any word of query.Text, ranked by ts_rank
+func (s *PostgresStore) Search(query SearchQuery) ([]types.SearchHit, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []types.SearchHit{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	// searchTerms only returns letters and digits, so the terms are safe tsquery lexemes
+	headline := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d`, matchStart, matchEnd, snippetWords, snippetWords/2)
+	rows, err := s.pool.Query(ctx, `SELECT f.message_id, s.id, s.name, m.role, m.created_at,
+		ts_headline('english', f.document, q, $1), ts_rank(f.vector, q)::DOUBLE PRECISION AS rank
+		FROM message_search f
+		JOIN messages m ON m.id = f.message_id
+		JOIN sessions s ON s.id = m.session_id
+		CROSS JOIN to_tsquery('english', $2) AS q
+		WHERE f.vector @@ q
+		ORDER BY rank DESC
+		LIMIT $3`, headline, strings.Join(terms, " | "), query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]types.SearchHit, 0)
+	for rows.Next() {
+		hit, err := scanSearchHit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read search hit: %w", err)
+		}
+		hits = append(hits, *hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	return hits, nil
+}
+
+// AddMessage appends a message to a session. The session row is locked for the
+// transaction so that replicas appending to the same session get distinct positions.
+func (s *PostgresStore) AddMessage(sessionID uuid.UUID, message types.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	newMessageDefaults(&message)
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var locked string
+		err := tx.QueryRow(ctx, `SELECT id FROM sessions WHERE id = $1 FOR UPDATE`, sessionID.String()).Scan(&locked)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock session: %w", err)
+		}
+
+		var position int
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position) + 1, 0) FROM messages WHERE session_id = $1`, sessionID.String()).Scan(&position); err != nil {
+			return fmt.Errorf("failed to read message position: %w", err)
+		}
+
+		if err := insertPostgresMessage(ctx, tx, sessionID, position, message); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE sessions SET updated_at = $1 WHERE id = $2`, message.Timestamp, sessionID.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteSession removes a session; its messages are removed with it
+func (s *PostgresStore) DeleteSession(id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM sessions WHERE id = $1`, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// insertPostgresMessage writes a message with its intent and prescription at the given position
+func insertPostgresMessage(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, position int, message types.Message) error {
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+
+	_, err := tx.Exec(ctx, `INSERT INTO messages (id, session_id, position, role, content, created_at, attachments, prompt_version, analyzer, sources, model, feedback)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		message.ID.String(), sessionID.String(), position, string(message.Role), message.Content, message.Timestamp,
+		encodeJSON(message.Attachments), message.PromptVersion, message.Analyzer, encodeJSON(message.Sources), message.Model, encodeJSON(message.Feedback))
+	if err != nil {
+		return fmt.Errorf("failed to insert message: %w", err)
+	}
+
+	return insertPostgresMessageDetails(ctx, tx, message)
+}
+
+// insertPostgresMessageDetails writes a message's intent, prescription and search document
+func insertPostgresMessageDetails(ctx context.Context, tx pgx.Tx, message types.Message) error {
+	if _, err := tx.Exec(ctx, `INSERT INTO message_search (message_id, document) VALUES ($1, $2)`, message.ID.String(), searchDocument(message)); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+
+	if intent := message.Intent; intent != nil {
+		_, err := tx.Exec(ctx, `INSERT INTO intents (message_id, category, confidence, categories, symptoms, source) VALUES ($1, $2, $3, $4, $5, $6)`,
+			message.ID.String(), string(intent.Category), intent.Confidence, encodeJSON(intent.Categories), encodeJSON(intent.Symptoms), string(intent.Source))
+		if err != nil {
+			return fmt.Errorf("failed to insert intent: %w", err)
+		}
+	}
+
+	if p := message.Prescription; p != nil {
+		_, err := tx.Exec(ctx, `INSERT INTO prescriptions (message_id, diagnosis, explanation, treatment, steps, commands, follow_up, closing)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			message.ID.String(), p.Diagnosis, p.Explanation, p.Treatment, encodeJSON(p.Steps), encodeJSON(p.Commands), p.FollowUp, p.Closing)
+		if err != nil {
+			return fmt.Errorf("failed to insert prescription: %w", err)
+		}
+	}
+
+	return nil
+}
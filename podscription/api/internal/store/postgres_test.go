@@ -0,0 +1,368 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"podscription-api/types"
+)
+
+// postgresTestDSN names the environment variable pointing the Postgres tests at a database
+// they may create schemas in; the tests are skipped when it is unset
+const postgresTestDSN = "POSTGRES_TEST_DSN"
+
+// postgresTestSchema creates an empty schema for one test, dropped when the test ends, and
+// returns a DSN whose connections use it
+func postgresTestSchema(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv(postgresTestDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresTestDSN)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to Postgres: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	schema := "podscription_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := conn.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			t.Errorf("failed to connect to Postgres: %v", err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("failed to drop schema: %v", err)
+		}
+	})
+
+	// Unknown DSN parameters are sent to the server as run-time parameters
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	default:
+		return dsn + " search_path=" + schema
+	}
+}
+
+// newPostgresTestStore opens a store on an empty schema of the test database
+func newPostgresTestStore(t *testing.T) *PostgresStore {
+	t.Helper()
+
+	store, err := NewPostgresStore(postgresTestSchema(t), 10)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func TestPostgresMigrateConcurrently(t *testing.T) {
+	dsn := postgresTestSchema(t)
+
+	// Replicas starting together must apply each migration once
+	const replicas = 5
+	stores := make([]*PostgresStore, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stores[i], errs[i] = NewPostgresStore(dsn, 2)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("replica %d failed to migrate: %v", i, err)
+		}
+		defer stores[i].Close()
+	}
+
+	ctx := context.Background()
+	var applied, latest int
+	err := stores[0].pool.QueryRow(ctx, `SELECT COUNT(*), MAX(version) FROM schema_migrations`).Scan(&applied, &latest)
+	if err != nil {
+		t.Fatalf("failed to read schema_migrations: %v", err)
+	}
+	if applied != len(postgresMigrations) || latest != len(postgresMigrations) {
+		t.Fatalf("got %d migrations up to version %d, want %d", applied, latest, len(postgresMigrations))
+	}
+
+	// Reopening a migrated database applies nothing
+	store, err := NewPostgresStore(dsn, 2)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	if err := store.pool.QueryRow(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("failed to read schema_migrations: %v", err)
+	}
+	if applied != len(postgresMigrations) {
+		t.Fatalf("got %d migrations after reopening, want %d", applied, len(postgresMigrations))
+	}
+}
+
+func TestPostgresAddMessageConcurrently(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	session, err := store.CreateSession("concurrent")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	const messages = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, messages)
+	for i := range messages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.AddMessage(session.ID, types.Message{
+				Role:    types.MessageRoleUser,
+				Content: fmt.Sprintf("message %d", i),
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if len(stored.Messages) != messages {
+		t.Fatalf("got %d messages, want %d", len(stored.Messages), messages)
+	}
+
+	var positions int
+	err = store.pool.QueryRow(context.Background(), `SELECT COUNT(DISTINCT position) FROM messages WHERE session_id = $1`,
+		session.ID.String()).Scan(&positions)
+	if err != nil {
+		t.Fatalf("failed to count positions: %v", err)
+	}
+	if positions != messages {
+		t.Fatalf("got %d distinct positions, want %d", positions, messages)
+	}
+}
+
+func TestPostgresAddMessageToMissingSession(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	err := store.AddMessage(uuid.New(), types.Message{Role: types.MessageRoleUser, Content: "orphan"})
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got error %v, want ErrSessionNotFound", err)
+	}
+
+	// The failed transaction leaves nothing behind
+	var count int
+	if err := store.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM messages`).Scan(&count); err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("got %d messages, want none", count)
+	}
+}
+
+func TestPostgresUpdateSession(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	session, err := store.CreateSession("bundle")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	message := types.Message{ID: uuid.New(), Role: types.MessageRoleAssistant, Content: "opening"}
+	if err := store.AddMessage(session.ID, message); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+
+	// Binding the bundle changes nothing else
+	bundleID := uuid.New()
+	if err := store.UpdateSession(session.ID, SessionUpdate{BundleID: &bundleID}); err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+	if err := store.UpdateSession(uuid.New(), SessionUpdate{BundleID: &bundleID}); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got error %v updating a missing session, want ErrSessionNotFound", err)
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if stored.BundleID == nil || *stored.BundleID != bundleID || stored.Name != "bundle" {
+		t.Fatalf("got session %q bound to %v, want %q bound to %s", stored.Name, stored.BundleID, "bundle", bundleID)
+	}
+	if len(stored.Messages) != 1 || stored.Messages[0].ID != message.ID {
+		t.Fatalf("got messages %+v, want the opening message with its own ID", stored.Messages)
+	}
+}
+
+func TestPostgresUpdatePlanConcurrently(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	session, err := store.CreateSession("plan")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	// Every writer's step survives, the session row serializes the updates
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.UpdatePlan(session.ID, func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error) {
+				if plan == nil {
+					plan = &types.TreatmentPlan{}
+				}
+				plan.Steps = append(plan.Steps, types.PlanStep{ID: uuid.New(), Number: i + 1, Status: types.StepStatusPending})
+				return plan, nil
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to update plan: %v", err)
+		}
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if stored.Plan == nil || len(stored.Plan.Steps) != writers {
+		t.Fatalf("got plan %+v, want %d steps", stored.Plan, writers)
+	}
+}
+
+func TestPostgresSetMessageFeedback(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	session, err := store.CreateSession("feedback")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.AddMessage(session.ID, types.Message{Role: types.MessageRoleAssistant, Content: "answer"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+
+	feedback := &types.Feedback{Rating: types.FeedbackRatingDown, Comment: "wrong namespace"}
+	if err := store.SetMessageFeedback(session.ID, stored.Messages[0].ID, feedback); err != nil {
+		t.Fatalf("failed to set feedback: %v", err)
+	}
+	if err := store.SetMessageFeedback(session.ID, uuid.New(), feedback); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("got error %v for a missing message, want ErrMessageNotFound", err)
+	}
+
+	stored, err = store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if got := stored.Messages[0].Feedback; got == nil || got.Rating != feedback.Rating || got.Comment != feedback.Comment {
+		t.Fatalf("got feedback %+v, want %+v", got, feedback)
+	}
+}
+
+func TestPostgresAggregates(t *testing.T) {
+	store := newPostgresTestStore(t)
+
+	session, err := store.CreateSession("aggregates")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	answer := types.Message{
+		Role:          types.MessageRoleAssistant,
+		Content:       "restart the pod",
+		PromptVersion: "1.0.0",
+		Model:         "test-model",
+		Intent:        &types.PodIntent{Category: types.IntentCategoryPodIssues, Confidence: 0.9},
+		Prescription:  &types.Prescription{Diagnosis: "OOMKilled", Commands: []string{"kubectl delete pod web-1"}},
+	}
+	if err := store.AddMessage(session.ID, answer); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	if _, err := store.CreateSession("empty"); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+
+	_, err = store.UpdateResolution(session.ID, func(*types.Resolution) (*types.Resolution, error) {
+		return &types.Resolution{Status: types.ResolutionStatusResolved, TimeToResolution: 90}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to resolve session: %v", err)
+	}
+	feedback := &types.Feedback{Rating: types.FeedbackRatingUp, WrongCommands: []string{"kubectl delete pod web-1"}}
+	if err := store.SetMessageFeedback(session.ID, stored.Messages[0].ID, feedback); err != nil {
+		t.Fatalf("failed to set feedback: %v", err)
+	}
+
+	// Sessions without messages are left out of the tallies
+	tallies, err := store.ResolutionTallies()
+	if err != nil {
+		t.Fatalf("failed to tally resolutions: %v", err)
+	}
+	if len(tallies) != 1 {
+		t.Fatalf("got tallies %+v, want one", tallies)
+	}
+	tally := tallies[0]
+	if tally.Category != types.IntentCategoryPodIssues || tally.Status != types.ResolutionStatusResolved ||
+		tally.Sessions != 1 || len(tally.TimesToResolution) != 1 || tally.TimesToResolution[0] != 90 {
+		t.Fatalf("got tally %+v", tally)
+	}
+
+	// A flagged command makes feedback negative even with a thumbs up
+	groups, err := store.FeedbackGroups(10)
+	if err != nil {
+		t.Fatalf("failed to group feedback: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("got groups %+v, want one", groups)
+	}
+	group := groups[0]
+	if group.PromptVersion != "1.0.0" || group.Model != "test-model" || group.Category != types.IntentCategoryPodIssues ||
+		group.Rated != 1 || group.Negative != 1 || group.WrongCommands["kubectl delete pod web-1"] != 1 {
+		t.Fatalf("got group %+v", group)
+	}
+	if len(group.Examples) != 1 || group.Examples[0].Diagnosis != "OOMKilled" || group.Examples[0].SessionID != session.ID {
+		t.Fatalf("got examples %+v", group.Examples)
+	}
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// ErrInvalidCursor is returned when a page cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SessionQuery selects a page of session summaries, ordered by UpdatedAt then ID
+type SessionQuery struct {
+	// Category keeps sessions with a message classified in the category
+	Category types.IntentCategory
+	// Name keeps sessions whose name contains it, ignoring case
+	Name string
+	// UpdatedAfter and UpdatedBefore bound UpdatedAt when set; the range is [after, before)
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Archived      bool
+	// Ascending lists the least recently updated sessions first
+	Ascending bool
+	// After continues a listing past the session it marks
+	After *Cursor
+	Limit int
+}
+
+// Cursor marks the last session of a page
+type Cursor struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes the cursor for use in URLs
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.UpdatedAt.UnixNano(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor produced by Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	unix, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{UpdatedAt: time.Unix(0, unix), ID: parsed}, nil
+}
+
+// summarize projects a session onto its summary
+func summarize(session *types.Session) types.SessionSummary {
+	summary := types.SessionSummary{
+		ID:           session.ID,
+		Name:         session.Name,
+		MessageCount: len(session.Messages),
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+		BundleID:     session.BundleID,
+		Archived:     session.Archived,
+	}
+
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		message := session.Messages[i]
+		if summary.LastIntent == "" && message.Intent != nil {
+			summary.LastIntent = message.Intent.Category
+		}
+		if summary.LastDiagnosis == "" && message.Prescription != nil {
+			summary.LastDiagnosis = message.Prescription.Diagnosis
+		}
+	}
+
+	return summary
+}
+
+// matches reports whether a session passes the query's filters
+func (q SessionQuery) matches(session *types.Session) bool {
+	if session.Archived != q.Archived {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(session.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if !q.UpdatedAfter.IsZero() && session.UpdatedAt.Before(q.UpdatedAfter) {
+		return false
+	}
+	if !q.UpdatedBefore.IsZero() && !session.UpdatedAt.Before(q.UpdatedBefore) {
+		return false
+	}
+	if q.After != nil && !q.follows(session.UpdatedAt, session.ID) {
+		return false
+	}
+	if q.Category != "" {
+		for _, message := range session.Messages {
+			if message.Intent != nil && message.Intent.Category == q.Category {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// follows reports whether a session comes after the query's cursor in its order
+func (q SessionQuery) follows(updatedAt time.Time, id uuid.UUID) bool {
+	comparison := updatedAt.Compare(q.After.UpdatedAt)
+	if comparison == 0 {
+		comparison = strings.Compare(id.String(), q.After.ID.String())
+	}
+	if q.Ascending {
+		return comparison > 0
+	}
+	return comparison < 0
+}
+
+// sortSummaries orders summaries by UpdatedAt then ID, in the query's direction
+func (q SessionQuery) sortSummaries(summaries []types.SessionSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt) == q.Ascending
+		}
+		return (a.ID.String() < b.ID.String()) == q.Ascending
+	})
+}
+
+// summaryQuery renders the SQL selecting a page of summaries; placeholder renders the nth bind
+// parameter, like is the case-insensitive match operator and timestamp converts bound times
+func (q SessionQuery) summaryQuery(placeholder func(n int) string, like string, timestamp func(time.Time) interface{}) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		return placeholder(len(args))
+	}
+
+	where = append(where, "s.archived = "+bind(q.Archived))
+	if q.Name != "" {
+		where = append(where, fmt.Sprintf(`s.name %s %s ESCAPE '\'`, like, bind("%"+escapeLike(q.Name)+"%")))
+	}
+	if !q.UpdatedAfter.IsZero() {
+		where = append(where, "s.updated_at >= "+bind(timestamp(q.UpdatedAfter)))
+	}
+	if !q.UpdatedBefore.IsZero() {
+		where = append(where, "s.updated_at < "+bind(timestamp(q.UpdatedBefore)))
+	}
+	if q.Category != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM messages m JOIN intents i ON i.message_id = m.id
+			WHERE m.session_id = s.id AND i.category = `+bind(string(q.Category))+`)`)
+	}
+
+	direction, comparison := "DESC", "<"
+	if q.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(s.updated_at, s.id) %s (%s, %s)",
+			comparison, bind(timestamp(q.After.UpdatedAt)), bind(q.After.ID.String())))
+	}
+
+	query := `SELECT s.id, s.name, s.bundle_id, s.archived, s.created_at, s.updated_at,
+		(SELECT COUNT(*) FROM messages m WHERE m.session_id = s.id),
+		(SELECT i.category FROM messages m JOIN intents i ON i.message_id = m.id
+			WHERE m.session_id = s.id ORDER BY m.position DESC LIMIT 1),
+		(SELECT p.diagnosis FROM messages m JOIN prescriptions p ON p.message_id = m.id
+			WHERE m.session_id = s.id ORDER BY m.position DESC LIMIT 1)
+		FROM sessions s
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY s.updated_at ` + direction + `, s.id ` + direction + `
+		LIMIT ` + bind(q.Limit)
+
+	return query, args
+}
+
+// escapeLike escapes the LIKE wildcards in a literal search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// createTiedSessions creates sessions sharing one UpdatedAt between two older and newer ones,
+// returning their IDs in ascending (UpdatedAt, ID) order
+func createTiedSessions(t *testing.T, store Store, setUpdatedAt func(id uuid.UUID, at time.Time)) []uuid.UUID {
+	t.Helper()
+
+	tied := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	times := []time.Time{tied.Add(-time.Hour), tied, tied, tied, tied, tied, tied.Add(time.Hour)}
+
+	type created struct {
+		id uuid.UUID
+		at time.Time
+	}
+	sessions := make([]created, 0, len(times))
+	for _, at := range times {
+		session, err := store.CreateSession("")
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		setUpdatedAt(session.ID, at)
+		sessions = append(sessions, created{session.ID, at})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].at.Equal(sessions[j].at) {
+			return sessions[i].at.Before(sessions[j].at)
+		}
+		return sessions[i].id.String() < sessions[j].id.String()
+	})
+	ids := make([]uuid.UUID, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.id
+	}
+	return ids
+}
+
+// pageThrough lists every session matching query a page at a time, following the cursors
+func pageThrough(t *testing.T, store Store, query SessionQuery) []uuid.UUID {
+	t.Helper()
+
+	var ids []uuid.UUID
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("cursor never reached the last page")
+		}
+		summaries, err := store.ListSessionSummaries(query)
+		if err != nil {
+			t.Fatalf("failed to list sessions: %v", err)
+		}
+		for _, summary := range summaries {
+			ids = append(ids, summary.ID)
+		}
+		if len(summaries) < query.Limit {
+			return ids
+		}
+
+		// Cursors go through their URL form, as they do between API requests
+		last := summaries[len(summaries)-1]
+		cursor, err := ParseCursor(Cursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.String())
+		if err != nil {
+			t.Fatalf("failed to parse cursor: %v", err)
+		}
+		query.After = cursor
+	}
+}
+
+func TestListSessionSummariesCursorAcrossEqualTimestamps(t *testing.T) {
+	stores := map[string]func(t *testing.T) (Store, func(id uuid.UUID, at time.Time)){
+		"memory": func(t *testing.T) (Store, func(uuid.UUID, time.Time)) {
+			store := newTestMemoryStore(t, "")
+			return store, func(id uuid.UUID, at time.Time) {
+				store.sessions[id].UpdatedAt = at
+			}
+		},
+		"sqlite": func(t *testing.T) (Store, func(uuid.UUID, time.Time)) {
+			store, _ := newTestSQLiteStore(t)
+			return store, func(id uuid.UUID, at time.Time) {
+				if _, err := store.db.Exec(`UPDATE sessions SET updated_at = ? WHERE id = ?`, at.UnixNano(), id.String()); err != nil {
+					t.Fatalf("failed to set updated_at: %v", err)
+				}
+			}
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, setUpdatedAt := open(t)
+			ascending := createTiedSessions(t, store, setUpdatedAt)
+			descending := make([]uuid.UUID, len(ascending))
+			for i, id := range ascending {
+				descending[len(ascending)-1-i] = id
+			}
+
+			// Pages of two split the five tied sessions, so the ID has to break the tie
+			for _, tt := range []struct {
+				name  string
+				query SessionQuery
+				want  []uuid.UUID
+			}{
+				{"newest first", SessionQuery{Limit: 2}, descending},
+				{"oldest first", SessionQuery{Limit: 2, Ascending: true}, ascending},
+				{"one page", SessionQuery{Limit: 10}, descending},
+			} {
+				t.Run(tt.name, func(t *testing.T) {
+					got := pageThrough(t, store, tt.query)
+					if len(got) != len(tt.want) {
+						t.Fatalf("got %d sessions, want %d", len(got), len(tt.want))
+					}
+					for i := range got {
+						if got[i] != tt.want[i] {
+							t.Fatalf("got %v, want %v", got, tt.want)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestListSessionSummariesFilters(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	web, err := store.CreateSession("Crashing web_pod")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.AddMessage(web.ID, types.Message{
+		Role:         types.MessageRoleAssistant,
+		Intent:       &types.PodIntent{Category: types.IntentCategoryPodIssues},
+		Prescription: &types.Prescription{Diagnosis: "OOMKilled"},
+	}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	if _, err := store.CreateSession("webhook"); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query SessionQuery
+		want  int
+	}{
+		{"all", SessionQuery{Limit: 10}, 2},
+		{"name ignores case", SessionQuery{Name: "WEB", Limit: 10}, 2},
+		{"underscore is literal", SessionQuery{Name: "web_", Limit: 10}, 1},
+		{"category", SessionQuery{Category: types.IntentCategoryPodIssues, Limit: 10}, 1},
+		{"archived", SessionQuery{Archived: true, Limit: 10}, 0},
+		{"updated before", SessionQuery{UpdatedBefore: web.CreatedAt, Limit: 10}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, err := store.ListSessionSummaries(tt.query)
+			if err != nil {
+				t.Fatalf("failed to list sessions: %v", err)
+			}
+			if len(summaries) != tt.want {
+				t.Fatalf("got %d sessions, want %d", len(summaries), tt.want)
+			}
+		})
+	}
+
+	summaries, err := store.ListSessionSummaries(SessionQuery{Category: types.IntentCategoryPodIssues, Limit: 1})
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("got %d sessions (%v), want the web session", len(summaries), err)
+	}
+	if got := summaries[0]; got.MessageCount != 1 || got.LastIntent != types.IntentCategoryPodIssues || got.LastDiagnosis != "OOMKilled" {
+		t.Fatalf("got summary %+v, want its message count, last intent and diagnosis", got)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	cursor := Cursor{UpdatedAt: time.Unix(0, 1767323045123456789), ID: uuid.New()}
+	parsed, err := ParseCursor(cursor.String())
+	if err != nil {
+		t.Fatalf("failed to parse cursor: %v", err)
+	}
+	if !parsed.UpdatedAt.Equal(cursor.UpdatedAt) || parsed.ID != cursor.ID {
+		t.Fatalf("got %+v, want %+v", parsed, cursor)
+	}
+
+	for _, invalid := range []string{"", "not base64!", Cursor{}.String()[:8], "MTIzNDU"} {
+		if _, err := ParseCursor(invalid); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("got %v for %q, want ErrInvalidCursor", err, invalid)
+		}
+	}
+}
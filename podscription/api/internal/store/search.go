@@ -0,0 +1,260 @@
+package store
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"podscription-api/internal/text"
+	"podscription-api/types"
+)
+
+// maxSearchTerms caps the terms of a search query that are matched
+const maxSearchTerms = 16
+
+// Highlight markers wrapped around matched terms in search snippets
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+// Private-use characters delimiting matched terms in raw snippets; renderSnippet escapes the
+// text around them and replaces them with the highlight markers
+const (
+	matchStart = "\uE000"
+	matchEnd   = "\uE001"
+)
+
+// snippetWords is the length of a search snippet, in words
+const snippetWords = 24
+
+// SearchQuery selects the messages matching free text, best matches first
+type SearchQuery struct {
+	Text  string
+	Limit int
+}
+
+// searchDocument is the text of a message that search matches: its content,
+// and the diagnosis, symptoms and commands recorded with it
+func searchDocument(message types.Message) string {
+	parts := []string{message.Content}
+	if message.Prescription != nil {
+		parts = append(parts, message.Prescription.Diagnosis)
+	}
+	if message.Intent != nil && len(message.Intent.Symptoms) > 0 {
+		parts = append(parts, strings.Join(message.Intent.Symptoms, " "))
+	}
+	if message.Prescription != nil {
+		parts = append(parts, message.Prescription.Commands...)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// searchTerms splits a query into distinct lowercase words, leaving out stop words
+func searchTerms(query string) []string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, word := range text.Words(query) {
+		if seen[word] || text.IsStopWord(word) || len(terms) == maxSearchTerms {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// searchIndex is an in-process inverted index over message search documents, scored with BM25
+type searchIndex struct {
+	// postings maps a stemmed term to the messages containing it and its frequency in each
+	postings  map[string]map[uuid.UUID]int
+	documents map[uuid.UUID]indexedDocument
+	// totalLength is the summed length of all documents, in terms
+	totalLength int
+}
+
+// indexedDocument is a message's entry in the search index
+type indexedDocument struct {
+	sessionID uuid.UUID
+	text      string
+	terms     map[string]int
+	length    int
+}
+
+// scoredMessage is a search match
+type scoredMessage struct {
+	sessionID uuid.UUID
+	messageID uuid.UUID
+	score     float64
+	snippet   string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:  make(map[string]map[uuid.UUID]int),
+		documents: make(map[uuid.UUID]indexedDocument),
+	}
+}
+
+// add indexes a message, replacing its previous entry
+func (idx *searchIndex) add(sessionID uuid.UUID, message types.Message) {
+	idx.remove(message.ID)
+
+	document := indexedDocument{
+		sessionID: sessionID,
+		text:      searchDocument(message),
+		terms:     make(map[string]int),
+	}
+	for _, word := range text.Words(document.text) {
+		document.terms[text.Stem(word)]++
+		document.length++
+	}
+
+	for term, count := range document.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[uuid.UUID]int)
+		}
+		idx.postings[term][message.ID] = count
+	}
+	idx.documents[message.ID] = document
+	idx.totalLength += document.length
+}
+
+// remove drops a message from the index
+func (idx *searchIndex) remove(messageID uuid.UUID) {
+	document, ok := idx.documents[messageID]
+	if !ok {
+		return
+	}
+
+	for term := range document.terms {
+		delete(idx.postings[term], messageID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.documents, messageID)
+	idx.totalLength -= document.length
+}
+
+// search returns up to limit messages matching any of the terms, best first
+func (idx *searchIndex) search(terms []string, limit int) []scoredMessage {
+	const k1, b = 1.2, 0.75
+	if len(idx.documents) == 0 {
+		return nil
+	}
+
+	stems := make(map[string]bool, len(terms))
+	scores := make(map[uuid.UUID]float64)
+	averageLength := float64(idx.totalLength) / float64(len(idx.documents))
+	for _, term := range terms {
+		stemmed := text.Stem(term)
+		if stems[stemmed] {
+			continue
+		}
+		stems[stemmed] = true
+
+		postings := idx.postings[stemmed]
+		idf := math.Log(1 + (float64(len(idx.documents))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for messageID, frequency := range postings {
+			length := float64(idx.documents[messageID].length)
+			tf := float64(frequency)
+			scores[messageID] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/averageLength))
+		}
+	}
+
+	matches := make([]scoredMessage, 0, len(scores))
+	for messageID, score := range scores {
+		matches = append(matches, scoredMessage{
+			sessionID: idx.documents[messageID].sessionID,
+			messageID: messageID,
+			score:     score,
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].messageID.String() < matches[j].messageID.String()
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	for i := range matches {
+		matches[i].snippet = highlight(idx.documents[matches[i].messageID].text, stems)
+	}
+	return matches
+}
+
+// highlight excerpts the words of document around its first match, marking the words whose stems are in stems
+func highlight(document string, stems map[string]bool) string {
+	words := strings.Fields(document)
+	matched := func(word string) bool {
+		for _, part := range text.Words(word) {
+			if stems[text.Stem(part)] {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, word := range words {
+		if matched(word) {
+			start = max(0, i-snippetWords/3)
+			break
+		}
+	}
+	end := min(len(words), start+snippetWords)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		if matched(words[i]) {
+			b.WriteString(matchStart + words[i] + matchEnd)
+		} else {
+			b.WriteString(words[i])
+		}
+	}
+	if end < len(words) {
+		b.WriteString("…")
+	}
+	return renderSnippet(b.String())
+}
+
+// renderSnippet HTML-escapes a raw snippet and turns its match delimiters into highlight markers.
+// Snippets are rendered as HTML and message text is user input, so the highlight markers must be
+// the only markup; delimiters within the text itself only ever open or close a highlight.
+func renderSnippet(snippet string) string {
+	var b strings.Builder
+	open := false
+	for snippet != "" {
+		i := strings.IndexAny(snippet, matchStart+matchEnd)
+		if i < 0 {
+			b.WriteString(html.EscapeString(snippet))
+			break
+		}
+		b.WriteString(html.EscapeString(snippet[:i]))
+
+		if delimiter := snippet[i : i+len(matchStart)]; delimiter == matchStart && !open {
+			b.WriteString(highlightStart)
+			open = true
+		} else if delimiter == matchEnd && open {
+			b.WriteString(highlightEnd)
+			open = false
+		}
+		snippet = snippet[i+len(matchStart):]
+	}
+	if open {
+		b.WriteString(highlightEnd)
+	}
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"podscription-api/types"
+)
+
+// searchStores opens an empty store of every kind that runs without external services
+func searchStores(t *testing.T) map[string]Store {
+	sqlite, _ := newTestSQLiteStore(t)
+	return map[string]Store{
+		"memory": newTestMemoryStore(t, ""),
+		"sqlite": sqlite,
+	}
+}
+
+func TestSearch(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			session, err := store.CreateSession("checkout outage")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			messages := []types.Message{
+				{Role: types.MessageRoleUser, Content: "checkout pods are crashing after the deploy"},
+				{
+					Role:         types.MessageRoleAssistant,
+					Content:      "The container exceeds its memory limit.",
+					Intent:       &types.PodIntent{Category: types.IntentCategoryPodIssues, Symptoms: []string{"OOMKilled"}},
+					Prescription: &types.Prescription{Diagnosis: "Memory exhaustion", Commands: []string{"kubectl top pod"}},
+				},
+				{Role: types.MessageRoleUser, Content: "DNS lookups for the payments service time out"},
+			}
+			for _, message := range messages {
+				if err := store.AddMessage(session.ID, message); err != nil {
+					t.Fatalf("failed to add message: %v", err)
+				}
+			}
+			stored, err := store.GetSession(session.ID)
+			if err != nil {
+				t.Fatalf("failed to get session: %v", err)
+			}
+
+			tests := []struct {
+				name  string
+				query string
+				want  int
+			}{
+				{"content", "crashing", 0},
+				{"stemmed", "crashes", 0},
+				{"symptom", "oomkilled", 1},
+				{"diagnosis", "exhaustion", 1},
+				{"command", "kubectl", 1},
+				{"any word", "payments unrelated", 2},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					hits, err := store.Search(SearchQuery{Text: tt.query, Limit: 10})
+					if err != nil {
+						t.Fatalf("failed to search: %v", err)
+					}
+					if len(hits) != 1 {
+						t.Fatalf("got %d hits, want 1", len(hits))
+					}
+					hit := hits[0]
+					if hit.MessageID != stored.Messages[tt.want].ID || hit.SessionID != session.ID || hit.SessionName != "checkout outage" {
+						t.Fatalf("got hit %+v, want message %d", hit, tt.want)
+					}
+					if !strings.Contains(hit.Snippet, highlightStart) {
+						t.Fatalf("got snippet %q, want the match highlighted", hit.Snippet)
+					}
+				})
+			}
+
+			for _, query := range []string{"", "the and of", "kubernetes"} {
+				hits, err := store.Search(SearchQuery{Text: query, Limit: 10})
+				if err != nil || len(hits) != 0 {
+					t.Fatalf("got %d hits (%v) for %q, want none", len(hits), err, query)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRanksBestMatchFirst(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			session, err := store.CreateSession("")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			for _, content := range []string{
+				"the ingress returns 502 for some requests",
+				"ingress 502 on every request; the ingress controller logs upstream 502 errors",
+			} {
+				if err := store.AddMessage(session.ID, types.Message{Role: types.MessageRoleUser, Content: content}); err != nil {
+					t.Fatalf("failed to add message: %v", err)
+				}
+			}
+
+			hits, err := store.Search(SearchQuery{Text: "ingress 502", Limit: 1})
+			if err != nil || len(hits) != 1 {
+				t.Fatalf("got %d hits (%v), want the limit of 1", len(hits), err)
+			}
+			if !strings.Contains(hits[0].Snippet, "controller") {
+				t.Fatalf("got snippet %q, want the message with more matches first", hits[0].Snippet)
+			}
+		})
+	}
+}
+
+func TestSearchEscapesSnippets(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			session, err := store.CreateSession("")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			content := `the dashboard shows <script>alert(1)</script> instead of the crashing pods`
+			if err := store.AddMessage(session.ID, types.Message{Role: types.MessageRoleUser, Content: content}); err != nil {
+				t.Fatalf("failed to add message: %v", err)
+			}
+
+			for _, query := range []string{"crashing", "script"} {
+				hits, err := store.Search(SearchQuery{Text: query, Limit: 10})
+				if err != nil || len(hits) != 1 {
+					t.Fatalf("got %d hits (%v) for %q, want 1", len(hits), err, query)
+				}
+				snippet := hits[0].Snippet
+				if !strings.Contains(snippet, "&lt;") || !strings.Contains(snippet, "&gt;") {
+					t.Fatalf("got snippet %q for %q, want the script tags escaped", snippet, query)
+				}
+				// Only the highlight markers may remain as markup
+				markup := strings.NewReplacer(highlightStart, "", highlightEnd, "").Replace(snippet)
+				if strings.ContainsAny(markup, "<>") {
+					t.Fatalf("got snippet %q for %q, want no markup but the highlights", snippet, query)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "pod is ready", "pod is ready"},
+		{"highlight", "pod " + matchStart + "crashing" + matchEnd + " again", "pod <mark>crashing</mark> again"},
+		{"escaped", matchStart + "<b>" + matchEnd + ` & "x"`, "<mark>&lt;b&gt;</mark> &amp; &#34;x&#34;"},
+		{"unclosed", matchStart + "crash", "<mark>crash</mark>"},
+		{"stray end", "crash" + matchEnd, "crash"},
+		{"nested start", matchStart + "a" + matchStart + "b" + matchEnd, "<mark>ab</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderSnippet(tt.raw); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// sessionColumns selects a session without its messages, in scanSession order
+const sessionColumns = `id, name, bundle_id, archived, created_at, updated_at, summary, plan, resolution`
+
+// messageColumns selects a message with its intent and prescription, in scanMessage order
+const messageColumns = `m.id, m.session_id, m.role, m.content, m.created_at, m.attachments, m.prompt_version, m.analyzer, m.sources, m.model, m.feedback,
+	i.category, i.confidence, i.categories, i.symptoms, i.source,
+	p.diagnosis, p.explanation, p.treatment, p.steps, p.commands, p.follow_up, p.closing
+	FROM messages m
+	LEFT JOIN intents i ON i.message_id = m.id
+	LEFT JOIN prescriptions p ON p.message_id = m.id`
+
+// assignments renders the SET clauses of the attributes an update changes; placeholder renders
+// the nth bind parameter
+func (u SessionUpdate) assignments(placeholder func(n int) string) ([]string, []interface{}) {
+	var set []string
+	var args []interface{}
+	assign := func(column string, value interface{}) {
+		args = append(args, value)
+		set = append(set, column+" = "+placeholder(len(args)))
+	}
+
+	if u.Name != nil {
+		assign("name", *u.Name)
+	}
+	if u.Archived != nil {
+		assign("archived", *u.Archived)
+	}
+	if u.BundleID != nil {
+		assign("bundle_id", u.BundleID.String())
+	}
+	return set, args
+}
+
+// rowScanner is satisfied by the rows of database/sql and pgx
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowIterator is satisfied by *sql.Rows and pgx.Rows
+type rowIterator interface {
+	rowScanner
+	Next() bool
+	Err() error
+}
+
+// scanIDs reads rows of a single ID column
+func scanIDs(rows rowIterator) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("failed to read ID: %w", err)
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q: %w", raw, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// scanSession reads a session row without its messages
+func scanSession(row rowScanner) (*types.Session, error) {
+	var id string
+	var bundleID, summary, plan, resolution sql.NullString
+	var createdAt, updatedAt timestampColumn
+	session := &types.Session{Messages: make([]types.Message, 0)}
+
+	if err := row.Scan(&id, &session.Name, &bundleID, &session.Archived, &createdAt, &updatedAt, &summary, &plan, &resolution); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if session.ID, err = uuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if bundleID.Valid {
+		parsed, err := uuid.Parse(bundleID.String)
+		if err != nil {
+			return nil, err
+		}
+		session.BundleID = &parsed
+	}
+	session.CreatedAt = createdAt.Time
+	session.UpdatedAt = updatedAt.Time
+	if err := decodeJSON(summary, &session.Summary); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(plan, &session.Plan); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(resolution, &session.Resolution); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// scanSummary reads a row selected by SessionQuery.summaryQuery
+func scanSummary(row rowScanner) (*types.SessionSummary, error) {
+	var id string
+	var bundleID, lastIntent, lastDiagnosis sql.NullString
+	var createdAt, updatedAt timestampColumn
+	summary := &types.SessionSummary{}
+
+	err := row.Scan(&id, &summary.Name, &bundleID, &summary.Archived, &createdAt, &updatedAt,
+		&summary.MessageCount, &lastIntent, &lastDiagnosis)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.ID, err = uuid.Parse(id); err != nil {
+		return nil, err
+	}
+	if bundleID.Valid {
+		parsed, err := uuid.Parse(bundleID.String)
+		if err != nil {
+			return nil, err
+		}
+		summary.BundleID = &parsed
+	}
+	summary.CreatedAt = createdAt.Time
+	summary.UpdatedAt = updatedAt.Time
+	summary.LastIntent = types.IntentCategory(lastIntent.String)
+	summary.LastDiagnosis = lastDiagnosis.String
+
+	return summary, nil
+}
+
+// scanSearchHit reads a search hit row: message ID, session ID and name, role, timestamp, raw snippet and score
+func scanSearchHit(row rowScanner) (*types.SearchHit, error) {
+	var messageID, sessionID, role string
+	var timestamp timestampColumn
+	hit := &types.SearchHit{}
+
+	if err := row.Scan(&messageID, &sessionID, &hit.SessionName, &role, &timestamp, &hit.Snippet, &hit.Score); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if hit.MessageID, err = uuid.Parse(messageID); err != nil {
+		return nil, err
+	}
+	if hit.SessionID, err = uuid.Parse(sessionID); err != nil {
+		return nil, err
+	}
+	hit.Role = types.MessageRole(role)
+	hit.Timestamp = timestamp.Time
+	hit.Snippet = renderSnippet(hit.Snippet)
+
+	return hit, nil
+}
+
+// scanMessage reads a row selected with messageColumns, returning the message and its session ID
+func scanMessage(row rowScanner) (*types.Message, uuid.UUID, error) {
+	var id, sessionID, role string
+	var createdAt timestampColumn
+	var attachments, sources, feedback sql.NullString
+	var category, categories, symptoms, source sql.NullString
+	var confidence sql.NullFloat64
+	var diagnosis, explanation, treatment, steps, commands, followUp, closing sql.NullString
+	message := &types.Message{}
+
+	err := row.Scan(&id, &sessionID, &role, &message.Content, &createdAt, &attachments, &message.PromptVersion, &message.Analyzer, &sources, &message.Model, &feedback,
+		&category, &confidence, &categories, &symptoms, &source,
+		&diagnosis, &explanation, &treatment, &steps, &commands, &followUp, &closing)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	if message.ID, err = uuid.Parse(id); err != nil {
+		return nil, uuid.Nil, err
+	}
+	session, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	message.Role = types.MessageRole(role)
+	message.Timestamp = createdAt.Time
+	if err := decodeJSON(attachments, &message.Attachments); err != nil {
+		return nil, uuid.Nil, err
+	}
+	if err := decodeJSON(sources, &message.Sources); err != nil {
+		return nil, uuid.Nil, err
+	}
+	if err := decodeJSON(feedback, &message.Feedback); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	if category.Valid {
+		message.Intent = &types.PodIntent{
+			Category:   types.IntentCategory(category.String),
+			Confidence: confidence.Float64,
+			Source:     types.IntentSource(source.String),
+		}
+		if err := decodeJSON(categories, &message.Intent.Categories); err != nil {
+			return nil, uuid.Nil, err
+		}
+		if err := decodeJSON(symptoms, &message.Intent.Symptoms); err != nil {
+			return nil, uuid.Nil, err
+		}
+	}
+
+	if diagnosis.Valid {
+		message.Prescription = &types.Prescription{
+			Diagnosis:   diagnosis.String,
+			Explanation: explanation.String,
+			Treatment:   treatment.String,
+			FollowUp:    followUp.String,
+			Closing:     closing.String,
+		}
+		if err := decodeJSON(steps, &message.Prescription.Steps); err != nil {
+			return nil, uuid.Nil, err
+		}
+		if err := decodeJSON(commands, &message.Prescription.Commands); err != nil {
+			return nil, uuid.Nil, err
+		}
+	}
+
+	return message, session, nil
+}
+
+// encodeJSON stores values as JSON text, and nil values as NULL
+func encodeJSON(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
+
+// decodeJSON reads a column written by encodeJSON
+func decodeJSON(column sql.NullString, value interface{}) error {
+	if !column.Valid {
+		return nil
+	}
+	return json.Unmarshal([]byte(column.String), value)
+}
+
+// nullableUUID stores optional IDs as NULL
+func nullableUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+// timestampColumn scans the Unix nanoseconds SQLite stores and the timestamps Postgres returns
+type timestampColumn struct {
+	time.Time
+}
+
+// Scan implements sql.Scanner
+func (t *timestampColumn) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		t.Time = time.Unix(0, v)
+	case time.Time:
+		t.Time = v
+	default:
+		return fmt.Errorf("unsupported timestamp value %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,658 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+	"podscription-api/types"
+)
+
+// sqliteMigrations are applied in order and recorded in schema_migrations; append new
+// migrations to the end and never edit one that has been released
+var sqliteMigrations = []string{
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		bundle_id  TEXT,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX sessions_updated_at ON sessions (updated_at);
+
+	CREATE TABLE messages (
+		id             TEXT PRIMARY KEY,
+		session_id     TEXT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		position       INTEGER NOT NULL,
+		role           TEXT NOT NULL,
+		content        TEXT NOT NULL,
+		created_at     INTEGER NOT NULL,
+		attachments    TEXT,
+		prompt_version TEXT NOT NULL DEFAULT '',
+		analyzer       TEXT NOT NULL DEFAULT '',
+		UNIQUE (session_id, position)
+	);
+
+	CREATE TABLE intents (
+		message_id TEXT PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+		category   TEXT NOT NULL,
+		confidence REAL NOT NULL,
+		categories TEXT,
+		symptoms   TEXT,
+		source     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX intents_category ON intents (category);
+
+	CREATE TABLE prescriptions (
+		message_id  TEXT PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+		diagnosis   TEXT NOT NULL,
+		explanation TEXT NOT NULL DEFAULT '',
+		treatment   TEXT NOT NULL DEFAULT '',
+		steps       TEXT,
+		commands    TEXT,
+		follow_up   TEXT NOT NULL DEFAULT '',
+		closing     TEXT NOT NULL DEFAULT ''
+	);`,
+	`ALTER TABLE sessions ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;`,
+	`DROP INDEX sessions_updated_at;
+	CREATE INDEX sessions_archived_updated_at ON sessions (archived, updated_at, id);`,
+	`CREATE VIRTUAL TABLE message_search USING fts5 (message_id UNINDEXED, document, tokenize = 'porter unicode61');
+	CREATE TRIGGER messages_search_delete AFTER DELETE ON messages BEGIN
+		DELETE FROM message_search WHERE message_id = old.id;
+	END;
+	INSERT INTO message_search (message_id, document)
+	SELECT m.id, concat_ws(char(10), m.content, p.diagnosis,
+		(SELECT group_concat(value, ' ') FROM json_each(i.symptoms)),
+		(SELECT group_concat(value, char(10)) FROM json_each(p.commands)))
+	FROM messages m
+	LEFT JOIN intents i ON i.message_id = m.id
+	LEFT JOIN prescriptions p ON p.message_id = m.id;`,
+	`ALTER TABLE messages ADD COLUMN sources TEXT;`,
+	`ALTER TABLE sessions ADD COLUMN summary TEXT;`,
+	`ALTER TABLE sessions ADD COLUMN plan TEXT;`,
+	`ALTER TABLE sessions ADD COLUMN resolution TEXT;`,
+	`ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE messages ADD COLUMN feedback TEXT;`,
+}
+
+// SQLiteStore implements Store on a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at path and migrates its schema
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY between our own requests
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies the migrations newer than the database's schema version
+func (s *SQLiteStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for version := current + 1; version <= len(sqliteMigrations); version++ {
+		err := s.withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(sqliteMigrations[version-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UnixNano())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateSession creates a new session
+func (s *SQLiteStore) CreateSession(name string) (*types.Session, error) {
+	now := time.Now()
+	session := &types.Session{
+		ID:        uuid.New(),
+		Name:      name,
+		Messages:  make([]types.Message, 0),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		if session.Name == "" {
+			var count int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+				return err
+			}
+			session.Name = fmt.Sprintf("Session %d", count+1)
+		}
+		_, err := tx.Exec(`INSERT INTO sessions (id, name, bundle_id, archived, created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?)`,
+			session.ID.String(), session.Name, nil, now.UnixNano(), now.UnixNano())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession retrieves a session by ID
+func (s *SQLiteStore) GetSession(id uuid.UUID) (*types.Session, error) {
+	row := s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id.String())
+	session, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	rows, err := s.db.Query(`SELECT `+messageColumns+` WHERE m.session_id = ? ORDER BY m.position`, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		message, _, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		session.Messages = append(session.Messages, *message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return session, nil
+}
+
+// UpdateSession changes the attributes set in update
+func (s *SQLiteStore) UpdateSession(id uuid.UUID, update SessionUpdate) error {
+	set, args := update.assignments(func(int) string { return "?" })
+	set = append(set, "updated_at = ?")
+	args = append(args, time.Now().UnixNano(), id.String())
+
+	result, err := s.db.Exec(`UPDATE sessions SET `+strings.Join(set, ", ")+` WHERE id = ?`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// SetSummary replaces a session's rolling conversation summary
+func (s *SQLiteStore) SetSummary(id uuid.UUID, summary *types.ConversationSummary) error {
+	result, err := s.db.Exec(`UPDATE sessions SET summary = ?, updated_at = ? WHERE id = ?`,
+		encodeJSON(summary), time.Now().UnixNano(), id.String())
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// UpdatePlan replaces a session's treatment plan with the one fn derives from the current plan.
+// The store's single connection serializes the transaction with every other write.
+func (s *SQLiteStore) UpdatePlan(id uuid.UUID, fn PlanFunc) (*types.TreatmentPlan, error) {
+	return updateSQLiteColumn(s, id, "plan", fn)
+}
+
+// UpdateResolution replaces a session's resolution with the one fn derives from the current one
+func (s *SQLiteStore) UpdateResolution(id uuid.UUID, fn ResolutionFunc) (*types.Resolution, error) {
+	return updateSQLiteColumn(s, id, "resolution", fn)
+}
+
+// updateSQLiteColumn replaces a JSON column of a session with what fn derives from its current value
+func updateSQLiteColumn[T any](s *SQLiteStore, id uuid.UUID, column string, fn func(*T) (*T, error)) (*T, error) {
+	var updated *T
+	err := s.withTx(func(tx *sql.Tx) error {
+		var raw sql.NullString
+		err := tx.QueryRow(`SELECT `+column+` FROM sessions WHERE id = ?`, id.String()).Scan(&raw)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read session: %w", err)
+		}
+
+		var current *T
+		if err := decodeJSON(raw, &current); err != nil {
+			return fmt.Errorf("failed to decode session %s: %w", column, err)
+		}
+		if updated, err = fn(current); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`UPDATE sessions SET `+column+` = ?, updated_at = ? WHERE id = ?`,
+			encodeJSON(updated), time.Now().UnixNano(), id.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetMessageFeedback replaces the feedback on a message of a session
+func (s *SQLiteStore) SetMessageFeedback(sessionID, messageID uuid.UUID, feedback *types.Feedback) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE messages SET feedback = ? WHERE id = ? AND session_id = ?`,
+			encodeJSON(feedback), messageID.String(), sessionID.String())
+		if err != nil {
+			return fmt.Errorf("failed to update message: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+
+		if _, err := tx.Exec(`UPDATE sessions SET updated_at = ? WHERE id = ?`, time.Now().UnixNano(), sessionID.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListSessions returns all sessions, most recently updated first
+func (s *SQLiteStore) ListSessions() ([]*types.Session, error) {
+	rows, err := s.db.Query(`SELECT ` + sessionColumns + ` FROM sessions ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*types.Session, 0)
+	byID := make(map[uuid.UUID]*types.Session)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		sessions = append(sessions, session)
+		byID[session.ID] = session
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	messageRows, err := s.db.Query(`SELECT ` + messageColumns + ` ORDER BY m.session_id, m.position`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	defer messageRows.Close()
+
+	for messageRows.Next() {
+		message, sessionID, err := scanMessage(messageRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		if session, ok := byID[sessionID]; ok {
+			session.Messages = append(session.Messages, *message)
+		}
+	}
+	if err := messageRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// ListSessionSummaries returns up to query.Limit summaries of the sessions matching query
+func (s *SQLiteStore) ListSessionSummaries(query SessionQuery) ([]types.SessionSummary, error) {
+	statement, args := query.summaryQuery(
+		func(int) string { return "?" },
+		"LIKE",
+		func(t time.Time) interface{} { return t.UnixNano() },
+	)
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]types.SessionSummary, 0)
+	for rows.Next() {
+		summary, err := scanSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session: %w", err)
+		}
+		summaries = append(summaries, *summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// ExpiredSessions returns the IDs of the sessions last updated before cutoff
+func (s *SQLiteStore) ExpiredSessions(cutoff time.Time, includeArchived bool) ([]uuid.UUID, error) {
+	query := `SELECT id FROM sessions WHERE updated_at < ?`
+	if !includeArchived {
+		query += ` AND archived = 0`
+	}
+
+	rows, err := s.db.Query(query, cutoff.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired sessions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIDs(rows)
+}
+
+// ResolutionTallies counts the sessions with messages by intent category and resolution status
+func (s *SQLiteStore) ResolutionTallies() ([]ResolutionTally, error) {
+	rows, err := s.db.Query(`SELECT category, status, COUNT(*),
+		CASE WHEN status = 'resolved' THEN json_group_array(time_to_resolution) END
+		FROM (
+			SELECT COALESCE((SELECT i.category FROM messages m JOIN intents i ON i.message_id = m.id
+					WHERE m.session_id = s.id ORDER BY m.position LIMIT 1), 'general') AS category,
+				COALESCE(json_extract(s.resolution, '$.status'), 'open') AS status,
+				COALESCE(json_extract(s.resolution, '$.timeToResolution'), 0) AS time_to_resolution
+			FROM sessions s
+			WHERE EXISTS (SELECT 1 FROM messages m WHERE m.session_id = s.id)
+		)
+		GROUP BY category, status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	return readResolutionTallies(rows)
+}
+
+// sqliteNegativeFeedback holds for a thumbs down or feedback flagging commands
+const sqliteNegativeFeedback = `(json_extract(m.feedback, '$.rating') = 'down' OR json_array_length(m.feedback, '$.wrongCommands') > 0)`
+
+// FeedbackGroups aggregates the feedback on messages by prompt version, model, analyzer and intent category
+func (s *SQLiteStore) FeedbackGroups(examples int) ([]types.FeedbackGroup, error) {
+	groups := make(feedbackGroups)
+	queries := []struct {
+		query string
+		args  []interface{}
+		read  func(rows rowIterator) error
+	}{
+		{
+			query: `SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, ''),
+				COUNT(*), SUM(CASE WHEN ` + sqliteNegativeFeedback + ` THEN 1 ELSE 0 END)
+				FROM messages m
+				LEFT JOIN intents i ON i.message_id = m.id
+				WHERE m.feedback IS NOT NULL
+				GROUP BY 1, 2, 3, 4`,
+			read: groups.readCounts,
+		},
+		{
+			query: `SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, ''), c.value, COUNT(*)
+				FROM messages m
+				LEFT JOIN intents i ON i.message_id = m.id
+				JOIN json_each(m.feedback, '$.wrongCommands') c
+				WHERE m.feedback IS NOT NULL
+				GROUP BY 1, 2, 3, 4, 5`,
+			read: groups.readWrongCommands,
+		},
+		{
+			query: `SELECT prompt_version, model, analyzer, category, session_id, id, diagnosis, feedback
+				FROM (
+					SELECT m.prompt_version, m.model, m.analyzer, COALESCE(i.category, '') AS category,
+						m.session_id, m.id, COALESCE(p.diagnosis, '') AS diagnosis, m.feedback,
+						ROW_NUMBER() OVER (PARTITION BY m.prompt_version, m.model, m.analyzer, COALESCE(i.category, '')
+							ORDER BY julianday(json_extract(m.feedback, '$.updatedAt')) DESC) AS rank
+					FROM messages m
+					LEFT JOIN intents i ON i.message_id = m.id
+					LEFT JOIN prescriptions p ON p.message_id = m.id
+					WHERE m.feedback IS NOT NULL AND ` + sqliteNegativeFeedback + `
+				)
+				WHERE rank <= ?`,
+			args: []interface{}{examples},
+			read: groups.readExamples,
+		},
+	}
+
+	// The store has a single connection, so each query is read to the end before the next
+	for _, q := range queries {
+		rows, err := s.db.Query(q.query, q.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to group feedback: %w", err)
+		}
+		err = q.read(rows)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to group feedback: %w", err)
+		}
+	}
+	return groups.list(examples), nil
+}
+
+// Search returns up to query.Limit messages matching any word of query.Text, ranked by BM25
+func (s *SQLiteStore) Search(query SearchQuery) ([]types.SearchHit, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []types.SearchHit{}, nil
+	}
+	// Quoting every term keeps FTS5 operators and syntax in the user's text literal
+	match := `"` + strings.Join(terms, `" OR "`) + `"`
+
+	rows, err := s.db.Query(`SELECT f.message_id, s.id, s.name, m.role, m.created_at,
+		snippet(message_search, 1, ?, ?, '…', ?), -bm25(message_search)
+		FROM message_search f
+		JOIN messages m ON m.id = f.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE message_search MATCH ?
+		ORDER BY bm25(message_search)
+		LIMIT ?`, matchStart, matchEnd, snippetWords, match, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]types.SearchHit, 0)
+	for rows.Next() {
+		hit, err := scanSearchHit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read search hit: %w", err)
+		}
+		hits = append(hits, *hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	return hits, nil
+}
+
+// AddMessage appends a message to a session
+func (s *SQLiteStore) AddMessage(sessionID uuid.UUID, message types.Message) error {
+	newMessageDefaults(&message)
+
+	return s.withTx(func(tx *sql.Tx) error {
+		var position int
+		err := tx.QueryRow(`SELECT (SELECT COALESCE(MAX(position) + 1, 0) FROM messages WHERE session_id = ?1) FROM sessions WHERE id = ?1`,
+			sessionID.String()).Scan(&position)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read session: %w", err)
+		}
+
+		if err := insertMessage(tx, sessionID, position, message); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`UPDATE sessions SET updated_at = ? WHERE id = ?`, message.Timestamp.UnixNano(), sessionID.String()); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteSession removes a session; its messages are removed with it
+func (s *SQLiteStore) DeleteSession(id uuid.UUID) error {
+	result, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return nil
+}
+
+// ImportJSON copies the sessions of a memory store file into the database when it has no
+// sessions yet, then renames the file to <path>.imported so that it is imported only once.
+// It returns how many sessions were imported; a missing file imports nothing.
+func (s *SQLiteStore) ImportJSON(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read sessions from %s: %w", path, err)
+	}
+
+	var sessions map[uuid.UUID]*types.Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return 0, fmt.Errorf("failed to decode sessions from %s: %w", path, err)
+	}
+
+	imported := 0
+	err = s.withTx(func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count sessions: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+
+		for _, session := range sessions {
+			_, err := tx.Exec(`INSERT INTO sessions (id, name, bundle_id, archived, created_at, updated_at, summary, plan, resolution)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				session.ID.String(), session.Name, nullableUUID(session.BundleID), session.Archived, session.CreatedAt.UnixNano(),
+				session.UpdatedAt.UnixNano(), encodeJSON(session.Summary), encodeJSON(session.Plan), encodeJSON(session.Resolution))
+			if err != nil {
+				return fmt.Errorf("failed to insert session: %w", err)
+			}
+			for position, message := range session.Messages {
+				if err := insertMessage(tx, session.ID, position, message); err != nil {
+					return err
+				}
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil || imported == 0 {
+		return 0, err
+	}
+
+	if err := os.Rename(path, path+".imported"); err != nil {
+		return imported, fmt.Errorf("failed to rename imported sessions file: %w", err)
+	}
+	return imported, nil
+}
+
+// withTx runs fn in a transaction, committing when it succeeds
+func (s *SQLiteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertMessage writes a message with its intent and prescription at the given position
+func insertMessage(tx *sql.Tx, sessionID uuid.UUID, position int, message types.Message) error {
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+
+	_, err := tx.Exec(`INSERT INTO messages (id, session_id, position, role, content, created_at, attachments, prompt_version, analyzer, sources, model, feedback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		message.ID.String(), sessionID.String(), position, string(message.Role), message.Content, message.Timestamp.UnixNano(),
+		encodeJSON(message.Attachments), message.PromptVersion, message.Analyzer, encodeJSON(message.Sources), message.Model, encodeJSON(message.Feedback))
+	if err != nil {
+		return fmt.Errorf("failed to insert message: %w", err)
+	}
+
+	return insertMessageDetails(tx, message)
+}
+
+// insertMessageDetails writes a message's intent, prescription and search document
+func insertMessageDetails(tx *sql.Tx, message types.Message) error {
+	if _, err := tx.Exec(`INSERT INTO message_search (message_id, document) VALUES (?, ?)`, message.ID.String(), searchDocument(message)); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+
+	if intent := message.Intent; intent != nil {
+		_, err := tx.Exec(`INSERT INTO intents (message_id, category, confidence, categories, symptoms, source) VALUES (?, ?, ?, ?, ?, ?)`,
+			message.ID.String(), string(intent.Category), intent.Confidence, encodeJSON(intent.Categories), encodeJSON(intent.Symptoms), string(intent.Source))
+		if err != nil {
+			return fmt.Errorf("failed to insert intent: %w", err)
+		}
+	}
+
+	if p := message.Prescription; p != nil {
+		_, err := tx.Exec(`INSERT INTO prescriptions (message_id, diagnosis, explanation, treatment, steps, commands, follow_up, closing)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			message.ID.String(), p.Diagnosis, p.Explanation, p.Treatment, encodeJSON(p.Steps), encodeJSON(p.Commands), p.FollowUp, p.Closing)
+		if err != nil {
+			return fmt.Errorf("failed to insert prescription: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"podscription-api/types"
+)
+
+// newTestSQLiteStore opens a SQLite store in a temporary directory, closed when the test ends
+func newTestSQLiteStore(t *testing.T) (*SQLiteStore, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "podscription.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func TestSQLiteSessionRoundTrip(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	session, err := store.CreateSession("crashing web pod")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	question := types.Message{Role: types.MessageRoleUser, Content: "web-1 is in CrashLoopBackOff"}
+	answer := types.Message{
+		Role:    types.MessageRoleAssistant,
+		Content: "## 🩺 Diagnosis: Recurrent Container Arrest",
+		Intent: &types.PodIntent{
+			Category:   types.IntentCategoryPodIssues,
+			Confidence: 0.9,
+			Symptoms:   []string{"CrashLoopBackOff"},
+		},
+		Prescription: &types.Prescription{
+			Diagnosis: "Recurrent Container Arrest",
+			Steps:     []types.TreatmentStep{{Title: "Read the logs", Command: "kubectl logs web-1 --previous", Risk: types.RiskLevelLow}},
+			Commands:  []string{"kubectl logs web-1 --previous"},
+			FollowUp:  "Add a liveness probe.",
+		},
+	}
+	for _, message := range []types.Message{question, answer} {
+		if err := store.AddMessage(session.ID, message); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if stored.Name != "crashing web pod" || !stored.CreatedAt.Equal(session.CreatedAt) {
+		t.Fatalf("got session %q created %v, want %q created %v", stored.Name, stored.CreatedAt, session.Name, session.CreatedAt)
+	}
+	if len(stored.Messages) != 2 || stored.Messages[0].Content != question.Content || stored.Messages[1].Content != answer.Content {
+		t.Fatalf("got messages %+v, want the question and the answer in order", stored.Messages)
+	}
+	if stored.Messages[0].Intent != nil || stored.Messages[0].Prescription != nil {
+		t.Fatalf("got %+v, want no intent or prescription on the question", stored.Messages[0])
+	}
+
+	got := stored.Messages[1]
+	if got.ID.String() == "" || got.Timestamp.IsZero() {
+		t.Fatalf("got message %+v, want an ID and a timestamp", got)
+	}
+	if got.Intent == nil || got.Intent.Category != types.IntentCategoryPodIssues || got.Intent.Confidence != 0.9 || len(got.Intent.Symptoms) != 1 {
+		t.Fatalf("got intent %+v, want %+v", got.Intent, answer.Intent)
+	}
+	if p := got.Prescription; p == nil || p.Diagnosis != answer.Prescription.Diagnosis || len(p.Steps) != 1 || p.Steps[0].Risk != types.RiskLevelLow || p.FollowUp != answer.Prescription.FollowUp {
+		t.Fatalf("got prescription %+v, want %+v", got.Prescription, answer.Prescription)
+	}
+	if stored.UpdatedAt.Before(got.Timestamp) {
+		t.Fatalf("got updated %v before the last message at %v", stored.UpdatedAt, got.Timestamp)
+	}
+
+	sessions, err := store.ListSessions()
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || len(sessions[0].Messages) != 2 {
+		t.Fatalf("got %d sessions, want the one session with its messages", len(sessions))
+	}
+
+	if _, err := store.GetSession(uuid.New()); err == nil {
+		t.Fatal("got a session for an unknown ID, want an error")
+	}
+	if err := store.AddMessage(uuid.New(), question); err == nil {
+		t.Fatal("added a message to an unknown session, want an error")
+	}
+}
+
+func TestSQLiteUpdateSession(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	session, err := store.CreateSession("bundle")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	message := types.Message{ID: uuid.New(), Role: types.MessageRoleAssistant, Content: "opening"}
+	if err := store.AddMessage(session.ID, message); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+
+	// Binding the bundle changes nothing else
+	bundleID := uuid.New()
+	if err := store.UpdateSession(session.ID, SessionUpdate{BundleID: &bundleID}); err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+	if err := store.UpdateSession(uuid.New(), SessionUpdate{BundleID: &bundleID}); err == nil {
+		t.Fatal("updated a missing session, want an error")
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if stored.BundleID == nil || *stored.BundleID != bundleID || stored.Name != "bundle" {
+		t.Fatalf("got session %q bound to %v, want %q bound to %s", stored.Name, stored.BundleID, "bundle", bundleID)
+	}
+	if len(stored.Messages) != 1 || stored.Messages[0].ID != message.ID {
+		t.Fatalf("got messages %+v, want the opening message with its own ID", stored.Messages)
+	}
+}
+
+func TestSQLiteSetSummary(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	session, err := store.CreateSession("long conversation")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	summary := &types.ConversationSummary{
+		Findings: []string{"Reported: web-1 restarts", "Diagnosed: OOMKilled"},
+		Commands: []string{"kubectl top pod web-1"},
+		Through:  2,
+	}
+	if err := store.SetSummary(session.ID, summary); err != nil {
+		t.Fatalf("failed to set summary: %v", err)
+	}
+	if err := store.SetSummary(uuid.New(), summary); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got %v for a missing session, want ErrSessionNotFound", err)
+	}
+
+	// Renaming keeps the summary
+	name := "renamed"
+	if err := store.UpdateSession(session.ID, SessionUpdate{Name: &name}); err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if got := stored.Summary; got == nil || got.Through != 2 || len(got.Findings) != 2 || got.Commands[0] != "kubectl top pod web-1" {
+		t.Fatalf("got summary %+v, want it stored", got)
+	}
+}
+
+func TestSQLiteDeleteSessionCascades(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	deleted, err := store.CreateSession("deleted")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	kept, err := store.CreateSession("kept")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	answer := types.Message{
+		Role:         types.MessageRoleAssistant,
+		Content:      "check the logs",
+		Intent:       &types.PodIntent{Category: types.IntentCategoryPodIssues, Confidence: 0.9},
+		Prescription: &types.Prescription{Diagnosis: "OOMKilled"},
+	}
+	for _, id := range []uuid.UUID{deleted.ID, kept.ID} {
+		if err := store.AddMessage(id, answer); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	if err := store.DeleteSession(deleted.ID); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	if _, err := store.GetSession(deleted.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got %v, want ErrSessionNotFound", err)
+	}
+	if err := store.DeleteSession(deleted.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("got %v deleting twice, want ErrSessionNotFound", err)
+	}
+
+	// The messages, intents and prescriptions of the other session remain
+	for _, table := range []string{"messages", "intents", "prescriptions"} {
+		var count int
+		if err := store.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != 1 {
+			t.Fatalf("got %d rows in %s, want only the kept session's", count, table)
+		}
+	}
+	if stored, err := store.GetSession(kept.ID); err != nil || len(stored.Messages) != 1 {
+		t.Fatalf("got %+v (%v), want the kept session unchanged", stored, err)
+	}
+}
+
+func TestSQLiteExpiredSessions(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+
+	active, err := store.CreateSession("active")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	archived, err := store.CreateSession("archived")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	yes := true
+	if err := store.UpdateSession(archived.ID, SessionUpdate{Archived: &yes}); err != nil {
+		t.Fatalf("failed to archive session: %v", err)
+	}
+
+	if ids, err := store.ExpiredSessions(active.CreatedAt, true); err != nil || len(ids) != 0 {
+		t.Fatalf("got %v (%v), want nothing updated before the first session was created", ids, err)
+	}
+	cutoff := time.Now().Add(time.Second)
+	if ids, err := store.ExpiredSessions(cutoff, false); err != nil || len(ids) != 1 || ids[0] != active.ID {
+		t.Fatalf("got %v (%v), want only the active session", ids, err)
+	}
+	if ids, err := store.ExpiredSessions(cutoff, true); err != nil || len(ids) != 2 {
+		t.Fatalf("got %v (%v), want both sessions", ids, err)
+	}
+}
+
+func TestSQLiteMigrationsRerun(t *testing.T) {
+	store, path := newTestSQLiteStore(t)
+
+	session, err := store.CreateSession("before restart")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.AddMessage(session.ID, types.Message{Role: types.MessageRoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	store.Close()
+
+	// Opening an existing database applies no migration twice and keeps its data
+	for i := 0; i < 2; i++ {
+		reopened, err := NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("failed to reopen store: %v", err)
+		}
+
+		var applied, version int
+		if err := reopened.db.QueryRow(`SELECT COUNT(*), MAX(version) FROM schema_migrations`).Scan(&applied, &version); err != nil {
+			t.Fatalf("failed to read schema version: %v", err)
+		}
+		if applied != len(sqliteMigrations) || version != len(sqliteMigrations) {
+			t.Fatalf("got %d migrations up to version %d, want %d", applied, version, len(sqliteMigrations))
+		}
+
+		stored, err := reopened.GetSession(session.ID)
+		if err != nil {
+			t.Fatalf("failed to get session after reopening: %v", err)
+		}
+		if stored.Name != "before restart" || len(stored.Messages) != 1 {
+			t.Fatalf("got session %q with %d messages, want it unchanged", stored.Name, len(stored.Messages))
+		}
+		reopened.Close()
+	}
+}
+
+func TestSQLiteImportJSON(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "sessions.json")
+
+	// Sessions written by the memory store before switching to SQLite
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	memory, err := NewMemoryStore(jsonPath, 0, logger)
+	if err != nil {
+		t.Fatalf("failed to open memory store: %v", err)
+	}
+	session, err := memory.CreateSession("history")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	for _, message := range []types.Message{
+		{Role: types.MessageRoleUser, Content: "pod keeps restarting"},
+		{
+			Role:         types.MessageRoleAssistant,
+			Content:      "check the logs",
+			Intent:       &types.PodIntent{Category: types.IntentCategoryPodIssues, Confidence: 0.9},
+			Prescription: &types.Prescription{Diagnosis: "OOMKilled", Commands: []string{"kubectl logs web-1"}},
+		},
+	} {
+		if err := memory.AddMessage(session.ID, message); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+	if err := memory.Close(); err != nil {
+		t.Fatalf("failed to flush memory store: %v", err)
+	}
+
+	store, err := NewSQLiteStore(filepath.Join(dir, "podscription.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	imported, err := store.ImportJSON(jsonPath)
+	if err != nil {
+		t.Fatalf("failed to import sessions: %v", err)
+	}
+	if imported != 1 {
+		t.Fatalf("imported %d sessions, want 1", imported)
+	}
+
+	stored, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to get imported session: %v", err)
+	}
+	if stored.Name != "history" || len(stored.Messages) != 2 {
+		t.Fatalf("got session %q with %d messages", stored.Name, len(stored.Messages))
+	}
+	if p := stored.Messages[1].Prescription; p == nil || p.Diagnosis != "OOMKilled" {
+		t.Fatalf("got prescription %+v", p)
+	}
+	hits, err := store.Search(SearchQuery{Text: "OOMKilled", Limit: 10})
+	if err != nil || len(hits) != 1 || hits[0].MessageID != stored.Messages[1].ID {
+		t.Fatalf("got hits %+v (%v), want the imported answer indexed", hits, err)
+	}
+
+	// The file is set aside, so a restart does not import it again
+	if _, err := os.Stat(jsonPath); !os.IsNotExist(err) {
+		t.Fatalf("sessions file was not renamed: %v", err)
+	}
+	if _, err := os.Stat(jsonPath + ".imported"); err != nil {
+		t.Fatalf("imported sessions file is missing: %v", err)
+	}
+	imported, err = store.ImportJSON(jsonPath)
+	if err != nil || imported != 0 {
+		t.Fatalf("imported %d sessions (%v) from a missing file", imported, err)
+	}
+}
+
+func TestSQLiteImportJSONIntoUsedStore(t *testing.T) {
+	store, path := newTestSQLiteStore(t)
+	jsonPath := filepath.Join(filepath.Dir(path), "sessions.json")
+	if err := os.WriteFile(jsonPath, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("failed to write sessions file: %v", err)
+	}
+	if _, err := store.CreateSession("existing"); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	// A database that already has sessions is left alone
+	imported, err := store.ImportJSON(jsonPath)
+	if err != nil || imported != 0 {
+		t.Fatalf("imported %d sessions (%v) into a used store", imported, err)
+	}
+	if _, err := os.Stat(jsonPath); err != nil {
+		t.Fatalf("sessions file of a skipped import was moved: %v", err)
+	}
+}
+
+func TestUpdatePlan(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return newTestMemoryStore(t, "") },
+		"sqlite": func(t *testing.T) Store {
+			store, _ := newTestSQLiteStore(t)
+			return store
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			session, err := store.CreateSession("plan")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+
+			addStep := func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error) {
+				if plan == nil {
+					plan = &types.TreatmentPlan{}
+				}
+				plan.Steps = append(plan.Steps, types.PlanStep{ID: uuid.New(), Number: len(plan.Steps) + 1, Status: types.StepStatusPending})
+				return plan, nil
+			}
+			for range 2 {
+				if _, err := store.UpdatePlan(session.ID, addStep); err != nil {
+					t.Fatalf("failed to update plan: %v", err)
+				}
+			}
+
+			// An error from fn leaves the plan as it was, even when fn changed it first
+			failure := errors.New("step not found")
+			_, err = store.UpdatePlan(session.ID, func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error) {
+				plan.Steps[0].Status = types.StepStatusDone
+				return nil, failure
+			})
+			if !errors.Is(err, failure) {
+				t.Fatalf("got %v, want the error from fn", err)
+			}
+			if _, err := store.UpdatePlan(uuid.New(), addStep); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("got %v for a missing session, want ErrSessionNotFound", err)
+			}
+
+			stored, err := store.GetSession(session.ID)
+			if err != nil {
+				t.Fatalf("failed to get session: %v", err)
+			}
+			if stored.Plan == nil || len(stored.Plan.Steps) != 2 || stored.Plan.Steps[1].Number != 2 {
+				t.Fatalf("got plan %+v, want both steps", stored.Plan)
+			}
+			if stored.Plan.Steps[0].Status != types.StepStatusPending {
+				t.Fatalf("got step %+v, want the failed update discarded", stored.Plan.Steps[0])
+			}
+		})
+	}
+}
+
+func TestUpdateResolution(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			session, err := store.CreateSession("resolution")
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+
+			resolved, err := store.UpdateResolution(session.ID, func(previous *types.Resolution) (*types.Resolution, error) {
+				if previous != nil {
+					t.Fatalf("got resolution %+v, want none yet", previous)
+				}
+				return &types.Resolution{Status: types.ResolutionStatusResolved, RootCause: "memory limit"}, nil
+			})
+			if err != nil {
+				t.Fatalf("failed to update resolution: %v", err)
+			}
+
+			// An error from fn leaves the resolution as it was, even when fn changed it first
+			failure := errors.New("invalid status")
+			_, err = store.UpdateResolution(session.ID, func(previous *types.Resolution) (*types.Resolution, error) {
+				previous.RootCause = "changed"
+				return nil, failure
+			})
+			if !errors.Is(err, failure) {
+				t.Fatalf("got %v, want the error from fn", err)
+			}
+			if _, err := store.UpdateResolution(uuid.New(), func(r *types.Resolution) (*types.Resolution, error) { return r, nil }); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("got %v for a missing session, want ErrSessionNotFound", err)
+			}
+
+			stored, err := store.GetSession(session.ID)
+			if err != nil {
+				t.Fatalf("failed to get session: %v", err)
+			}
+			if stored.Resolution == nil || *stored.Resolution != *resolved {
+				t.Fatalf("got resolution %+v, want %+v", stored.Resolution, resolved)
+			}
+		})
+	}
+}
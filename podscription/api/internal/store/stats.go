@@ -0,0 +1,247 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+// ResolutionTally counts the sessions of an intent category with a resolution status. A session
+// falls in the category of its first classified message, or general, and is open until closed.
+type ResolutionTally struct {
+	Category types.IntentCategory
+	Status   types.ResolutionStatus
+	Sessions int
+	// TimesToResolution are those of the resolved sessions, in seconds
+	TimesToResolution []float64
+}
+
+// tallyResolutions counts the sessions with messages by category and resolution status
+func tallyResolutions(sessions map[uuid.UUID]*types.Session) []ResolutionTally {
+	type tallyKey struct {
+		category types.IntentCategory
+		status   types.ResolutionStatus
+	}
+	tallies := make(map[tallyKey]*ResolutionTally)
+	for _, session := range sessions {
+		if len(session.Messages) == 0 {
+			continue
+		}
+
+		key := tallyKey{category: types.IntentCategoryGeneral, status: types.ResolutionStatusOpen}
+		for _, message := range session.Messages {
+			if message.Intent != nil {
+				key.category = message.Intent.Category
+				break
+			}
+		}
+		if session.Resolution != nil {
+			key.status = session.Resolution.Status
+		}
+
+		tally := tallies[key]
+		if tally == nil {
+			tally = &ResolutionTally{Category: key.category, Status: key.status}
+			tallies[key] = tally
+		}
+		tally.Sessions++
+		if key.status == types.ResolutionStatusResolved {
+			tally.TimesToResolution = append(tally.TimesToResolution, session.Resolution.TimeToResolution)
+		}
+	}
+
+	result := make([]ResolutionTally, 0, len(tallies))
+	for _, tally := range tallies {
+		result = append(result, *tally)
+	}
+	return result
+}
+
+// readResolutionTallies reads the category, status, session count and JSON array of times to
+// resolution of each row
+func readResolutionTallies(rows rowIterator) ([]ResolutionTally, error) {
+	tallies := make([]ResolutionTally, 0)
+	for rows.Next() {
+		var category, status string
+		var times sql.NullString
+		tally := ResolutionTally{}
+		if err := rows.Scan(&category, &status, &tally.Sessions, &times); err != nil {
+			return nil, fmt.Errorf("failed to read resolution tally: %w", err)
+		}
+		tally.Category = types.IntentCategory(category)
+		tally.Status = types.ResolutionStatus(status)
+		if err := decodeJSON(times, &tally.TimesToResolution); err != nil {
+			return nil, fmt.Errorf("failed to decode times to resolution: %w", err)
+		}
+		tallies = append(tallies, tally)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resolution tallies: %w", err)
+	}
+	return tallies, nil
+}
+
+// feedbackKey identifies the group of the feedback on a message
+type feedbackKey struct {
+	promptVersion, model, analyzer string
+	category                       types.IntentCategory
+}
+
+// messageFeedbackKey returns the group of the feedback on a message
+func messageFeedbackKey(message types.Message) feedbackKey {
+	key := feedbackKey{promptVersion: message.PromptVersion, model: message.Model, analyzer: message.Analyzer}
+	if message.Intent != nil {
+		key.category = message.Intent.Category
+	}
+	return key
+}
+
+// feedbackGroups collects the feedback groups read by a store
+type feedbackGroups map[feedbackKey]*types.FeedbackGroup
+
+// group returns the group of a key, creating it when it is new
+func (g feedbackGroups) group(key feedbackKey) *types.FeedbackGroup {
+	group := g[key]
+	if group == nil {
+		group = &types.FeedbackGroup{
+			PromptVersion: key.promptVersion,
+			Model:         key.model,
+			Analyzer:      key.analyzer,
+			Category:      key.category,
+			Examples:      make([]types.NegativeFeedback, 0),
+		}
+		g[key] = group
+	}
+	return group
+}
+
+// addMessage counts the feedback on a message, keeping it as an example when it is negative
+func (g feedbackGroups) addMessage(sessionID uuid.UUID, message types.Message) {
+	if message.Feedback == nil {
+		return
+	}
+
+	group := g.group(messageFeedbackKey(message))
+	group.Rated++
+	if !message.Feedback.Negative() {
+		return
+	}
+	group.Negative++
+
+	for _, command := range message.Feedback.WrongCommands {
+		if group.WrongCommands == nil {
+			group.WrongCommands = make(map[string]int)
+		}
+		group.WrongCommands[command]++
+	}
+	example := negativeFeedback(sessionID, message.ID, message.Feedback)
+	if message.Prescription != nil {
+		example.Diagnosis = message.Prescription.Diagnosis
+	}
+	group.Examples = append(group.Examples, example)
+}
+
+// negativeFeedback lists feedback as an example of its group
+func negativeFeedback(sessionID, messageID uuid.UUID, feedback *types.Feedback) types.NegativeFeedback {
+	return types.NegativeFeedback{
+		SessionID:     sessionID,
+		MessageID:     messageID,
+		Comment:       feedback.Comment,
+		WrongCommands: feedback.WrongCommands,
+		UpdatedAt:     feedback.UpdatedAt,
+	}
+}
+
+// scanFeedbackKey reads the prompt version, model, analyzer and category leading a feedback row,
+// then the remaining columns into dest
+func scanFeedbackKey(rows rowScanner, dest ...interface{}) (feedbackKey, error) {
+	var key feedbackKey
+	var category string
+	if err := rows.Scan(append([]interface{}{&key.promptVersion, &key.model, &key.analyzer, &category}, dest...)...); err != nil {
+		return key, err
+	}
+	key.category = types.IntentCategory(category)
+	return key, nil
+}
+
+// readCounts reads the rated and negative counts of each group
+func (g feedbackGroups) readCounts(rows rowIterator) error {
+	for rows.Next() {
+		var rated, negative int
+		key, err := scanFeedbackKey(rows, &rated, &negative)
+		if err != nil {
+			return fmt.Errorf("failed to read feedback counts: %w", err)
+		}
+		group := g.group(key)
+		group.Rated += rated
+		group.Negative += negative
+	}
+	return rows.Err()
+}
+
+// readWrongCommands reads how often each command of a group was flagged
+func (g feedbackGroups) readWrongCommands(rows rowIterator) error {
+	for rows.Next() {
+		var command string
+		var count int
+		key, err := scanFeedbackKey(rows, &command, &count)
+		if err != nil {
+			return fmt.Errorf("failed to read flagged commands: %w", err)
+		}
+		group := g.group(key)
+		if group.WrongCommands == nil {
+			group.WrongCommands = make(map[string]int)
+		}
+		group.WrongCommands[command] += count
+	}
+	return rows.Err()
+}
+
+// readExamples reads the session and message IDs, diagnosis and feedback of negative feedback
+func (g feedbackGroups) readExamples(rows rowIterator) error {
+	for rows.Next() {
+		var sessionID, messageID, diagnosis string
+		var raw sql.NullString
+		key, err := scanFeedbackKey(rows, &sessionID, &messageID, &diagnosis, &raw)
+		if err != nil {
+			return fmt.Errorf("failed to read negative feedback: %w", err)
+		}
+
+		var feedback types.Feedback
+		if err := decodeJSON(raw, &feedback); err != nil {
+			return fmt.Errorf("failed to decode feedback: %w", err)
+		}
+		parsedSessionID, err := uuid.Parse(sessionID)
+		if err != nil {
+			return err
+		}
+		parsedMessageID, err := uuid.Parse(messageID)
+		if err != nil {
+			return err
+		}
+		example := negativeFeedback(parsedSessionID, parsedMessageID, &feedback)
+		example.Diagnosis = diagnosis
+
+		group := g.group(key)
+		group.Examples = append(group.Examples, example)
+	}
+	return rows.Err()
+}
+
+// list returns the groups with up to examples of their most recent negative feedback
+func (g feedbackGroups) list(examples int) []types.FeedbackGroup {
+	groups := make([]types.FeedbackGroup, 0, len(g))
+	for _, group := range g {
+		sort.Slice(group.Examples, func(i, j int) bool {
+			return group.Examples[i].UpdatedAt.After(group.Examples[j].UpdatedAt)
+		})
+		if len(group.Examples) > examples {
+			group.Examples = group.Examples[:examples]
+		}
+		groups = append(groups, *group)
+	}
+	return groups
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"podscription-api/types"
+)
+
+func TestResolutionTallies(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Each session is classified by its first message with an intent
+			addSession := func(messages ...types.Message) *types.Session {
+				session, err := store.CreateSession(name)
+				if err != nil {
+					t.Fatalf("failed to create session: %v", err)
+				}
+				for _, message := range messages {
+					if err := store.AddMessage(session.ID, message); err != nil {
+						t.Fatalf("failed to add message: %v", err)
+					}
+				}
+				return session
+			}
+			resolve := func(session *types.Session, resolution types.Resolution) {
+				_, err := store.UpdateResolution(session.ID, func(*types.Resolution) (*types.Resolution, error) {
+					return &resolution, nil
+				})
+				if err != nil {
+					t.Fatalf("failed to resolve session: %v", err)
+				}
+			}
+			podIssue := types.Message{Role: types.MessageRoleUser, Content: "web-1 restarts",
+				Intent: &types.PodIntent{Category: types.IntentCategoryPodIssues}}
+			networking := types.Message{Role: types.MessageRoleUser, Content: "DNS times out",
+				Intent: &types.PodIntent{Category: types.IntentCategoryNetworking}}
+
+			resolve(addSession(podIssue, networking), types.Resolution{Status: types.ResolutionStatusResolved, TimeToResolution: 90})
+			resolve(addSession(podIssue), types.Resolution{Status: types.ResolutionStatusResolved, TimeToResolution: 30})
+			addSession(podIssue)
+			resolve(addSession(types.Message{Role: types.MessageRoleUser, Content: "hello"}), types.Resolution{Status: types.ResolutionStatusAbandoned})
+			// Sessions without messages are left out
+			resolve(addSession(), types.Resolution{Status: types.ResolutionStatusAbandoned})
+
+			tallies, err := store.ResolutionTallies()
+			if err != nil {
+				t.Fatalf("failed to tally resolutions: %v", err)
+			}
+			got := make(map[types.IntentCategory]map[types.ResolutionStatus]ResolutionTally)
+			for _, tally := range tallies {
+				if got[tally.Category] == nil {
+					got[tally.Category] = make(map[types.ResolutionStatus]ResolutionTally)
+				}
+				got[tally.Category][tally.Status] = tally
+			}
+			if len(tallies) != 3 {
+				t.Fatalf("got tallies %+v, want resolved and open pod issues and abandoned general sessions", tallies)
+			}
+			resolved := got[types.IntentCategoryPodIssues][types.ResolutionStatusResolved]
+			if resolved.Sessions != 2 || len(resolved.TimesToResolution) != 2 || resolved.TimesToResolution[0]+resolved.TimesToResolution[1] != 120 {
+				t.Fatalf("got resolved tally %+v, want both resolved sessions with their times", resolved)
+			}
+			if open := got[types.IntentCategoryPodIssues][types.ResolutionStatusOpen]; open.Sessions != 1 || open.TimesToResolution != nil {
+				t.Fatalf("got open tally %+v, want the unresolved session without times", open)
+			}
+			if abandoned := got[types.IntentCategoryGeneral][types.ResolutionStatusAbandoned]; abandoned.Sessions != 1 {
+				t.Fatalf("got abandoned tally %+v, want the unclassified session", abandoned)
+			}
+		})
+	}
+}
+
+func TestFeedbackGroups(t *testing.T) {
+	for name, store := range searchStores(t) {
+		t.Run(name, func(t *testing.T) {
+			session, err := store.CreateSession(name)
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			diagnosed := func(diagnosis string) types.Message {
+				return types.Message{
+					ID:            uuid.New(),
+					Role:          types.MessageRoleAssistant,
+					PromptVersion: "1.0.0",
+					Model:         "test-model",
+					Intent:        &types.PodIntent{Category: types.IntentCategoryPodIssues},
+					Prescription:  &types.Prescription{Diagnosis: diagnosis, Commands: []string{"kubectl delete pod web-1"}},
+				}
+			}
+			offline := types.Message{ID: uuid.New(), Role: types.MessageRoleAssistant, Analyzer: "rules",
+				Intent: &types.PodIntent{Category: types.IntentCategoryNetworking}}
+			messages := []types.Message{diagnosed("OOMKilled"), diagnosed("CrashLoopBackOff"), diagnosed("ImagePullBackOff"), diagnosed("Evicted"), offline}
+			for _, message := range messages {
+				if err := store.AddMessage(session.ID, message); err != nil {
+					t.Fatalf("failed to add message: %v", err)
+				}
+			}
+
+			reportedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			feedback := []*types.Feedback{
+				{Rating: types.FeedbackRatingUp, UpdatedAt: reportedAt},
+				{Rating: types.FeedbackRatingDown, Comment: "wrong namespace", UpdatedAt: reportedAt},
+				// A flagged command makes feedback negative even with a thumbs up
+				{Rating: types.FeedbackRatingUp, WrongCommands: []string{"kubectl delete pod web-1"}, UpdatedAt: reportedAt.Add(time.Hour)},
+				// The fourth answer is not rated
+				nil,
+				{Rating: types.FeedbackRatingDown, UpdatedAt: reportedAt},
+			}
+			for i, f := range feedback {
+				if f == nil {
+					continue
+				}
+				if err := store.SetMessageFeedback(session.ID, messages[i].ID, f); err != nil {
+					t.Fatalf("failed to set feedback: %v", err)
+				}
+			}
+			if err := store.SetMessageFeedback(session.ID, uuid.New(), feedback[0]); !errors.Is(err, ErrMessageNotFound) {
+				t.Fatalf("got %v for a missing message, want ErrMessageNotFound", err)
+			}
+
+			groups, err := store.FeedbackGroups(1)
+			if err != nil {
+				t.Fatalf("failed to group feedback: %v", err)
+			}
+			if len(groups) != 2 {
+				t.Fatalf("got groups %+v, want the diagnoses and the offline answers", groups)
+			}
+			for _, group := range groups {
+				switch group.Analyzer {
+				case "":
+					if group.PromptVersion != "1.0.0" || group.Model != "test-model" || group.Category != types.IntentCategoryPodIssues ||
+						group.Rated != 3 || group.Negative != 2 || group.WrongCommands["kubectl delete pod web-1"] != 1 {
+						t.Fatalf("got group %+v, want three rated diagnoses, two negative", group)
+					}
+					// Only the most recent negative feedback is kept as an example
+					if len(group.Examples) != 1 || group.Examples[0].Diagnosis != "ImagePullBackOff" ||
+						group.Examples[0].SessionID != session.ID || group.Examples[0].MessageID != messages[2].ID {
+						t.Fatalf("got examples %+v, want the flagged command", group.Examples)
+					}
+				case "rules":
+					if group.Category != types.IntentCategoryNetworking || group.Rated != 1 || group.Negative != 1 ||
+						len(group.Examples) != 1 || group.Examples[0].Diagnosis != "" {
+						t.Fatalf("got group %+v, want the offline answer", group)
+					}
+				default:
+					t.Fatalf("got group %+v", group)
+				}
+			}
+		})
+	}
+}
@@ -1,15 +1,74 @@
 package store
 
 import (
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"podscription-api/types"
 )
 
-// Store defines the interface for session storage
+var (
+	// ErrSessionNotFound is returned when a session does not exist
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrMessageNotFound is returned when a session has no message with the given ID
+	ErrMessageNotFound = errors.New("message not found")
+)
+
+// SessionUpdate holds the session attributes to change; nil fields are left unchanged
+type SessionUpdate struct {
+	Name     *string
+	Archived *bool
+	BundleID *uuid.UUID
+}
+
+// PlanFunc derives a session's new treatment plan from its current one, which may be nil
+type PlanFunc func(plan *types.TreatmentPlan) (*types.TreatmentPlan, error)
+
+// ResolutionFunc derives a session's new resolution from its current one, which may be nil
+type ResolutionFunc func(resolution *types.Resolution) (*types.Resolution, error)
+
+// Store defines the interface for session storage. Writes change only the fields they name,
+// so that requests and replicas working on the same session do not undo each other's changes.
 type Store interface {
 	CreateSession(name string) (*types.Session, error)
 	GetSession(id uuid.UUID) (*types.Session, error)
-	UpdateSession(session *types.Session) error
+	// UpdateSession changes the attributes set in update
+	UpdateSession(id uuid.UUID, update SessionUpdate) error
+	// SetSummary replaces a session's rolling conversation summary
+	SetSummary(id uuid.UUID, summary *types.ConversationSummary) error
+	// UpdatePlan replaces a session's treatment plan with the one fn derives from the current
+	// plan, atomically; an error from fn leaves the plan unchanged and is returned
+	UpdatePlan(id uuid.UUID, fn PlanFunc) (*types.TreatmentPlan, error)
+	// UpdateResolution replaces a session's resolution like UpdatePlan replaces its plan
+	UpdateResolution(id uuid.UUID, fn ResolutionFunc) (*types.Resolution, error)
+	// SetMessageFeedback replaces the feedback on a message of a session
+	SetMessageFeedback(sessionID, messageID uuid.UUID, feedback *types.Feedback) error
 	ListSessions() ([]*types.Session, error)
+	// ListSessionSummaries returns up to query.Limit summaries of the sessions matching query
+	ListSessionSummaries(query SessionQuery) ([]types.SessionSummary, error)
+	// ExpiredSessions returns the IDs of the sessions last updated before cutoff, including the
+	// archived ones only when includeArchived is set
+	ExpiredSessions(cutoff time.Time, includeArchived bool) ([]uuid.UUID, error)
+	// ResolutionTallies counts the sessions with messages by intent category and resolution status
+	ResolutionTallies() ([]ResolutionTally, error)
+	// FeedbackGroups aggregates the feedback on messages by prompt version, model, analyzer and
+	// intent category, with up to examples of each group's most recent negative feedback
+	FeedbackGroups(examples int) ([]types.FeedbackGroup, error)
+	// AddMessage appends a message to a session, assigning its ID and timestamp unless they are set
 	AddMessage(sessionID uuid.UUID, message types.Message) error
-}
\ No newline at end of file
+	// DeleteSession removes a session and its messages
+	DeleteSession(id uuid.UUID) error
+	// Search returns up to query.Limit messages matching query.Text, best first
+	Search(query SearchQuery) ([]types.SearchHit, error)
+}
+
+// newMessageDefaults assigns the ID and timestamp of a message that has none yet
+func newMessageDefaults(message *types.Message) {
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+}
@@ -0,0 +1,57 @@
+// Package text splits natural language into the terms that message search, related cases,
+// runbook excerpts and support bundle lookups match on
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are too common in problem descriptions, answers and runbooks to match on
+var stopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "any": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "can": true, "do": true, "does": true, "for": true, "from": true,
+	"has": true, "have": true, "how": true, "i": true, "if": true, "in": true, "is": true, "it": true,
+	"its": true, "me": true, "my": true, "not": true, "of": true, "on": true, "or": true, "our": true,
+	"so": true, "that": true, "the": true, "then": true, "there": true, "this": true, "to": true,
+	"was": true, "we": true, "what": true, "when": true, "which": true, "why": true, "with": true,
+	"you": true, "your": true,
+}
+
+// IsStopWord reports whether a lowercase word is too common to match on
+func IsStopWord(word string) bool {
+	return stopWords[word]
+}
+
+// IsSeparator reports whether r separates words
+func IsSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Words splits text into lowercase runs of letters and digits
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), IsSeparator)
+}
+
+// Tokenize splits text into lowercase, lightly stemmed terms without stop words and single characters
+func Tokenize(text string) []string {
+	words := Words(text)
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if len(word) < 2 || stopWords[word] {
+			continue
+		}
+		terms = append(terms, Stem(word))
+	}
+	return terms
+}
+
+// Stem strips common English suffixes so that "crashing" and "crashes" match "crash"
+func Stem(term string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(term, suffix) && len(term)-len(suffix) >= 3 {
+			return strings.TrimSuffix(term, suffix)
+		}
+	}
+	return term
+}
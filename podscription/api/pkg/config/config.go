@@ -5,11 +5,36 @@ import (
 	"strconv"
 )
 
+// Supported LLM provider names
+const (
+	ProviderOpenAI           = "openai"
+	ProviderAzureOpenAI      = "azure"
+	ProviderAnthropic        = "anthropic"
+	ProviderOpenAICompatible = "openai-compatible"
+	ProviderFake             = "fake"
+)
+
+// Supported store types
+const (
+	StoreMemory   = "memory"
+	StoreSQLite   = "sqlite"
+	StorePostgres = "postgres"
+)
+
 // Config holds the application configuration
 type Config struct {
-	Server Server `json:"server"`
-	OpenAI OpenAI `json:"openai"`
-	Store  Store  `json:"store"`
+	Server     Server     `json:"server"`
+	LLM        LLM        `json:"llm"`
+	Prompts    Prompts    `json:"prompts"`
+	Classifier Classifier `json:"classifier"`
+	Analyzers  Analyzers  `json:"analyzers"`
+	Cluster    Cluster    `json:"cluster"`
+	Bundles    Bundles    `json:"bundles"`
+	Store      Store      `json:"store"`
+	Retention  Retention  `json:"retention"`
+	Cases      Cases      `json:"cases"`
+	Knowledge  Knowledge  `json:"knowledge"`
+	History    History    `json:"history"`
 }
 
 // Server holds server configuration
@@ -18,37 +43,233 @@ type Server struct {
 	Port int    `json:"port"`
 }
 
-// OpenAI holds OpenAI API configuration
-type OpenAI struct {
-	APIKey      string `json:"apiKey"`
-	Model       string `json:"model"`
+// LLM holds language model provider configuration
+type LLM struct {
+	Provider    string  `json:"provider"`
+	APIKey      string  `json:"apiKey"`
+	Model       string  `json:"model"`
+	BaseURL     string  `json:"baseUrl,omitempty"`
+	APIVersion  string  `json:"apiVersion,omitempty"`
 	Temperature float32 `json:"temperature"`
-	MaxTokens   int    `json:"maxTokens"`
+	MaxTokens   int     `json:"maxTokens"`
+	// JSONMode asks OpenAI-style endpoints for a JSON object response; many self-hosted
+	// OpenAI-compatible servers reject or ignore it, so it is off for them unless enabled
+	JSONMode bool `json:"jsonMode"`
+	// ContextWindow is the model's context window in tokens; 0 looks it up by model name
+	ContextWindow int `json:"contextWindow"`
+	// FixturesPath overrides the built-in fixtures of the fake provider
+	FixturesPath string `json:"fixturesPath,omitempty"`
+}
+
+// Prompts holds prompt template configuration
+type Prompts struct {
+	// TemplatesDir overrides the embedded prompt templates with files of the same name
+	TemplatesDir string `json:"templatesDir,omitempty"`
+}
+
+// Classifier holds local intent classification configuration
+type Classifier struct {
+	// RulesEnabled classifies well-known error signatures without an LLM round trip
+	RulesEnabled bool `json:"rulesEnabled"`
+	// MinConfidence is the lowest rule confidence trusted without consulting the LLM
+	MinConfidence float64 `json:"minConfidence"`
+}
+
+// Analyzers holds offline analyzer configuration
+type Analyzers struct {
+	// OfflineEnabled answers well-known failures with built-in prescriptions when the LLM fails
+	OfflineEnabled bool `json:"offlineEnabled"`
+}
+
+// Cluster holds live cluster context configuration
+type Cluster struct {
+	// Enabled gathers read-only state of the objects mentioned in a conversation
+	Enabled bool `json:"enabled"`
+	// Kubeconfig is the kubeconfig path; empty uses the in-cluster service account
+	Kubeconfig string `json:"kubeconfig"`
+	// TimeoutSeconds bounds how long gathering cluster state may delay a diagnosis
+	TimeoutSeconds int `json:"timeoutSeconds"`
+}
+
+// Bundles holds support bundle configuration
+type Bundles struct {
+	// Dir is where ingested bundle indexes are kept; empty keeps them in memory only
+	Dir string `json:"dir,omitempty"`
+	// MaxSizeMB is the largest compressed bundle accepted for upload
+	MaxSizeMB int `json:"maxSizeMb"`
 }
 
 // Store holds data store configuration
 type Store struct {
 	Type string `json:"type"`
 	Path string `json:"path,omitempty"`
+	// ImportPath is a memory store JSON file imported once into an empty sqlite store
+	ImportPath string `json:"importPath,omitempty"`
+	// DSN is the Postgres connection string of the postgres store
+	DSN string `json:"-"`
+	// MaxConns caps the postgres store's connection pool
+	MaxConns int `json:"maxConns"`
+	// FlushIntervalMs batches the memory store's writes to its file; changes made
+	// within the interval before a crash are lost
+	FlushIntervalMs int `json:"flushIntervalMs"`
+}
+
+// Retention actions applied to expired sessions
+const (
+	RetentionArchive = "archive"
+	RetentionDelete  = "delete"
+)
+
+// Retention holds the session retention policy
+type Retention struct {
+	// Days is how long after their last update sessions expire; 0 keeps them forever
+	Days int `json:"days"`
+	// Action is what happens to expired sessions, archive or delete
+	Action string `json:"action"`
+	// IntervalMinutes is how often expired sessions are looked for
+	IntervalMinutes int `json:"intervalMinutes"`
+}
+
+// Cases holds related case retrieval configuration
+type Cases struct {
+	// Enabled shows diagnoses the most similar past sessions and returns them as related cases
+	Enabled bool `json:"enabled"`
+	// Limit caps the related cases per message
+	Limit int `json:"limit"`
+	// MinSimilarity is the lowest similarity, from 0 to 1, of a related case
+	MinSimilarity float64 `json:"minSimilarity"`
+}
+
+// Knowledge holds runbook knowledge base configuration
+type Knowledge struct {
+	// Dir holds the markdown and YAML runbooks cited by diagnoses; empty disables the knowledge base
+	Dir string `json:"dir,omitempty"`
+	// Limit caps the runbook excerpts shown with each message
+	Limit int `json:"limit"`
+}
+
+// History holds conversation history configuration
+type History struct {
+	// MaxTokens caps the history shown with each message, however large the context window
+	MaxTokens int `json:"maxTokens"`
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
+	provider := getEnv("LLM_PROVIDER", ProviderOpenAI)
+	storeType := getEnv("STORE_TYPE", StoreMemory)
+
 	return &Config{
 		Server: Server{
 			Host: getEnv("SERVER_HOST", "localhost"),
 			Port: getEnvAsInt("SERVER_PORT", 8080),
 		},
-		OpenAI: OpenAI{
-			APIKey:      getEnv("OPENAI_API_KEY", ""),
-			Model:       getEnv("OPENAI_MODEL", "gpt-3.5-turbo"),
-			Temperature: getEnvAsFloat32("OPENAI_TEMPERATURE", 0.7),
-			MaxTokens:   getEnvAsInt("OPENAI_MAX_TOKENS", 1000),
+		LLM: LLM{
+			Provider:      provider,
+			APIKey:        getEnv("LLM_API_KEY", getEnv(defaultAPIKeyEnv(provider), "")),
+			Model:         getEnv("LLM_MODEL", getEnv("OPENAI_MODEL", defaultModel(provider))),
+			BaseURL:       getEnvNonEmpty("LLM_BASE_URL", defaultBaseURL(provider)),
+			APIVersion:    getEnv("LLM_API_VERSION", ""),
+			Temperature:   getEnvAsFloat32("LLM_TEMPERATURE", getEnvAsFloat32("OPENAI_TEMPERATURE", 0.7)),
+			MaxTokens:     getEnvAsInt("LLM_MAX_TOKENS", getEnvAsInt("OPENAI_MAX_TOKENS", 1000)),
+			JSONMode:      getEnvAsBool("LLM_JSON_MODE", provider != ProviderOpenAICompatible),
+			ContextWindow: getEnvAsInt("LLM_CONTEXT_WINDOW", 0),
+			FixturesPath:  getEnv("LLM_FIXTURES_PATH", ""),
+		},
+		Prompts: Prompts{
+			TemplatesDir: getEnv("PROMPT_TEMPLATES_DIR", ""),
+		},
+		Classifier: Classifier{
+			RulesEnabled:  getEnvAsBool("CLASSIFIER_RULES_ENABLED", true),
+			MinConfidence: getEnvAsFloat64("CLASSIFIER_MIN_CONFIDENCE", 0.85),
+		},
+		Analyzers: Analyzers{
+			OfflineEnabled: getEnvAsBool("OFFLINE_ANALYZERS_ENABLED", true),
+		},
+		Cluster: Cluster{
+			Enabled:        getEnvAsBool("CLUSTER_CONTEXT_ENABLED", false),
+			Kubeconfig:     getEnv("KUBECONFIG", ""),
+			TimeoutSeconds: getEnvAsInt("CLUSTER_CONTEXT_TIMEOUT", 5),
+		},
+		Bundles: Bundles{
+			Dir:       getEnv("BUNDLES_DIR", "./data/bundles"),
+			MaxSizeMB: getEnvAsInt("BUNDLE_MAX_SIZE_MB", 200),
 		},
 		Store: Store{
-			Type: getEnv("STORE_TYPE", "memory"),
-			Path: getEnv("STORE_PATH", ""),
+			Type:            storeType,
+			Path:            getEnv("STORE_PATH", defaultStorePath(storeType)),
+			ImportPath:      getEnv("STORE_IMPORT_PATH", ""),
+			DSN:             getEnv("STORE_DSN", getEnv("DATABASE_URL", "")),
+			MaxConns:        getEnvAsInt("STORE_MAX_CONNS", 10),
+			FlushIntervalMs: getEnvAsInt("STORE_FLUSH_INTERVAL_MS", 1000),
+		},
+		Retention: Retention{
+			Days:            getEnvAsInt("SESSION_RETENTION_DAYS", 0),
+			Action:          getEnv("SESSION_RETENTION_ACTION", RetentionArchive),
+			IntervalMinutes: getEnvAsInt("SESSION_RETENTION_INTERVAL_MINUTES", 60),
+		},
+		Cases: Cases{
+			Enabled:       getEnvAsBool("RELATED_CASES_ENABLED", true),
+			Limit:         getEnvAsInt("RELATED_CASES_LIMIT", 3),
+			MinSimilarity: getEnvAsFloat64("RELATED_CASES_MIN_SIMILARITY", 0.2),
 		},
+		Knowledge: Knowledge{
+			Dir:   getEnv("KNOWLEDGE_DIR", ""),
+			Limit: getEnvAsInt("KNOWLEDGE_LIMIT", 3),
+		},
+		History: History{
+			MaxTokens: getEnvAsInt("HISTORY_MAX_TOKENS", 8000),
+		},
+	}
+}
+
+// defaultAPIKeyEnv returns the provider-specific environment variable holding the API key
+func defaultAPIKeyEnv(provider string) string {
+	switch provider {
+	case ProviderAzureOpenAI:
+		return "AZURE_OPENAI_API_KEY"
+	case ProviderAnthropic:
+		return "ANTHROPIC_API_KEY"
+	default:
+		return "OPENAI_API_KEY"
+	}
+}
+
+// defaultModel returns a sensible model (or Azure deployment) name for a provider
+func defaultModel(provider string) string {
+	switch provider {
+	case ProviderAnthropic:
+		return "claude-3-5-sonnet-latest"
+	case ProviderOpenAICompatible:
+		return "llama3.1"
+	case ProviderFake:
+		return "fake"
+	default:
+		return "gpt-3.5-turbo"
+	}
+}
+
+// defaultBaseURL returns the default API endpoint for a provider, if it has one
+func defaultBaseURL(provider string) string {
+	switch provider {
+	case ProviderAnthropic:
+		return "https://api.anthropic.com"
+	case ProviderOpenAICompatible:
+		// Ollama's OpenAI-compatible endpoint
+		return "http://localhost:11434/v1"
+	default:
+		return ""
+	}
+}
+
+// defaultStorePath returns where a store type keeps its data by default
+func defaultStorePath(storeType string) string {
+	switch storeType {
+	case StoreSQLite:
+		return "./data/podscription.db"
+	default:
+		// The memory store only persists when a path is given
+		return ""
 	}
 }
 
@@ -59,6 +280,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvNonEmpty is getEnv for settings where an empty value means unset, as when an env file
+// or compose file passes on a variable nobody filled in
+func getEnvNonEmpty(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
@@ -73,4 +303,20 @@ func getEnvAsFloat32(key string, defaultValue float32) float32 {
 		return float32(value)
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvAsFloat64(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -26,19 +26,178 @@ const (
 	IntentCategoryGeneral     IntentCategory = "general"
 )
 
-// PodIntent represents the classified intent of a user's message
+// IntentCategories lists every known intent category
+var IntentCategories = []IntentCategory{
+	IntentCategoryNetworking,
+	IntentCategoryStorage,
+	IntentCategoryPodIssues,
+	IntentCategoryRBAC,
+	IntentCategoryPerformance,
+	IntentCategoryGeneral,
+}
+
+// Valid reports whether the category is one of the known intent categories
+func (c IntentCategory) Valid() bool {
+	for _, category := range IntentCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// IntentSource represents which classifier produced an intent
+type IntentSource string
+
+const (
+	IntentSourceRules    IntentSource = "rules"
+	IntentSourceLLM      IntentSource = "llm"
+	IntentSourceFallback IntentSource = "fallback"
+)
+
+// CategoryScore represents how strongly a message matches a single category
+type CategoryScore struct {
+	Category IntentCategory `json:"category"`
+	Score    float64        `json:"score"`
+}
+
+// PodIntent represents the classified intent of a user's message.
+// Category and Confidence describe the most likely category; Categories holds
+// every matching category ranked by score for mixed problems.
 type PodIntent struct {
-	Category   IntentCategory `json:"category"`
-	Confidence float64        `json:"confidence"`
-	Symptoms   []string       `json:"symptoms"`
+	Category   IntentCategory  `json:"category"`
+	Confidence float64         `json:"confidence"`
+	Categories []CategoryScore `json:"categories,omitempty"`
+	Symptoms   []string        `json:"symptoms"`
+	Source     IntentSource    `json:"source,omitempty"`
+}
+
+// RiskLevel represents how disruptive a treatment step is
+type RiskLevel string
+
+const (
+	RiskLevelLow    RiskLevel = "low"
+	RiskLevelMedium RiskLevel = "medium"
+	RiskLevelHigh   RiskLevel = "high"
+)
+
+// TreatmentStep represents a single ordered step of a prescription
+type TreatmentStep struct {
+	Title          string    `json:"title"`
+	Rationale      string    `json:"rationale,omitempty"`
+	Command        string    `json:"command,omitempty"`
+	ExpectedOutput string    `json:"expectedOutput,omitempty"`
+	Risk           RiskLevel `json:"risk"`
+}
+
+// StepStatus is how a prescribed treatment step went
+type StepStatus string
+
+const (
+	StepStatusPending StepStatus = "pending"
+	StepStatusDone    StepStatus = "done"
+	StepStatusSkipped StepStatus = "skipped"
+	StepStatusFailed  StepStatus = "failed"
+)
+
+// Valid reports whether the status is one of the known step statuses
+func (s StepStatus) Valid() bool {
+	switch s {
+	case StepStatusPending, StepStatusDone, StepStatusSkipped, StepStatusFailed:
+		return true
+	}
+	return false
+}
+
+// TreatmentPlan tracks the steps prescribed in a session and what came of them
+type TreatmentPlan struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanStep is a prescribed treatment step with the engineer's report on it
+type PlanStep struct {
+	ID uuid.UUID `json:"id"`
+	// MessageID is the assistant message that prescribed the step
+	MessageID uuid.UUID `json:"messageId"`
+	// Number is the step's position in its prescription, from 1
+	Number  int        `json:"number"`
+	Title   string     `json:"title"`
+	Command string     `json:"command,omitempty"`
+	Status  StepStatus `json:"status"`
+	// Output is what the engineer saw when running the step
+	Output    string     `json:"output,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// Reported is set once the step's outcome has been given to the model
+	Reported bool `json:"reported,omitempty"`
+}
+
+// ResolutionStatus is where a consultation stands
+type ResolutionStatus string
+
+const (
+	ResolutionStatusOpen      ResolutionStatus = "open"
+	ResolutionStatusResolved  ResolutionStatus = "resolved"
+	ResolutionStatusAbandoned ResolutionStatus = "abandoned"
+)
+
+// Valid reports whether the status is one of the known resolution statuses
+func (s ResolutionStatus) Valid() bool {
+	switch s {
+	case ResolutionStatusOpen, ResolutionStatusResolved, ResolutionStatusAbandoned:
+		return true
+	}
+	return false
+}
+
+// Resolution records how a consultation ended
+type Resolution struct {
+	Status    ResolutionStatus `json:"status"`
+	RootCause string           `json:"rootCause,omitempty"`
+	// FixedBy is the assistant message whose prescription fixed the problem
+	FixedBy *uuid.UUID `json:"fixedBy,omitempty"`
+	// ClosedAt is when the session was resolved or abandoned
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+	// TimeToResolution is the time from the first message to the resolution, in seconds
+	TimeToResolution float64 `json:"timeToResolution,omitempty"`
 }
 
 // Prescription represents the AI's structured response
 type Prescription struct {
-	Diagnosis string   `json:"diagnosis"`
-	Treatment string   `json:"treatment"`
-	Commands  []string `json:"commands,omitempty"`
-	FollowUp  string   `json:"followUp,omitempty"`
+	Diagnosis   string          `json:"diagnosis"`
+	Explanation string          `json:"explanation,omitempty"`
+	Treatment   string          `json:"treatment"`
+	Steps       []TreatmentStep `json:"steps,omitempty"`
+	Commands    []string        `json:"commands,omitempty"`
+	FollowUp    string          `json:"followUp,omitempty"`
+	Closing     string          `json:"closing,omitempty"`
+}
+
+// AttachmentKind represents the kind of output attached to a message
+type AttachmentKind string
+
+const (
+	AttachmentKindDescribe AttachmentKind = "describe"
+	AttachmentKindEvents   AttachmentKind = "events"
+	AttachmentKindLogs     AttachmentKind = "logs"
+	AttachmentKindManifest AttachmentKind = "manifest"
+)
+
+// Valid reports whether the kind is one of the known attachment kinds
+func (k AttachmentKind) Valid() bool {
+	switch k {
+	case AttachmentKindDescribe, AttachmentKindEvents, AttachmentKindLogs, AttachmentKindManifest:
+		return true
+	}
+	return false
+}
+
+// Attachment is raw kubectl output, container logs or a manifest attached to a message
+type Attachment struct {
+	Kind    AttachmentKind `json:"kind"`
+	Name    string         `json:"name,omitempty"`
+	Content string         `json:"content"`
+	// Facts are extracted from the content by the server
+	Facts []string `json:"facts,omitempty"`
 }
 
 // Message represents a single message in a conversation
@@ -47,8 +206,71 @@ type Message struct {
 	Role         MessageRole   `json:"role"`
 	Content      string        `json:"content"`
 	Timestamp    time.Time     `json:"timestamp"`
+	Attachments  []Attachment  `json:"attachments,omitempty"`
 	Intent       *PodIntent    `json:"intent,omitempty"`
 	Prescription *Prescription `json:"prescription,omitempty"`
+	// PromptVersion identifies the prompt templates that produced an assistant message
+	PromptVersion string `json:"promptVersion,omitempty"`
+	// Model names the model that produced an assistant message
+	Model string `json:"model,omitempty"`
+	// Analyzer names the built-in offline analyzer that produced an assistant message
+	// when the LLM was unavailable
+	Analyzer string `json:"analyzer,omitempty"`
+	// Sources are the runbook excerpts shown to the model for an assistant message,
+	// cited in its answer by their Ref
+	Sources []KnowledgeSource `json:"sources,omitempty"`
+	// Feedback is the team's opinion of an assistant message
+	Feedback *Feedback `json:"feedback,omitempty"`
+}
+
+// FeedbackRating is a thumbs up or down on an assistant message
+type FeedbackRating string
+
+const (
+	FeedbackRatingUp   FeedbackRating = "up"
+	FeedbackRatingDown FeedbackRating = "down"
+)
+
+// Valid reports whether the rating is one of the known ratings
+func (r FeedbackRating) Valid() bool {
+	return r == FeedbackRatingUp || r == FeedbackRatingDown
+}
+
+// Feedback rates an assistant message and flags the prescribed commands that were wrong
+type Feedback struct {
+	Rating  FeedbackRating `json:"rating"`
+	Comment string         `json:"comment,omitempty"`
+	// WrongCommands are commands of the message's prescription flagged as wrong
+	WrongCommands []string  `json:"wrongCommands,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Negative reports whether the feedback is a thumbs down or flags a command
+func (f *Feedback) Negative() bool {
+	return f.Rating == FeedbackRatingDown || len(f.WrongCommands) > 0
+}
+
+// ConversationSummary condenses the turns of a session that no longer fit the model's context
+type ConversationSummary struct {
+	// Findings are the problems reported and the diagnoses reached, oldest first
+	Findings []string `json:"findings,omitempty"`
+	// Commands are the commands prescribed so far
+	Commands []string `json:"commands,omitempty"`
+	// Results are the facts extracted from output the user attached
+	Results []string `json:"results,omitempty"`
+	// Through is the number of leading messages of the session the summary covers
+	Through   int       `json:"through"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// KnowledgeSource is a runbook excerpt relevant to a message
+type KnowledgeSource struct {
+	Ref int `json:"ref"`
+	// Path is the runbook's path within the knowledge directory
+	Path    string  `json:"path"`
+	Title   string  `json:"title"`
+	Excerpt string  `json:"excerpt"`
+	Score   float64 `json:"score"`
 }
 
 // Session represents a conversation session
@@ -58,18 +280,126 @@ type Session struct {
 	Messages  []Message `json:"messages"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	// BundleID is the support bundle the session's answers are grounded in
+	BundleID *uuid.UUID `json:"bundleId,omitempty"`
+	// Archived sessions are kept for reference but hidden from the default session list
+	Archived bool `json:"archived"`
+	// Summary condenses the turns that no longer fit the model's context
+	Summary *ConversationSummary `json:"summary,omitempty"`
+	// Plan tracks the treatment steps prescribed so far
+	Plan *TreatmentPlan `json:"plan,omitempty"`
+	// Resolution records how the consultation ended; sessions without one are open
+	Resolution *Resolution `json:"resolution,omitempty"`
+}
+
+// SessionSummary is the lightweight projection of a session used to list sessions
+type SessionSummary struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	MessageCount int       `json:"messageCount"`
+	// LastIntent is the category of the most recently classified message
+	LastIntent IntentCategory `json:"lastIntent,omitempty"`
+	// LastDiagnosis is the diagnosis of the most recent prescription
+	LastDiagnosis string     `json:"lastDiagnosis,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	BundleID      *uuid.UUID `json:"bundleId,omitempty"`
+	Archived      bool       `json:"archived"`
+}
+
+// SessionPage is one page of a session listing
+type SessionPage struct {
+	Sessions []SessionSummary `json:"sessions"`
+	// NextCursor fetches the following page; it is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// RelatedCase is a past session similar to a new message, with the diagnosis it reached
+type RelatedCase struct {
+	SessionID   uuid.UUID      `json:"sessionId"`
+	SessionName string         `json:"sessionName"`
+	Category    IntentCategory `json:"category,omitempty"`
+	Symptoms    []string       `json:"symptoms,omitempty"`
+	Diagnosis   string         `json:"diagnosis"`
+	Treatment   string         `json:"treatment,omitempty"`
+	// Status and RootCause are the recorded outcome of the case, if any
+	Status    ResolutionStatus `json:"status,omitempty"`
+	RootCause string           `json:"rootCause,omitempty"`
+	// Similarity is the cosine similarity to the message, between 0 and 1
+	Similarity float64   `json:"similarity"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// SearchHit is a message matching a search, with the session it belongs to
+type SearchHit struct {
+	SessionID   uuid.UUID   `json:"sessionId"`
+	SessionName string      `json:"sessionName"`
+	MessageID   uuid.UUID   `json:"messageId"`
+	Role        MessageRole `json:"role"`
+	Timestamp   time.Time   `json:"timestamp"`
+	// Snippet is an excerpt of the matching text with the matched terms wrapped in <mark> tags
+	Snippet string `json:"snippet"`
+	// Score ranks hits; higher is more relevant, and only comparable within one search
+	Score float64 `json:"score"`
+}
+
+// SearchResponse represents the results of a search across sessions
+type SearchResponse struct {
+	Query string      `json:"query"`
+	Hits  []SearchHit `json:"hits"`
+}
+
+// FindingSeverity represents how urgent a support bundle finding is
+type FindingSeverity string
+
+const (
+	FindingSeverityCritical FindingSeverity = "critical"
+	FindingSeverityWarning  FindingSeverity = "warning"
+	FindingSeverityInfo     FindingSeverity = "info"
+)
+
+// Finding is a problem detected in a support bundle
+type Finding struct {
+	Severity FindingSeverity `json:"severity"`
+	Object   string          `json:"object"`
+	Summary  string          `json:"summary"`
+}
+
+// BundleSummary describes an ingested support bundle
+type BundleSummary struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Resources int       `json:"resources"`
+	Events    int       `json:"events"`
+	Logs      int       `json:"logs"`
+	Findings  []Finding `json:"findings"`
+}
+
+// BundleUploadResponse represents the response to a support bundle upload
+type BundleUploadResponse struct {
+	Bundle  BundleSummary `json:"bundle"`
+	Session Session       `json:"session"`
 }
 
 // ChatRequest represents an incoming chat message request
 type ChatRequest struct {
-	SessionID *uuid.UUID `json:"sessionId,omitempty"`
-	Content   string     `json:"content"`
+	SessionID   *uuid.UUID   `json:"sessionId,omitempty"`
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 // ChatResponse represents the response to a chat request
 type ChatResponse struct {
 	Session Session `json:"session"`
 	Message Message `json:"message"`
+	// RelatedCases are past sessions similar to the message, shown to the model with their outcomes
+	RelatedCases []RelatedCase `json:"relatedCases,omitempty"`
+}
+
+// StreamDelta represents an incremental chunk of a streamed assistant response
+type StreamDelta struct {
+	Content string `json:"content"`
 }
 
 // CreateSessionRequest represents a request to create a new session
@@ -77,6 +407,101 @@ type CreateSessionRequest struct {
 	Name string `json:"name,omitempty"`
 }
 
+// ListSessionsRequest represents the query parameters of a session listing
+type ListSessionsRequest struct {
+	Cursor        string `form:"cursor"`
+	Limit         int    `form:"limit"`
+	Order         string `form:"order"`
+	Category      string `form:"category"`
+	Name          string `form:"name"`
+	UpdatedAfter  string `form:"updatedAfter"`
+	UpdatedBefore string `form:"updatedBefore"`
+	Archived      bool   `form:"archived"`
+}
+
+// UpdateSessionRequest represents a request to rename or archive a session; omitted
+// fields are left unchanged
+type UpdateSessionRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+}
+
+// UpdatePlanStepRequest reports how a prescribed treatment step went
+type UpdatePlanStepRequest struct {
+	Status StepStatus `json:"status" binding:"required"`
+	// Output is what running the step showed; omitted output is left unchanged
+	Output *string `json:"output,omitempty"`
+}
+
+// UpdateResolutionRequest resolves, abandons or reopens a session
+type UpdateResolutionRequest struct {
+	Status    ResolutionStatus `json:"status" binding:"required"`
+	RootCause string           `json:"rootCause,omitempty"`
+	// FixedBy is the assistant message whose prescription fixed the problem, for resolved sessions
+	FixedBy *uuid.UUID `json:"fixedBy,omitempty"`
+}
+
+// ResolutionStats aggregates the outcomes of the consultations of an intent category
+type ResolutionStats struct {
+	Category  IntentCategory `json:"category,omitempty"`
+	Sessions  int            `json:"sessions"`
+	Open      int            `json:"open"`
+	Resolved  int            `json:"resolved"`
+	Abandoned int            `json:"abandoned"`
+	// ResolutionRate is the share of closed sessions that were resolved, between 0 and 1
+	ResolutionRate float64 `json:"resolutionRate"`
+	// MeanTimeToResolution and MedianTimeToResolution are in seconds
+	MeanTimeToResolution   float64 `json:"meanTimeToResolution"`
+	MedianTimeToResolution float64 `json:"medianTimeToResolution"`
+}
+
+// ResolutionStatsResponse holds the resolution statistics of each intent category and overall
+type ResolutionStatsResponse struct {
+	Categories []ResolutionStats `json:"categories"`
+	Total      ResolutionStats   `json:"total"`
+}
+
+// SetFeedbackRequest rates an assistant message, replacing any earlier feedback on it
+type SetFeedbackRequest struct {
+	Rating        FeedbackRating `json:"rating" binding:"required"`
+	Comment       string         `json:"comment,omitempty"`
+	WrongCommands []string       `json:"wrongCommands,omitempty"`
+}
+
+// NegativeFeedback is a negatively rated message in a feedback report
+type NegativeFeedback struct {
+	SessionID     uuid.UUID `json:"sessionId"`
+	MessageID     uuid.UUID `json:"messageId"`
+	Diagnosis     string    `json:"diagnosis,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	WrongCommands []string  `json:"wrongCommands,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// FeedbackGroup aggregates the feedback on the answers of a prompt version, model and intent category
+type FeedbackGroup struct {
+	PromptVersion string `json:"promptVersion,omitempty"`
+	Model         string `json:"model,omitempty"`
+	// Analyzer names the offline analyzer of answers given without the model
+	Analyzer string         `json:"analyzer,omitempty"`
+	Category IntentCategory `json:"category,omitempty"`
+	Rated    int            `json:"rated"`
+	Negative int            `json:"negative"`
+	// NegativeRate is the share of rated answers with negative feedback, between 0 and 1
+	NegativeRate float64 `json:"negativeRate"`
+	// WrongCommands counts the flags of each command
+	WrongCommands map[string]int `json:"wrongCommands,omitempty"`
+	// Examples are the most recent negative feedback of the group
+	Examples []NegativeFeedback `json:"examples"`
+}
+
+// FeedbackReport groups the feedback on assistant messages, most negative feedback first
+type FeedbackReport struct {
+	Groups   []FeedbackGroup `json:"groups"`
+	Rated    int             `json:"rated"`
+	Negative int             `json:"negative"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	ErrorCode string `json:"error"`
@@ -90,4 +515,4 @@ func (e *ErrorResponse) Error() string {
 		return e.Message
 	}
 	return e.ErrorCode
-}
\ No newline at end of file
+}
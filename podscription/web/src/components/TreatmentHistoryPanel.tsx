@@ -1,8 +1,8 @@
 import React from 'react';
-import { Session } from '../types';
+import { Session, SessionSummary } from '../types';
 
 interface TreatmentHistoryPanelProps {
-  sessions: Session[];
+  sessions: SessionSummary[];
   currentSession: Session | null;
   onSelectSession: (sessionId: string) => void;
   onNewSession: () => void;
@@ -30,21 +30,15 @@ const TreatmentHistoryPanel: React.FC<TreatmentHistoryPanelProps> = ({
     }
   };
 
-  const getSessionIcon = (session: Session) => {
-    if (session.messages.length === 0) return '📋';
-    
-    const lastMessage = session.messages[session.messages.length - 1];
-    if (lastMessage.role === 'assistant' && lastMessage.intent) {
-      switch (lastMessage.intent.category) {
-        case 'pod-issues': return '🔴';
-        case 'networking': return '🌐';
-        case 'storage': return '💾';
-        case 'performance': return '⚡';
-        case 'rbac': return '🔐';
-        default: return '📋';
-      }
+  const getSessionIcon = (session: SessionSummary) => {
+    switch (session.lastIntent) {
+      case 'pod-issues': return '🔴';
+      case 'networking': return '🌐';
+      case 'storage': return '💾';
+      case 'performance': return '⚡';
+      case 'rbac': return '🔐';
+      default: return '📋';
     }
-    return '📋';
   };
 
   return (
@@ -100,11 +94,11 @@ const TreatmentHistoryPanel: React.FC<TreatmentHistoryPanelProps> = ({
                       {session.name}
                     </p>
                     <p className="text-xs text-prescription-600 mt-1">
-                      {session.messages.length} messages • {formatDate(session.updatedAt)}
+                      {session.messageCount} messages • {formatDate(session.updatedAt)}
                     </p>
-                    {session.messages.length > 0 && (
+                    {session.lastDiagnosis && (
                       <p className="text-xs text-prescription-500 mt-1 truncate">
-                        Last: {session.messages[session.messages.length - 1].content.substring(0, 30)}...
+                        Last: {session.lastDiagnosis}
                       </p>
                     )}
                   </div>
@@ -1,18 +1,17 @@
 import React, { createContext, useContext, useReducer, ReactNode, useEffect } from 'react';
-import { Message, Session, PodscriptionContextType } from '../types';
+import { Message, Session, SessionSummary, PodscriptionContextType } from '../types';
 import { apiService } from '../services/apiService';
 
 interface State {
   currentSession: Session | null;
-  sessions: Session[];
+  sessions: SessionSummary[];
   isLoading: boolean;
   error: string | null;
 }
 
 type Action =
-  | { type: 'SET_SESSIONS'; payload: { sessions: Session[] } }
+  | { type: 'SET_SESSIONS'; payload: { sessions: SessionSummary[] } }
   | { type: 'SET_CURRENT_SESSION'; payload: { session: Session } }
-  | { type: 'SELECT_SESSION'; payload: { sessionId: string } }
   | { type: 'ADD_MESSAGE'; payload: { message: Message } }
   | { type: 'CREATE_SESSION'; payload: { name?: string } }
   | { type: 'SET_LOADING'; payload: { loading: boolean } }
@@ -25,6 +24,23 @@ const initialState: State = {
   error: null,
 };
 
+// summarize projects a loaded session onto the summary shown in the history sidebar
+function summarize(session: Session): SessionSummary {
+  const intents = session.messages.filter(m => m.intent);
+  const prescriptions = session.messages.filter(m => m.prescription);
+  return {
+    id: session.id,
+    name: session.name,
+    messageCount: session.messages.length,
+    lastIntent: intents[intents.length - 1]?.intent?.category,
+    lastDiagnosis: prescriptions[prescriptions.length - 1]?.prescription?.diagnosis,
+    createdAt: session.createdAt,
+    updatedAt: session.updatedAt,
+    bundleId: session.bundleId,
+    archived: session.archived,
+  };
+}
+
 function podscriptionReducer(state: State, action: Action): State {
   switch (action.type) {
     case 'SET_SESSIONS': {
@@ -39,15 +55,8 @@ function podscriptionReducer(state: State, action: Action): State {
         ...state,
         currentSession: session,
         sessions: state.sessions.some(s => s.id === session.id)
-          ? state.sessions.map(s => s.id === session.id ? session : s)
-          : [...state.sessions, session],
-      };
-    }
-    case 'SELECT_SESSION': {
-      const session = state.sessions.find(s => s.id === action.payload.sessionId);
-      return {
-        ...state,
-        currentSession: session || null,
+          ? state.sessions.map(s => s.id === session.id ? summarize(session) : s)
+          : [summarize(session), ...state.sessions],
       };
     }
     case 'ADD_MESSAGE': {
@@ -63,7 +72,7 @@ function podscriptionReducer(state: State, action: Action): State {
         ...state,
         currentSession: updatedSession,
         sessions: state.sessions.map(s => 
-          s.id === updatedSession.id ? updatedSession : s
+          s.id === updatedSession.id ? summarize(updatedSession) : s
         ),
       };
     }
@@ -74,12 +83,13 @@ function podscriptionReducer(state: State, action: Action): State {
         messages: [],
         createdAt: new Date(),
         updatedAt: new Date(),
+        archived: false,
       };
       
       return {
         ...state,
         currentSession: newSession,
-        sessions: [newSession, ...state.sessions],
+        sessions: [summarize(newSession), ...state.sessions],
       };
     }
     case 'SET_LOADING': {
@@ -108,7 +118,7 @@ export function PodscriptionProvider({ children }: { children: ReactNode }) {
   useEffect(() => {
     const loadSessions = async () => {
       try {
-        const sessions = await apiService.listSessions();
+        const { sessions } = await apiService.listSessions();
         dispatch({ type: 'SET_SESSIONS', payload: { sessions } });
       } catch (error) {
         console.warn('Failed to load sessions:', error);
@@ -136,8 +146,17 @@ export function PodscriptionProvider({ children }: { children: ReactNode }) {
     }
   };
 
-  const selectSession = (sessionId: string) => {
-    dispatch({ type: 'SELECT_SESSION', payload: { sessionId } });
+  const selectSession = async (sessionId: string) => {
+    try {
+      dispatch({ type: 'SET_ERROR', payload: { error: null } });
+
+      // The sidebar only holds summaries, so load the full conversation on selection
+      const session = await apiService.getSession(sessionId);
+      dispatch({ type: 'SET_CURRENT_SESSION', payload: { session } });
+    } catch (error) {
+      const errorMessage = error instanceof Error ? error.message : 'Failed to load session';
+      dispatch({ type: 'SET_ERROR', payload: { error: errorMessage } });
+    }
   };
 
   const sendMessage = async (content: string) => {
@@ -1,21 +1,46 @@
-import { Message, Session } from '../types';
+import { Attachment, BundleSummary, Feedback, FeedbackRating, FeedbackReport, Message, RelatedCase, Resolution, ResolutionStatsResponse, ResolutionStatus, SearchResponse, Session, SessionListOptions, SessionPage, StepStatus, TreatmentPlan } from '../types';
 
 interface ChatRequest {
   content: string;
   sessionId?: string;
+  attachments?: Attachment[];
 }
 
 interface ChatResponse {
   session: Session;
   message: Message;
+  relatedCases?: RelatedCase[];
+}
+
+interface BundleUploadResponse {
+  bundle: BundleSummary;
+  session: Session;
 }
 
 interface CreateSessionRequest {
   name?: string;
 }
 
-interface SessionsResponse {
-  sessions: Session[];
+interface UpdateSessionRequest {
+  name?: string;
+  archived?: boolean;
+}
+
+interface UpdatePlanStepRequest {
+  status: StepStatus;
+  output?: string;
+}
+
+interface UpdateResolutionRequest {
+  status: ResolutionStatus;
+  rootCause?: string;
+  fixedBy?: string;
+}
+
+interface SetFeedbackRequest {
+  rating: FeedbackRating;
+  comment?: string;
+  wrongCommands?: string[];
 }
 
 interface ApiError {
@@ -56,6 +81,10 @@ class ApiService {
         throw new Error(`API Error: ${errorData.message || errorData.error}`);
       }
 
+      if (response.status === 204) {
+        return undefined as T;
+      }
+
       return await response.json();
     } catch (error) {
       if (error instanceof Error) {
@@ -65,10 +94,11 @@ class ApiService {
     }
   }
 
-  async sendMessage(content: string, sessionId?: string): Promise<ChatResponse> {
+  async sendMessage(content: string, sessionId?: string, attachments?: Attachment[]): Promise<ChatResponse> {
     const request: ChatRequest = {
       content,
       ...(sessionId && { sessionId }),
+      ...(attachments?.length && { attachments }),
     };
 
     return this.fetchWithErrorHandling<ChatResponse>('/chat', {
@@ -92,9 +122,86 @@ class ApiService {
     return this.fetchWithErrorHandling<Session>(`/sessions/${sessionId}`);
   }
 
-  async listSessions(): Promise<Session[]> {
-    const response = await this.fetchWithErrorHandling<SessionsResponse>('/sessions');
-    return response.sessions;
+  async listSessions(options: SessionListOptions = {}): Promise<SessionPage> {
+    const params = new URLSearchParams();
+    Object.entries(options).forEach(([key, value]) => {
+      if (value !== undefined && value !== '') {
+        params.set(key, String(value));
+      }
+    });
+    const query = params.toString();
+
+    return this.fetchWithErrorHandling<SessionPage>(`/sessions${query ? `?${query}` : ''}`);
+  }
+
+  async updateSession(sessionId: string, update: UpdateSessionRequest): Promise<Session> {
+    return this.fetchWithErrorHandling<Session>(`/sessions/${sessionId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(update),
+    });
+  }
+
+  async deleteSession(sessionId: string): Promise<void> {
+    return this.fetchWithErrorHandling<void>(`/sessions/${sessionId}`, {
+      method: 'DELETE',
+    });
+  }
+
+  async getPlan(sessionId: string): Promise<TreatmentPlan> {
+    return this.fetchWithErrorHandling<TreatmentPlan>(`/sessions/${sessionId}/plan`);
+  }
+
+  async updatePlanStep(sessionId: string, stepId: string, update: UpdatePlanStepRequest): Promise<TreatmentPlan> {
+    return this.fetchWithErrorHandling<TreatmentPlan>(`/sessions/${sessionId}/plan/steps/${stepId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(update),
+    });
+  }
+
+  async setResolution(sessionId: string, update: UpdateResolutionRequest): Promise<Resolution> {
+    return this.fetchWithErrorHandling<Resolution>(`/sessions/${sessionId}/resolution`, {
+      method: 'PUT',
+      body: JSON.stringify(update),
+    });
+  }
+
+  async getResolutionStats(): Promise<ResolutionStatsResponse> {
+    return this.fetchWithErrorHandling<ResolutionStatsResponse>('/stats/resolutions');
+  }
+
+  async setFeedback(sessionId: string, messageId: string, feedback: SetFeedbackRequest): Promise<Feedback> {
+    return this.fetchWithErrorHandling<Feedback>(`/sessions/${sessionId}/messages/${messageId}/feedback`, {
+      method: 'PUT',
+      body: JSON.stringify(feedback),
+    });
+  }
+
+  async getFeedbackReport(): Promise<FeedbackReport> {
+    return this.fetchWithErrorHandling<FeedbackReport>('/feedback/report');
+  }
+
+  async uploadBundle(file: File): Promise<BundleUploadResponse> {
+    const form = new FormData();
+    form.append('bundle', file);
+
+    // Let the browser set the multipart boundary instead of the JSON content type
+    return this.fetchWithErrorHandling<BundleUploadResponse>('/bundles', {
+      method: 'POST',
+      headers: {},
+      body: form,
+    });
+  }
+
+  async getBundle(bundleId: string): Promise<BundleSummary> {
+    return this.fetchWithErrorHandling<BundleSummary>(`/bundles/${bundleId}`);
+  }
+
+  async search(query: string, limit?: number): Promise<SearchResponse> {
+    const params = new URLSearchParams({ q: query });
+    if (limit) {
+      params.set('limit', String(limit));
+    }
+    return this.fetchWithErrorHandling<SearchResponse>(`/search?${params.toString()}`);
   }
 
   async healthCheck(): Promise<{ status: string; service: string; version: string }> {
@@ -109,4 +216,4 @@ class ApiService {
 }
 
 export const apiService = new ApiService();
-export type { ChatResponse, ApiError };
\ No newline at end of file
+export type { ChatResponse, BundleUploadResponse, ApiError };
\ No newline at end of file
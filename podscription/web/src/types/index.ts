@@ -1,10 +1,69 @@
+export type AttachmentKind = 'describe' | 'events' | 'logs' | 'manifest';
+
+export interface Attachment {
+  kind: AttachmentKind;
+  name?: string;
+  content: string;
+  facts?: string[];
+}
+
 export interface Message {
   id: string;
   role: 'user' | 'assistant';
   content: string;
   timestamp: Date;
+  attachments?: Attachment[];
   intent?: PodIntent;
   prescription?: Prescription;
+  promptVersion?: string;
+  model?: string;
+  analyzer?: string;
+  sources?: KnowledgeSource[];
+  feedback?: Feedback;
+}
+
+export type FeedbackRating = 'up' | 'down';
+
+export interface Feedback {
+  rating: FeedbackRating;
+  comment?: string;
+  wrongCommands?: string[];
+  updatedAt: Date;
+}
+
+export interface NegativeFeedback {
+  sessionId: string;
+  messageId: string;
+  diagnosis?: string;
+  comment?: string;
+  wrongCommands?: string[];
+  updatedAt: Date;
+}
+
+export interface FeedbackGroup {
+  promptVersion?: string;
+  model?: string;
+  analyzer?: string;
+  category?: IntentCategory;
+  rated: number;
+  negative: number;
+  negativeRate: number;
+  wrongCommands?: Record<string, number>;
+  examples: NegativeFeedback[];
+}
+
+export interface FeedbackReport {
+  groups: FeedbackGroup[];
+  rated: number;
+  negative: number;
+}
+
+export interface KnowledgeSource {
+  ref: number;
+  path: string;
+  title: string;
+  excerpt: string;
+  score: number;
 }
 
 export interface Session {
@@ -13,27 +72,179 @@ export interface Session {
   messages: Message[];
   createdAt: Date;
   updatedAt: Date;
+  bundleId?: string;
+  archived: boolean;
+  summary?: ConversationSummary;
+  plan?: TreatmentPlan;
+  resolution?: Resolution;
+}
+
+export type ResolutionStatus = 'open' | 'resolved' | 'abandoned';
+
+export interface Resolution {
+  status: ResolutionStatus;
+  rootCause?: string;
+  fixedBy?: string;
+  closedAt?: Date;
+  timeToResolution?: number;
+}
+
+export interface ResolutionStats {
+  category?: IntentCategory;
+  sessions: number;
+  open: number;
+  resolved: number;
+  abandoned: number;
+  resolutionRate: number;
+  meanTimeToResolution: number;
+  medianTimeToResolution: number;
+}
+
+export interface ResolutionStatsResponse {
+  categories: ResolutionStats[];
+  total: ResolutionStats;
+}
+
+export type StepStatus = 'pending' | 'done' | 'skipped' | 'failed';
+
+export interface PlanStep {
+  id: string;
+  messageId: string;
+  number: number;
+  title: string;
+  command?: string;
+  status: StepStatus;
+  output?: string;
+  updatedAt?: Date;
+  reported?: boolean;
+}
+
+export interface TreatmentPlan {
+  steps: PlanStep[];
+}
+
+export interface ConversationSummary {
+  findings?: string[];
+  commands?: string[];
+  results?: string[];
+  through: number;
+  updatedAt: Date;
+}
+
+export interface SessionSummary {
+  id: string;
+  name: string;
+  messageCount: number;
+  lastIntent?: IntentCategory;
+  lastDiagnosis?: string;
+  createdAt: Date;
+  updatedAt: Date;
+  bundleId?: string;
+  archived: boolean;
+}
+
+export interface SessionPage {
+  sessions: SessionSummary[];
+  nextCursor?: string;
+}
+
+export interface SearchHit {
+  sessionId: string;
+  sessionName: string;
+  messageId: string;
+  role: 'user' | 'assistant';
+  timestamp: Date;
+  // HTML-escaped text whose matched terms are wrapped in <mark> tags
+  snippet: string;
+  score: number;
+}
+
+export interface SearchResponse {
+  query: string;
+  hits: SearchHit[];
+}
+
+export interface RelatedCase {
+  sessionId: string;
+  sessionName: string;
+  category?: IntentCategory;
+  symptoms?: string[];
+  diagnosis: string;
+  treatment?: string;
+  status?: ResolutionStatus;
+  rootCause?: string;
+  similarity: number;
+  updatedAt: Date;
+}
+
+export interface SessionListOptions {
+  cursor?: string;
+  limit?: number;
+  order?: 'asc' | 'desc';
+  category?: IntentCategory;
+  name?: string;
+  updatedAfter?: string;
+  updatedBefore?: string;
+  archived?: boolean;
+}
+
+export type FindingSeverity = 'critical' | 'warning' | 'info';
+
+export interface Finding {
+  severity: FindingSeverity;
+  object: string;
+  summary: string;
+}
+
+export interface BundleSummary {
+  id: string;
+  name: string;
+  createdAt: Date;
+  resources: number;
+  events: number;
+  logs: number;
+  findings: Finding[];
+}
+
+export interface TreatmentStep {
+  title: string;
+  rationale?: string;
+  command?: string;
+  expectedOutput?: string;
+  risk: 'low' | 'medium' | 'high';
 }
 
 export interface Prescription {
   diagnosis: string;
+  explanation?: string;
   treatment: string;
+  steps?: TreatmentStep[];
   commands?: string[];
   followUp?: string;
+  closing?: string;
+}
+
+export type IntentCategory = 'networking' | 'storage' | 'pod-issues' | 'rbac' | 'performance' | 'general';
+
+export interface CategoryScore {
+  category: IntentCategory;
+  score: number;
 }
 
 export interface PodIntent {
-  category: 'networking' | 'storage' | 'pod-issues' | 'rbac' | 'performance' | 'general';
+  category: IntentCategory;
   confidence: number;
+  categories?: CategoryScore[];
   symptoms: string[];
+  source?: 'rules' | 'llm' | 'fallback';
 }
 
 export interface PodscriptionContextType {
   currentSession: Session | null;
-  sessions: Session[];
+  sessions: SessionSummary[];
   isLoading: boolean;
   error: string | null;
   createSession: (name?: string) => Promise<void>;
-  selectSession: (sessionId: string) => void;
+  selectSession: (sessionId: string) => Promise<void>;
   sendMessage: (content: string) => Promise<void>;
 }
\ No newline at end of file